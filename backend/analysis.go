@@ -0,0 +1,73 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backend
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+)
+
+// Analyzer is implemented by backends reporting the cost and memory analysis of compiled functions.
+type Analyzer interface {
+	// Analysis returns the analysis of the last compilation of a function given its fully qualified name.
+	Analysis(funcName string) (*pjrtgraph.Analysis, error)
+	// Analyses returns the analyses of all the functions compiled by the backend, sorted by name.
+	Analyses() []*pjrtgraph.Analysis
+}
+
+var _ Analyzer = (*pBackend)(nil)
+
+type analyses struct {
+	mu     sync.Mutex
+	byName map[string]*pjrtgraph.Analysis
+}
+
+func (as *analyses) record(g *pjrtgraph.Graph) {
+	a, err := g.Analysis()
+	if err != nil {
+		return
+	}
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if as.byName == nil {
+		as.byName = make(map[string]*pjrtgraph.Analysis)
+	}
+	as.byName[a.Name] = a
+}
+
+// Analysis returns the analysis of the last compilation of a function given its fully qualified name.
+func (b *pBackend) Analysis(funcName string) (*pjrtgraph.Analysis, error) {
+	b.analyses.mu.Lock()
+	defer b.analyses.mu.Unlock()
+	a, ok := b.analyses.byName[funcName]
+	if !ok {
+		return nil, errors.Errorf("no analysis for function %s: the function has not been compiled by the backend", funcName)
+	}
+	return a, nil
+}
+
+// Analyses returns the analyses of all the functions compiled by the backend, sorted by name.
+func (b *pBackend) Analyses() []*pjrtgraph.Analysis {
+	b.analyses.mu.Lock()
+	defer b.analyses.mu.Unlock()
+	return slices.SortedFunc(maps.Values(b.analyses.byName), func(x, y *pjrtgraph.Analysis) int {
+		return strings.Compare(x.Name, y.Name)
+	})
+}
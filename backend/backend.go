@@ -16,30 +16,126 @@
 package backend
 
 import (
+	"log/slog"
+
+	"github.com/pkg/errors"
 	"github.com/gomlx/gopjrt/pjrt"
 	"github.com/gx-org/backend"
 	"github.com/gx-org/backend/ops"
 	"github.com/gx-org/backend/platform"
 	"github.com/gx-org/gx/build/builder"
+	"github.com/gx-org/xlapjrt/backend/bucket"
 	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+	"github.com/gx-org/xlapjrt/backend/metrics"
 	pjrtplatform "github.com/gx-org/xlapjrt/backend/platform"
 )
 
 type pBackend struct {
-	plat *pjrtplatform.Platform
-	bld  *builder.Builder
+	plat    *pjrtplatform.Platform
+	bld     *builder.Builder
+	buckets *bucket.Buckets
+
+	hoistThreshold int
+	exportDir      string
+	checkShapes    bool
+
+	analyses analyses
+}
+
+// Option configures a PJRT backend.
+type Option func(*pBackend) error
+
+// WithShapeBuckets configures the sizes to which the leading axis of the inputs
+// of functions compiled with the bucket package are padded.
+// See bucket.NewFunc.
+func WithShapeBuckets(sizes ...int) Option {
+	return func(b *pBackend) error {
+		var err error
+		b.buckets, err = bucket.New(sizes...)
+		return err
+	}
+}
+
+// WithConstantHoisting turns constants of at least threshold bytes into hidden parameters
+// of the executables instead of embedding them in the XLA programs.
+// Hoisted constants are uploaded once to the device when a function is compiled.
+func WithConstantHoisting(threshold int) Option {
+	return func(b *pBackend) error {
+		if threshold <= 0 {
+			return errors.Errorf("invalid constant hoisting threshold %d: must be positive", threshold)
+		}
+		b.hoistThreshold = threshold
+		return nil
+	}
+}
+
+// WithGraphExport exports the graph of every compiled function in a directory
+// as GraphViz DOT and JSON files. See pjrtgraph.ExportEnv to enable the export
+// with an environment variable instead. Export failures are logged and do not
+// prevent the compilation.
+func WithGraphExport(dir string) Option {
+	return func(b *pBackend) error {
+		if dir == "" {
+			return errors.Errorf("invalid empty graph export directory")
+		}
+		b.exportDir = dir
+		return nil
+	}
+}
+
+// WithShapeChecks cross-checks the shapes inferred by the GX builtins with the shapes
+// inferred by XLA. A mismatch returns an error when the graph is built.
+// This is a debug mode meant for testing builtins.
+func WithShapeChecks() Option {
+	return func(b *pBackend) error {
+		b.checkShapes = true
+		return nil
+	}
+}
+
+// WithMetrics records the compilations, runs, and transfers of the backend.
+// See metrics.NewExpvar for an implementation publishing expvar variables.
+func WithMetrics(m metrics.Metrics) Option {
+	return func(b *pBackend) error {
+		if m == nil {
+			return errors.Errorf("invalid nil metrics")
+		}
+		b.plat.SetMetrics(m)
+		return nil
+	}
+}
+
+// WithLogger emits debug events for graph compilations, buffer transfers, and runs to a logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(b *pBackend) error {
+		if logger == nil {
+			return errors.Errorf("invalid nil logger")
+		}
+		b.plat.SetLogger(logger)
+		return nil
+	}
 }
 
 // New returns a new PJRT backend.
-func New(builder *builder.Builder, plugin *pjrt.Plugin) (backend.Backend, error) {
+func New(builder *builder.Builder, plugin *pjrt.Plugin, opts ...Option) (backend.Backend, error) {
 	client, err := plugin.NewClient(nil)
 	if err != nil {
 		return nil, err
 	}
-	return &pBackend{
+	b := &pBackend{
 		bld:  builder,
 		plat: pjrtplatform.New(client),
-	}, nil
+	}
+	for _, opt := range opts {
+		if err := opt(b); err != nil {
+			// The backend is not returned: release the client now.
+			if dErr := client.Destroy(); dErr != nil {
+				slog.Error("cannot destroy PJRT client", "error", dErr)
+			}
+			return nil, err
+		}
+	}
+	return b, nil
 }
 
 // Platform used by the backend.
@@ -54,10 +150,25 @@ func (b *pBackend) Builder() *builder.Builder {
 
 // NewGraph returns a new XLA computation graph.
 func (b *pBackend) NewOps(funcName string) (ops.Graph, error) {
-	return pjrtgraph.New(b.plat, funcName, nil)
+	g, err := pjrtgraph.New(b.plat, funcName, nil)
+	if err != nil {
+		return nil, err
+	}
+	pjrtg := g.(*pjrtgraph.Graph)
+	pjrtg.HoistConstants(b.hoistThreshold)
+	pjrtg.ExportTo(b.exportDir)
+	pjrtg.CheckShapes(b.checkShapes)
+	pjrtg.OnCompiled(b.analyses.record)
+	return g, nil
 }
 
 // Client returns the PJRT client of the backend.
 func (b *pBackend) Client() *pjrt.Client {
 	return b.plat.Client()
 }
+
+// ShapeBuckets returns the shape buckets configured for the backend.
+// It returns nil if no buckets have been configured.
+func (b *pBackend) ShapeBuckets() *bucket.Buckets {
+	return b.buckets
+}
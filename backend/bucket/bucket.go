@@ -0,0 +1,378 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bucket compiles GX functions once per shape bucket.
+//
+// Each distinct input shape requires to trace and compile a GX function.
+// To limit the number of compilations for inputs of variable lengths,
+// the leading axis of the inputs is padded with zeros up to the smallest
+// bucket size it fits in: the function is compiled once per bucket and
+// the true lengths are passed as extra arguments to the function.
+package bucket
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/backend/platform"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/api"
+	"github.com/gx-org/gx/api/tracer"
+	"github.com/gx-org/gx/api/values"
+	"github.com/gx-org/gx/build/ir"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+	pjrtplatform "github.com/gx-org/xlapjrt/backend/platform"
+)
+
+// Buckets are the sizes to which the leading axis of inputs are padded.
+type Buckets struct {
+	sizes []int
+}
+
+// New returns buckets given their sizes.
+func New(sizes ...int) (*Buckets, error) {
+	if len(sizes) == 0 {
+		return nil, errors.Errorf("at least one bucket size is required")
+	}
+	sorted := slices.Clone(sizes)
+	slices.Sort(sorted)
+	for i, size := range sorted {
+		if size <= 0 {
+			return nil, errors.Errorf("invalid bucket size %d: must be positive", size)
+		}
+		if i > 0 && sorted[i-1] == size {
+			return nil, errors.Errorf("bucket size %d is repeated", size)
+		}
+	}
+	return &Buckets{sizes: sorted}, nil
+}
+
+// Sizes returns the sorted bucket sizes.
+func (b *Buckets) Sizes() []int {
+	return slices.Clone(b.sizes)
+}
+
+// Size returns the smallest bucket size in which a length fits.
+func (b *Buckets) Size(length int) (int, error) {
+	i, _ := slices.BinarySearch(b.sizes, length)
+	if i == len(b.sizes) {
+		return 0, errors.Errorf("length %d does not fit in the largest bucket of size %d", length, b.sizes[len(b.sizes)-1])
+	}
+	return b.sizes[i], nil
+}
+
+// Stats are statistics about the compilations of a function.
+type Stats struct {
+	// Compilations is the number of times the function has been traced and compiled.
+	Compilations int
+	// Runs is the number of times the function has been run.
+	Runs int
+}
+
+// Func is a GX function compiled once per bucket.
+//
+// The function takes n arrays followed by n integer scalars:
+// the ith scalar is the true length of the leading axis of the ith array
+// before padding. Values beyond the true length are zeros and the function
+// is responsible for masking them if they change its results.
+//
+// Results declared with the leading axis of an array parameter
+// (e.g. [n]float32 for a parameter of type [_n]float32) are trimmed to
+// the true length of that parameter. Other results are returned unchanged.
+// Padding and trimming are done on the device.
+type Func struct {
+	dev     *api.Device
+	fn      *ir.FuncDecl
+	buckets *Buckets
+	params  []*ir.Field
+	// trims is the index of the array parameter to the length of which each result
+	// is trimmed, or -1 if the result is not trimmed.
+	trims   []int
+	resizer *resizer
+
+	mu       sync.Mutex
+	compiled map[string]tracer.CompiledFunc
+	stats    Stats
+}
+
+// ShapeBucketer is implemented by backends configured with shape buckets.
+type ShapeBucketer interface {
+	ShapeBuckets() *Buckets
+}
+
+// NewFunc returns a function compiled once per bucket configured in the runtime of a device.
+func NewFunc(dev *api.Device, fn *ir.FuncDecl) (*Func, error) {
+	bck, ok := dev.Runtime().Backend().(ShapeBucketer)
+	if !ok || bck.ShapeBuckets() == nil {
+		return nil, errors.Errorf("cannot compile %s with shape buckets: no shape buckets configured in the runtime", fn.Name())
+	}
+	return NewFuncWithBuckets(dev, fn, bck.ShapeBuckets())
+}
+
+// NewFuncWithBuckets returns a function compiled once per bucket.
+func NewFuncWithBuckets(dev *api.Device, fn *ir.FuncDecl, buckets *Buckets) (*Func, error) {
+	if fn.FType.ReceiverField() != nil {
+		return nil, errors.Errorf("cannot compile method %s with shape buckets: only functions are supported", fn.Name())
+	}
+	params := fn.FType.Params.Fields()
+	if len(params) == 0 || len(params)%2 != 0 {
+		return nil, errors.Errorf("cannot compile %s with shape buckets: got %d parameters but want n arrays followed by n lengths", fn.Name(), len(params))
+	}
+	for _, param := range params[len(params)/2:] {
+		if kind := param.Type().Kind(); kind != ir.Int32Kind && kind != ir.Int64Kind {
+			return nil, errors.Errorf("cannot compile %s with shape buckets: length parameter %s has type %s but want int32 or int64", fn.Name(), param.Name.Name, param.Type().String())
+		}
+	}
+	plat, ok := dev.Runtime().Backend().Platform().(*pjrtplatform.Platform)
+	if !ok {
+		return nil, errors.Errorf("cannot compile %s with shape buckets: platform %T is not a PJRT platform", fn.Name(), dev.Runtime().Backend().Platform())
+	}
+	return &Func{
+		dev:      dev,
+		fn:       fn,
+		buckets:  buckets,
+		params:   params,
+		trims:    resultTrims(fn, params[:len(params)/2]),
+		resizer:  newResizer(plat, dev.PlatformDevice()),
+		compiled: make(map[string]tracer.CompiledFunc),
+	}, nil
+}
+
+// leadingAxisName returns the name of the leading axis of an array type,
+// or nil if the type is not an array or if its leading axis is not named.
+func leadingAxisName(typ ir.Type) *ir.AxLengthName {
+	arrayType, ok := ir.Underlying(typ).(ir.ArrayType)
+	if !ok || arrayType.Rank() == nil {
+		return nil
+	}
+	axes := arrayType.Rank().Axes()
+	if len(axes) == 0 {
+		return nil
+	}
+	var axis ir.Expr = axes[0]
+	for {
+		switch axisT := axis.(type) {
+		case *ir.AxisInfer:
+			if axisT.X == nil {
+				return nil
+			}
+			axis = axisT.X
+		case *ir.AxisExpr:
+			axis = axisT.X
+		case *ir.ValueRef:
+			name, _ := axisT.Stor.(*ir.AxLengthName)
+			return name
+		default:
+			return nil
+		}
+	}
+}
+
+// resultTrims returns, for each result of a function, the index of the array parameter
+// defining the leading axis of the result, or -1 if the leading axis of the result
+// does not depend on the padded axis of a parameter.
+func resultTrims(fn *ir.FuncDecl, arrays []*ir.Field) []int {
+	results := fn.FType.Results.Fields()
+	trims := make([]int, len(results))
+	for i, result := range results {
+		trims[i] = -1
+		name := leadingAxisName(result.Type())
+		if name == nil {
+			continue
+		}
+		for j, array := range arrays {
+			if leadingAxisName(array.Type()) == name {
+				trims[i] = j
+				break
+			}
+		}
+	}
+	return trims
+}
+
+// Stats returns the compilation statistics of the function.
+func (f *Func) Stats() Stats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
+
+// resizer pads or trims the leading axis of arrays on a device.
+type resizer struct {
+	plat *pjrtplatform.Platform
+	dev  platform.Device
+
+	mu      sync.Mutex
+	runners map[string]ops.Runner
+}
+
+func newResizer(plat *pjrtplatform.Platform, dev platform.Device) *resizer {
+	return &resizer{
+		plat:    plat,
+		dev:     dev,
+		runners: make(map[string]ops.Runner),
+	}
+}
+
+// runner returns the executable resizing the leading axis of arrays of a given shape to a length.
+func (r *resizer) runner(src *shape.Shape, length int) (ops.Runner, error) {
+	key := fmt.Sprintf("%s->%d", src, length)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if runner, ok := r.runners[key]; ok {
+		return runner, nil
+	}
+	g, err := pjrtgraph.New(r.plat, "bucket.resize", nil)
+	if err != nil {
+		return nil, err
+	}
+	pg := g.(*pjrtgraph.Graph)
+	x, err := pg.Argument("x", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	zero, err := pg.Scalar(0, src.DType)
+	if err != nil {
+		return nil, err
+	}
+	// Padding the end of the leading axis with a negative length trims the axis.
+	axes := make([]pjrtgraph.PadAxis, len(src.AxisLengths))
+	axes[0].High = length - src.AxisLengths[0]
+	resized, err := pg.Pad(x, zero, axes)
+	if err != nil {
+		return nil, err
+	}
+	dst := &shape.Shape{
+		DType:       src.DType,
+		AxisLengths: append([]int{length}, src.AxisLengths[1:]...),
+	}
+	runner, err := g.Compile(r.dev, []*ops.OutputNode{{Node: resized, Shape: dst}}, nil, []*shape.Shape{src})
+	if err != nil {
+		return nil, err
+	}
+	r.runners[key] = runner
+	return runner, nil
+}
+
+// resize returns an array on the device with its leading axis padded with zeros or trimmed to a length.
+func (r *resizer) resize(typ ir.Type, array values.Array, length int) (*values.DeviceArray, error) {
+	devArray, err := array.ToDevice(r.dev)
+	if err != nil {
+		return nil, err
+	}
+	if array.Shape().AxisLengths[0] == length {
+		return devArray, nil
+	}
+	runner, err := r.runner(array.Shape(), length)
+	if err != nil {
+		return nil, err
+	}
+	outs, _, err := runner.Run([]platform.Handle{devArray.DeviceHandle()})
+	if err != nil {
+		return nil, err
+	}
+	return values.NewDeviceArray(typ, outs[0])
+}
+
+func lengthValue(typ ir.Type, length int) (values.Value, error) {
+	if typ.Kind() == ir.Int32Kind {
+		return values.AtomIntegerValue(typ, int32(length))
+	}
+	return values.AtomIntegerValue(typ, int64(length))
+}
+
+// bucketArgs pads the arrays passed to the function and returns the arguments
+// of the function, the true lengths of the arrays, and the key of the bucket.
+func (f *Func) bucketArgs(arrays []values.Value) ([]values.Value, []int, string, error) {
+	numArrays := len(f.params) / 2
+	if len(arrays) != numArrays {
+		return nil, nil, "", errors.Errorf("cannot run %s: got %d arrays but want %d", f.fn.Name(), len(arrays), numArrays)
+	}
+	args := make([]values.Value, 2*numArrays)
+	lengths := make([]int, numArrays)
+	var key strings.Builder
+	for i, arg := range arrays {
+		array, ok := arg.(values.Array)
+		if !ok {
+			return nil, nil, "", errors.Errorf("cannot run %s: argument %d is a %T but want an array", f.fn.Name(), i, arg)
+		}
+		sh := array.Shape()
+		if len(sh.AxisLengths) == 0 {
+			return nil, nil, "", errors.Errorf("cannot run %s: argument %d is a scalar", f.fn.Name(), i)
+		}
+		lengths[i] = sh.AxisLengths[0]
+		size, err := f.buckets.Size(lengths[i])
+		if err != nil {
+			return nil, nil, "", errors.Errorf("cannot run %s: argument %d: %v", f.fn.Name(), i, err)
+		}
+		if args[i], err = f.resizer.resize(f.params[i].Type(), array, size); err != nil {
+			return nil, nil, "", err
+		}
+		if args[numArrays+i], err = lengthValue(f.params[numArrays+i].Type(), lengths[i]); err != nil {
+			return nil, nil, "", err
+		}
+		fmt.Fprintf(&key, "%s%v;", sh.DType, append([]int{size}, sh.AxisLengths[1:]...))
+	}
+	return args, lengths, key.String(), nil
+}
+
+// compile returns the function compiled for a bucket.
+func (f *Func) compile(key string, args []values.Value) (tracer.CompiledFunc, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stats.Runs++
+	if compiled, ok := f.compiled[key]; ok {
+		return compiled, nil
+	}
+	compiled, err := tracer.Trace(f.dev, f.fn, nil, args, nil)
+	if err != nil {
+		return nil, err
+	}
+	f.compiled[key] = compiled
+	f.stats.Compilations++
+	return compiled, nil
+}
+
+// Run the function given its arrays. The lengths are computed from the arrays.
+func (f *Func) Run(arrays []values.Value) ([]values.Value, error) {
+	args, lengths, key, err := f.bucketArgs(arrays)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := f.compile(key, args)
+	if err != nil {
+		return nil, err
+	}
+	outs, err := compiled.Run(nil, args, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i, out := range outs {
+		if i >= len(f.trims) || f.trims[i] < 0 {
+			continue
+		}
+		array, ok := out.(values.Array)
+		if !ok {
+			continue
+		}
+		if outs[i], err = f.resizer.resize(array.Type(), array, lengths[f.trims[i]]); err != nil {
+			return nil, err
+		}
+	}
+	return outs, nil
+}
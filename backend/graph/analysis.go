@@ -0,0 +1,201 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"github.com/pkg/errors"
+	"github.com/gomlx/gopjrt/xlabuilder"
+)
+
+// Analysis is the cost and memory analysis of a compiled graph.
+//
+// The cost fields (Flops, BytesAccessed, and their per-iteration counterparts) are
+// estimates computed by GX from the XLA operations, not numbers reported by XLA.
+// The number of iterations of a loop is only known at run time: the operations of
+// loop conditions and bodies are counted once, in the per-iteration fields.
+type Analysis struct {
+	// Name of the function compiled by the graph.
+	Name string
+
+	// Flops is the estimated number of floating-point (or integer) operations of a run,
+	// excluding the operations of loops.
+	Flops int64
+	// BytesAccessed is the estimated number of bytes read and written by the operations of a run,
+	// excluding the operations of loops.
+	BytesAccessed int64
+	// LoopFlopsPerIteration is the estimated number of operations of one iteration of every loop
+	// (condition and body) of a run. Nested loops are also counted once.
+	LoopFlopsPerIteration int64
+	// LoopBytesAccessedPerIteration is the estimated number of bytes read and written by one
+	// iteration of every loop of a run. Nested loops are also counted once.
+	LoopBytesAccessedPerIteration int64
+
+	// ArgumentBytes is the size of the arguments, including hoisted constants, on the device.
+	ArgumentBytes int64
+	// OutputBytes is the size of the outputs on the device.
+	OutputBytes int64
+	// TempBytes is the size of the temporary buffers allocated on the device during a run.
+	TempBytes int64
+	// AliasBytes is the size of the outputs aliasing arguments.
+	AliasBytes int64
+	// GeneratedCodeBytes is the size of the code generated by XLA.
+	GeneratedCodeBytes int64
+	// PeakBytes is the estimated peak device memory required to run the executable.
+	PeakBytes int64
+
+	// NumOutputs is the number of outputs of the GX function.
+	NumOutputs int
+	// NumTraced is the number of values traced by the GX function.
+	NumTraced int
+	// NumHoisted is the number of constants hoisted into hidden parameters.
+	NumHoisted int
+	// NumSubcomputations is the number of XLA subcomputations built for the GX functions
+	// called by the graph (e.g. loop conditions and bodies).
+	NumSubcomputations int
+	// NumReusedSubcomputations is the number of times a subcomputation has been reused
+	// instead of being built again for another call site.
+	NumReusedSubcomputations int
+}
+
+// Analysis returns the cost and memory analysis of the graph once it has been compiled.
+//
+// The memory usage is reported by PJRT. The cost is estimated from the XLA operations
+// of the graph, following the XLA cost model for the main operations: element-wise
+// operations cost one operation per element, reductions one operation per input element,
+// and dot products two operations (a multiplication and an addition) per multiplied pair.
+// The operations of called subcomputations are counted at every call site. The operations
+// of loop conditions and bodies are counted once per loop as the cost of an iteration.
+func (g *Graph) Analysis() (*Analysis, error) {
+	if g.executable == nil {
+		return nil, errors.Errorf("cannot analyse function %s: graph has not been compiled", g.builder.Name())
+	}
+	mem := g.executable.OnDeviceMemoryUsageStats
+	c := g.computationCost(g.root)
+	a := &Analysis{
+		Name:                          g.builder.Name(),
+		Flops:                         c.flops,
+		BytesAccessed:                 c.bytes,
+		LoopFlopsPerIteration:         c.loopFlops,
+		LoopBytesAccessedPerIteration: c.loopBytes,
+		ArgumentBytes:                 mem.Inputs,
+		OutputBytes:                   mem.Outputs,
+		TempBytes:                     mem.Temporary,
+		AliasBytes:                    mem.Aliases,
+		GeneratedCodeBytes:            mem.GeneratedCode,
+		PeakBytes:                     mem.Requirements(),
+		NumOutputs:                    len(g.out),
+		NumTraced:                     len(g.traced),
+		NumHoisted:                    len(g.hoisted),
+
+		NumSubcomputations:       g.subcomps.stats.Built,
+		NumReusedSubcomputations: g.subcomps.stats.Reused,
+	}
+	return a, nil
+}
+
+// cost is the estimated cost of a computation.
+type cost struct {
+	// flops and bytes are the cost of the operations outside loops.
+	flops, bytes int64
+	// loopFlops and loopBytes are the cost of one iteration of the loops.
+	loopFlops, loopBytes int64
+}
+
+// computationCost returns the estimated cost of the computation of an operation.
+func (g *Graph) computationCost(root *xlabuilder.Op) cost {
+	var c cost
+	visited := make(map[*xlabuilder.Op]bool)
+	var visit func(op *xlabuilder.Op)
+	visit = func(op *xlabuilder.Op) {
+		if op == nil || visited[op] {
+			return
+		}
+		visited[op] = true
+		for _, input := range op.OpInputs {
+			visit(input)
+		}
+		flops, bytes := opCost(op)
+		c.flops += flops
+		c.bytes += bytes
+		switch op.Type {
+		case xlabuilder.CallOp:
+			sub := g.subcomputationCost(op.ComputationArg)
+			c.flops += sub.flops
+			c.bytes += sub.bytes
+			c.loopFlops += sub.loopFlops
+			c.loopBytes += sub.loopBytes
+		case xlabuilder.WhileOp:
+			for _, comp := range []*xlabuilder.XlaComputation{op.ComputationArg, op.SecondComputationArg} {
+				sub := g.subcomputationCost(comp)
+				c.loopFlops += sub.flops + sub.loopFlops
+				c.loopBytes += sub.bytes + sub.loopBytes
+			}
+		}
+	}
+	visit(root)
+	return c
+}
+
+// subcomputationCost returns the estimated cost of a subcomputation built for a subgraph.
+// The cost of subcomputations unknown to the graph is 0.
+func (g *Graph) subcomputationCost(comp *xlabuilder.XlaComputation) cost {
+	key, ok := g.subcomps.keys[comp]
+	if !ok {
+		return cost{}
+	}
+	sub := g.subcomps.built[key]
+	return g.computationCost(sub.graph.xlaHandle(sub.out))
+}
+
+// opCost returns the estimated number of operations and bytes accessed by an XLA operation.
+func opCost(op *xlabuilder.Op) (flops, bytes int64) {
+	switch op.Type {
+	case xlabuilder.ParameterOp, xlabuilder.ConstantOp, xlabuilder.TupleOp, xlabuilder.GetTupleElementOp, xlabuilder.IdentityOp:
+		// No computation and no data movement.
+		return 0, 0
+	}
+	bytes = int64(op.Shape.Memory())
+	for _, input := range op.OpInputs {
+		bytes += int64(input.Shape.Memory())
+	}
+	switch op.Type {
+	case xlabuilder.IotaOp, xlabuilder.ReshapeOp, xlabuilder.BroadcastOp, xlabuilder.BroadcastInDimOp,
+		xlabuilder.TransposeOp, xlabuilder.ConcatenateOp, xlabuilder.SliceOp, xlabuilder.DynamicSliceOp,
+		xlabuilder.DynamicUpdateSliceOp, xlabuilder.PadOp, xlabuilder.ReverseOp, xlabuilder.GatherOp,
+		xlabuilder.ConvertDTypeOp, xlabuilder.BitcastOp, xlabuilder.CallOp, xlabuilder.WhileOp:
+		// Data movement only.
+		return 0, bytes
+	case xlabuilder.ReduceOp, xlabuilder.ArgMinMaxOp, xlabuilder.ReduceWindowOp:
+		if len(op.OpInputs) > 0 {
+			flops = int64(op.OpInputs[0].Shape.Size())
+		}
+		return flops, bytes
+	case xlabuilder.DotGeneralOp:
+		return dotFlops(op), bytes
+	}
+	// Element-wise operation.
+	return int64(op.Shape.Size()), bytes
+}
+
+// dotFlops returns the number of operations of a dot product:
+// each element of the output is a sum of products over the contracted axes.
+func dotFlops(op *xlabuilder.Op) int64 {
+	lhs, lhsContractingAxes, _, _, _, _ := xlabuilder.DecodeDotGeneral(op)
+	contracted := int64(1)
+	for _, axis := range lhsContractingAxes {
+		contracted *= int64(lhs.Shape.Dimensions[axis])
+	}
+	return 2 * int64(op.Shape.Size()) * contracted
+}
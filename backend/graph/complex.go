@@ -0,0 +1,146 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"go/token"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/xlabuilder"
+	"github.com/gx-org/backend/ops"
+)
+
+// GX does not have complex data types: complex arrays are passed to and returned by
+// the graph as two arrays of the same shape, the real part and the imaginary part.
+// The graph builds XLA complex arrays from these parts, so that complex operations
+// are computed by XLA.
+
+// complexDType returns the complex data type with the precision of a real data type.
+func complexDType(real dtypes.DType) (dtypes.DType, error) {
+	switch real {
+	case dtypes.Float32:
+		return dtypes.Complex64, nil
+	case dtypes.Float64:
+		return dtypes.Complex128, nil
+	}
+	return dtypes.InvalidDType, errors.Errorf("complex numbers with %s parts not supported: only float32 and float64 are supported", real)
+}
+
+// toComplex returns a complex array given its real and imaginary parts.
+func toComplex(re, im *xlabuilder.Op) (*xlabuilder.Op, error) {
+	if _, err := complexDType(re.Shape.DType); err != nil {
+		return nil, err
+	}
+	if re.Shape.DType != im.Shape.DType || !slices.Equal(re.Shape.Dimensions, im.Shape.Dimensions) {
+		return nil, errors.Errorf("real part %s and imaginary part %s have different shapes", re.Shape, im.Shape)
+	}
+	return xlabuilder.Complex(re, im)
+}
+
+// fromComplex returns the real and imaginary parts of a complex array.
+func (g *Graph) fromComplex(x *xlabuilder.Op, deps ...ops.Node) (re, im ops.Node, err error) {
+	reOp, err := xlabuilder.Real(x)
+	if err != nil {
+		return nil, nil, err
+	}
+	imOp, err := xlabuilder.Imag(x)
+	if err != nil {
+		return nil, nil, err
+	}
+	return g.newNode(reOp, deps...), g.newNode(imOp, deps...), nil
+}
+
+// ComplexBinary returns the real and imaginary parts of a binary arithmetic operator
+// applied to two complex arrays.
+func (g *Graph) ComplexBinary(op token.Token, xRe, xIm, yRe, yIm ops.Node) (re, im ops.Node, err error) {
+	var f func(x, y *xlabuilder.Op) (*xlabuilder.Op, error)
+	switch op {
+	case token.ADD:
+		f = xlabuilder.Add
+	case token.SUB:
+		f = xlabuilder.Sub
+	case token.MUL:
+		f = xlabuilder.Mul
+	case token.QUO:
+		f = xlabuilder.Div
+	default:
+		return nil, nil, g.opError("ComplexBinary", errors.Errorf("operator %s not supported on complex numbers", op), xRe, xIm, yRe, yIm)
+	}
+	x, err := toComplex(g.xlaHandle(xRe), g.xlaHandle(xIm))
+	if err != nil {
+		return nil, nil, g.opError("ComplexBinary", err, xRe, xIm, yRe, yIm)
+	}
+	y, err := toComplex(g.xlaHandle(yRe), g.xlaHandle(yIm))
+	if err != nil {
+		return nil, nil, g.opError("ComplexBinary", err, xRe, xIm, yRe, yIm)
+	}
+	out, err := f(x, y)
+	if err != nil {
+		return nil, nil, g.opError("ComplexBinary", err, xRe, xIm, yRe, yIm)
+	}
+	if re, im, err = g.fromComplex(out, xRe, xIm, yRe, yIm); err != nil {
+		return nil, nil, g.opError("ComplexBinary", err, xRe, xIm, yRe, yIm)
+	}
+	return re, im, nil
+}
+
+// Conj returns the real and imaginary parts of the complex conjugate of a complex array.
+func (g *Graph) Conj(re, im ops.Node) (outRe, outIm ops.Node, err error) {
+	x, err := toComplex(g.xlaHandle(re), g.xlaHandle(im))
+	if err != nil {
+		return nil, nil, g.opError("Conj", err, re, im)
+	}
+	out, err := xlabuilder.Conj(x)
+	if err != nil {
+		return nil, nil, g.opError("Conj", err, re, im)
+	}
+	if outRe, outIm, err = g.fromComplex(out, re, im); err != nil {
+		return nil, nil, g.opError("Conj", err, re, im)
+	}
+	return outRe, outIm, nil
+}
+
+// ComplexAbs returns the modulus of a complex array.
+func (g *Graph) ComplexAbs(re, im ops.Node) (ops.Node, error) {
+	x, err := toComplex(g.xlaHandle(re), g.xlaHandle(im))
+	if err != nil {
+		return nil, g.opError("ComplexAbs", err, re, im)
+	}
+	out, err := xlabuilder.Abs(x)
+	if err != nil {
+		return nil, g.opError("ComplexAbs", err, re, im)
+	}
+	return g.newNode(out, re, im), nil
+}
+
+// ComplexAngle returns the argument of a complex array in the range [-Pi, Pi].
+// The argument is the imaginary part of the principal value of the complex logarithm.
+func (g *Graph) ComplexAngle(re, im ops.Node) (ops.Node, error) {
+	x, err := toComplex(g.xlaHandle(re), g.xlaHandle(im))
+	if err != nil {
+		return nil, g.opError("ComplexAngle", err, re, im)
+	}
+	log, err := xlabuilder.Log(x)
+	if err != nil {
+		return nil, g.opError("ComplexAngle", err, re, im)
+	}
+	out, err := xlabuilder.Imag(log)
+	if err != nil {
+		return nil, g.opError("ComplexAngle", err, re, im)
+	}
+	return g.newNode(out, re, im), nil
+}
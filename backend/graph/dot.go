@@ -0,0 +1,250 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/xlabuilder"
+	"github.com/gx-org/backend/dtype"
+	"github.com/gx-org/backend/ops"
+	pjrtplatform "github.com/gx-org/xlapjrt/backend/platform"
+	pjrtgx "github.com/gx-org/xlapjrt"
+)
+
+// Precision of the computation of a dot product.
+type Precision int
+
+const (
+	// DefaultPrecision lets the backend choose the fastest computation.
+	DefaultPrecision Precision = iota
+	// HighPrecision computes low precision floating-point operands (bfloat16 and float16)
+	// in float32. Float32 and float64 operands are not affected: they are computed
+	// with the default precision of the backend.
+	//
+	// gopjrt does not expose the XLA precision configuration of dot products:
+	// a higher precision for float32 operands (XLA HIGHEST) is not supported.
+	HighPrecision
+)
+
+func (p Precision) String() string {
+	switch p {
+	case DefaultPrecision:
+		return "default"
+	case HighPrecision:
+		return "high"
+	}
+	return "invalid"
+}
+
+// DotConfig configures the computation of a dot product.
+type DotConfig struct {
+	// Precision of the computation.
+	Precision Precision
+	// OutputType is the data type of the result.
+	// OutputType is a pre-cast: the operands are converted to OutputType before
+	// the product is computed (for example, bfloat16 operands are multiplied and
+	// accumulated in float32). The product is not computed in the data type of
+	// the operands and then converted.
+	// dtype.Invalid keeps the data type of the operands.
+	OutputType dtype.DataType
+}
+
+// dataTypes returns the data type in which a product is computed and the data type of its result
+// given the data type of the operands.
+func (cfg *DotConfig) dataTypes(operand dtypes.DType) (compute, out dtypes.DType, err error) {
+	compute, out = operand, operand
+	if cfg == nil {
+		return
+	}
+	if cfg.OutputType != dtype.Invalid {
+		if out = pjrtgx.ToDType(cfg.OutputType); out == dtypes.InvalidDType {
+			return compute, out, pjrtplatform.NewError(pjrtplatform.ErrUnsupportedDType, nil, "cannot convert %s to a XLA data type", cfg.OutputType)
+		}
+		compute = out
+	}
+	if cfg.Precision != DefaultPrecision && (compute == dtypes.BFloat16 || compute == dtypes.Float16) {
+		compute = dtypes.Float32
+	}
+	return
+}
+
+func convertDType(op *xlabuilder.Op, target dtypes.DType) (*xlabuilder.Op, error) {
+	if op.Shape.DType == target {
+		return op, nil
+	}
+	return xlabuilder.ConvertDType(op, target)
+}
+
+// DotGeneralWithConfig returns a generic dot product node computed as specified by a configuration.
+// See DotGeneral for a description of the batch and reduce axes.
+func (g *Graph) DotGeneralWithConfig(x, y ops.Node, batchAxes, reduceAxes [2][]int, cfg *DotConfig) (ops.Node, error) {
+	xlaOp, err := g.dotGeneral(g.xlaHandle(x), g.xlaHandle(y), batchAxes, reduceAxes, cfg)
+	if err != nil {
+		return nil, g.opError("DotGeneral", err, x, y)
+	}
+	return g.newNode(xlaOp, x, y), nil
+}
+
+func (g *Graph) dotGeneral(x, y *xlabuilder.Op, batchAxes, reduceAxes [2][]int, cfg *DotConfig) (*xlabuilder.Op, error) {
+	compute, out, err := cfg.dataTypes(x.Shape.DType)
+	if err != nil {
+		return nil, err
+	}
+	if x, err = convertDType(x, compute); err != nil {
+		return nil, err
+	}
+	if y, err = convertDType(y, compute); err != nil {
+		return nil, err
+	}
+	xlaOp, err := xlabuilder.DotGeneral(
+		x, reduceAxes[0], batchAxes[0],
+		y, reduceAxes[1], batchAxes[1])
+	if err != nil {
+		return nil, err
+	}
+	return convertDType(xlaOp, out)
+}
+
+// MatMulAxisLengths returns the axis lengths of the result of MatMul given the axis lengths of its operands.
+func MatMulAxisLengths(x, y []int) ([]int, error) {
+	if len(x) == 0 || len(y) == 0 {
+		return nil, errors.Errorf("cannot multiply scalars: operands have %d and %d axes", len(x), len(y))
+	}
+	xMat, yMat := x, y
+	if len(x) == 1 {
+		xMat = []int{1, x[0]}
+	}
+	if len(y) == 1 {
+		yMat = []int{y[0], 1}
+	}
+	if xMat[len(xMat)-1] != yMat[len(yMat)-2] {
+		return nil, errors.Errorf("cannot multiply arrays with axis lengths %v and %v: contracting axes have different lengths", x, y)
+	}
+	batch, err := broadcastBatch(xMat[:len(xMat)-2], yMat[:len(yMat)-2])
+	if err != nil {
+		return nil, errors.Errorf("cannot multiply arrays with axis lengths %v and %v: %v", x, y, err)
+	}
+	out := batch
+	if len(x) > 1 {
+		out = append(out, x[len(x)-2])
+	}
+	if len(y) > 1 {
+		out = append(out, y[len(y)-1])
+	}
+	return out, nil
+}
+
+// broadcastBatch returns the batch axis lengths broadcasting the batch axes of two operands.
+// Batch axes are aligned on the last axis. Missing axes or axes of length 1 are broadcast.
+func broadcastBatch(x, y []int) ([]int, error) {
+	batch := make([]int, max(len(x), len(y)))
+	for i := range batch {
+		xi, yi := 1, 1
+		if j := len(x) - len(batch) + i; j >= 0 {
+			xi = x[j]
+		}
+		if j := len(y) - len(batch) + i; j >= 0 {
+			yi = y[j]
+		}
+		switch {
+		case xi == yi || yi == 1:
+			batch[i] = xi
+		case xi == 1:
+			batch[i] = yi
+		default:
+			return nil, errors.Errorf("batch axes of lengths %d and %d cannot be broadcast", xi, yi)
+		}
+	}
+	return batch, nil
+}
+
+// broadcastToBatch broadcasts a matrix, or a batch of matrices, to the given batch axis lengths.
+func broadcastToBatch(x *xlabuilder.Op, batch []int) (*xlabuilder.Op, error) {
+	dims := x.Shape.Dimensions
+	target := append(append([]int{}, batch...), dims[len(dims)-2:]...)
+	axes := make([]int, len(dims))
+	for i := range axes {
+		axes[i] = len(target) - len(dims) + i
+	}
+	return xlabuilder.BroadcastInDim(x, xlabuilder.MakeShape(x.Shape.DType, target...), axes)
+}
+
+// MatMul returns a node multiplying two matrices following the numpy matmul semantic:
+// the last two axes of the operands are multiplied as matrices while the other axes are
+// batch axes broadcast against each other.
+// An operand with a single axis is a vector: a matrix with one row on the left, one column on the right,
+// and that axis is removed from the result.
+func (g *Graph) MatMul(x, y ops.Node, cfg *DotConfig) (ops.Node, error) {
+	xlaOp, err := g.matMul(x, y, cfg)
+	if err != nil {
+		return nil, g.opError("MatMul", err, x, y)
+	}
+	return g.newNode(xlaOp, x, y), nil
+}
+
+func (g *Graph) matMul(x, y ops.Node, cfg *DotConfig) (*xlabuilder.Op, error) {
+	xOp, yOp := g.xlaHandle(x), g.xlaHandle(y)
+	xDims, yDims := xOp.Shape.Dimensions, yOp.Shape.Dimensions
+	out, err := MatMulAxisLengths(xDims, yDims)
+	if err != nil {
+		return nil, err
+	}
+	if len(xDims) == 1 {
+		if xOp, err = xlabuilder.Reshape(xOp, 1, xDims[0]); err != nil {
+			return nil, err
+		}
+	}
+	if len(yDims) == 1 {
+		if yOp, err = xlabuilder.Reshape(yOp, yDims[0], 1); err != nil {
+			return nil, err
+		}
+	}
+	xBatch := xOp.Shape.Dimensions[:xOp.Shape.Rank()-2]
+	yBatch := yOp.Shape.Dimensions[:yOp.Shape.Rank()-2]
+	batch, err := broadcastBatch(xBatch, yBatch)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Equal(xBatch, batch) {
+		if xOp, err = broadcastToBatch(xOp, batch); err != nil {
+			return nil, err
+		}
+	}
+	if !slices.Equal(yBatch, batch) {
+		if yOp, err = broadcastToBatch(yOp, batch); err != nil {
+			return nil, err
+		}
+	}
+	batchAxes := make([]int, len(batch))
+	for i := range batchAxes {
+		batchAxes[i] = i
+	}
+	xlaOp, err := g.dotGeneral(xOp, yOp,
+		[2][]int{batchAxes, batchAxes},
+		[2][]int{{len(batch) + 1}, {len(batch)}},
+		cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Equal(xlaOp.Shape.Dimensions, out) {
+		if xlaOp, err = xlabuilder.Reshape(xlaOp, out...); err != nil {
+			return nil, err
+		}
+	}
+	return xlaOp, nil
+}
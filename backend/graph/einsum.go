@@ -0,0 +1,340 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/xlabuilder"
+	"github.com/gx-org/backend/ops"
+)
+
+// EinsumSpec is a parsed einsum specification, for example "bij,bjk->bik".
+type EinsumSpec struct {
+	// Operands are the labels of the axes of each operand.
+	Operands [][]rune
+	// Output are the labels of the axes of the result.
+	Output []rune
+}
+
+// EinsumAxis is an axis of an operand of an einsum.
+type EinsumAxis struct {
+	Operand, Axis int
+}
+
+// ParseEinsum parses an einsum specification given the number of axes of each operand.
+//
+// The specification lists the axis labels of each operand separated by commas,
+// optionally followed by "->" and the axis labels of the result.
+// Labels are letters. A label repeated in an operand takes the diagonal of that operand.
+// Labels absent from the result are summed over.
+// Without "->", the result has the labels appearing exactly once, in alphabetical order.
+func ParseEinsum(spec string, ranks []int) (*EinsumSpec, error) {
+	spec = strings.Join(strings.Fields(spec), "")
+	inputs, output, explicit := strings.Cut(spec, "->")
+	operands := strings.Split(inputs, ",")
+	if len(operands) != len(ranks) {
+		return nil, errors.Errorf("einsum specification %q has %d operands but got %d arrays", spec, len(operands), len(ranks))
+	}
+	s := &EinsumSpec{Operands: make([][]rune, len(operands))}
+	counts := make(map[rune]int)
+	for i, operand := range operands {
+		labels, err := parseEinsumLabels(spec, operand)
+		if err != nil {
+			return nil, err
+		}
+		if len(labels) != ranks[i] {
+			return nil, errors.Errorf("einsum specification %q has %d labels for operand %d but the array has %d axes", spec, len(labels), i, ranks[i])
+		}
+		for _, label := range labels {
+			counts[label]++
+		}
+		s.Operands[i] = labels
+	}
+	if !explicit {
+		for label, count := range counts {
+			if count == 1 {
+				s.Output = append(s.Output, label)
+			}
+		}
+		slices.Sort(s.Output)
+		return s, nil
+	}
+	var err error
+	if s.Output, err = parseEinsumLabels(spec, output); err != nil {
+		return nil, err
+	}
+	for i, label := range s.Output {
+		if counts[label] == 0 {
+			return nil, errors.Errorf("einsum specification %q: output label %q does not appear in the operands", spec, label)
+		}
+		if slices.Contains(s.Output[:i], label) {
+			return nil, errors.Errorf("einsum specification %q: output label %q appears more than once", spec, label)
+		}
+	}
+	return s, nil
+}
+
+func parseEinsumLabels(spec, labels string) ([]rune, error) {
+	if strings.Contains(labels, ".") {
+		return nil, errors.Errorf("einsum specification %q: ellipsis not supported", spec)
+	}
+	runes := []rune(labels)
+	for _, label := range runes {
+		if !('a' <= label && label <= 'z') && !('A' <= label && label <= 'Z') {
+			return nil, errors.Errorf("einsum specification %q: invalid label %q", spec, label)
+		}
+	}
+	return runes, nil
+}
+
+func (s *EinsumSpec) String() string {
+	operands := make([]string, len(s.Operands))
+	for i, labels := range s.Operands {
+		operands[i] = string(labels)
+	}
+	return strings.Join(operands, ",") + "->" + string(s.Output)
+}
+
+// Occurrences returns all the axes of the operands with a given label.
+func (s *EinsumSpec) Occurrences(label rune) []EinsumAxis {
+	var axes []EinsumAxis
+	for operand, labels := range s.Operands {
+		for axis, l := range labels {
+			if l == label {
+				axes = append(axes, EinsumAxis{Operand: operand, Axis: axis})
+			}
+		}
+	}
+	return axes
+}
+
+// Labels returns all the labels of the operands in order of appearance.
+func (s *EinsumSpec) Labels() []rune {
+	var labels []rune
+	for _, operand := range s.Operands {
+		for _, label := range operand {
+			if !slices.Contains(labels, label) {
+				labels = append(labels, label)
+			}
+		}
+	}
+	return labels
+}
+
+// EinsumOutput returns the axes of the result of an einsum given the axes of its operands.
+func EinsumOutput[T any](s *EinsumSpec, operands [][]T) []T {
+	out := make([]T, len(s.Output))
+	for i, label := range s.Output {
+		ax := s.Occurrences(label)[0]
+		out[i] = operands[ax.Operand][ax.Axis]
+	}
+	return out
+}
+
+// needed returns true if a label is in the result or in an operand after the given one.
+func (s *EinsumSpec) needed(label rune, operand int) bool {
+	if slices.Contains(s.Output, label) {
+		return true
+	}
+	for _, labels := range s.Operands[operand+1:] {
+		if slices.Contains(labels, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// labelledOp is a XLA operation with a label for each axis.
+type labelledOp struct {
+	op     *xlabuilder.Op
+	labels []rune
+}
+
+// diagonals takes the diagonal of all the axes sharing the same label.
+func (x *labelledOp) diagonals() error {
+	for {
+		i, j := -1, -1
+		for axis, label := range x.labels {
+			if first := slices.Index(x.labels, label); first != axis {
+				i, j = first, axis
+				break
+			}
+		}
+		if i < 0 {
+			return nil
+		}
+		mask, err := diagonalMask(x.op, i, j)
+		if err != nil {
+			return err
+		}
+		zero, err := xlabuilder.ScalarZero(x.op.Builder(), x.op.Shape.DType)
+		if err != nil {
+			return err
+		}
+		if zero, err = xlabuilder.Broadcast(zero, x.op.Shape.Dimensions...); err != nil {
+			return err
+		}
+		if x.op, err = xlabuilder.Where(mask, x.op, zero); err != nil {
+			return err
+		}
+		if x.op, err = xlabuilder.ReduceSum(x.op, j); err != nil {
+			return err
+		}
+		x.labels = slices.Delete(x.labels, j, j+1)
+	}
+}
+
+// diagonalMask returns a boolean mask, of the same shape as x, true when the index along
+// axis i is equal to the index along axis j.
+func diagonalMask(x *xlabuilder.Op, i, j int) (*xlabuilder.Op, error) {
+	shape := xlabuilder.MakeShape(dtypes.Int32, x.Shape.Dimensions...)
+	iota0, err := xlabuilder.Iota(x.Builder(), shape, i)
+	if err != nil {
+		return nil, err
+	}
+	iota1, err := xlabuilder.Iota(x.Builder(), shape, j)
+	if err != nil {
+		return nil, err
+	}
+	return xlabuilder.Equal(iota0, iota1)
+}
+
+// sum sums over all the axes for which drop returns true.
+func (x *labelledOp) sum(drop func(rune) bool) error {
+	var axes []int
+	var kept []rune
+	for axis, label := range x.labels {
+		if drop(label) {
+			axes = append(axes, axis)
+			continue
+		}
+		kept = append(kept, label)
+	}
+	if len(axes) == 0 {
+		return nil
+	}
+	var err error
+	if x.op, err = xlabuilder.ReduceSum(x.op, axes...); err != nil {
+		return err
+	}
+	x.labels = kept
+	return nil
+}
+
+// checkEinsumAxisLengths checks that all the axes with the same label have the same length.
+func checkEinsumAxisLengths(spec *EinsumSpec, operands []*xlabuilder.Op) error {
+	for _, label := range spec.Labels() {
+		occurrences := spec.Occurrences(label)
+		first := occurrences[0]
+		want := operands[first.Operand].Shape.Dimensions[first.Axis]
+		for _, ax := range occurrences[1:] {
+			if got := operands[ax.Operand].Shape.Dimensions[ax.Axis]; got != want {
+				return errors.Errorf("einsum label %q has inconsistent axis lengths %d and %d", label, want, got)
+			}
+		}
+	}
+	return nil
+}
+
+// Einsum returns a node computing the Einstein summation of the operands as specified by spec.
+// Operands are contracted left to right with generic dot products computed as specified by cfg.
+func (g *Graph) Einsum(spec *EinsumSpec, operands []ops.Node, cfg *DotConfig) (ops.Node, error) {
+	if len(operands) != len(spec.Operands) {
+		return nil, g.opError("Einsum", errors.Errorf("einsum specification has %d operands but got %d arrays", len(spec.Operands), len(operands)), operands...)
+	}
+	xlaOps, err := g.xlaHandles(operands)
+	if err != nil {
+		return nil, g.opError("Einsum", err, operands...)
+	}
+	if err := checkEinsumAxisLengths(spec, xlaOps); err != nil {
+		return nil, g.opError("Einsum", err, operands...)
+	}
+	var acc *labelledOp
+	for i, xlaOp := range xlaOps {
+		x := &labelledOp{op: xlaOp, labels: slices.Clone(spec.Operands[i])}
+		if err := x.diagonals(); err != nil {
+			return nil, g.opError("Einsum", err, operands...)
+		}
+		if err := x.sum(func(label rune) bool {
+			return !spec.needed(label, i) && (acc == nil || !slices.Contains(acc.labels, label))
+		}); err != nil {
+			return nil, g.opError("Einsum", err, operands...)
+		}
+		if acc == nil {
+			acc = x
+			continue
+		}
+		if acc, err = g.einsumDot(spec, i, acc, x, cfg); err != nil {
+			return nil, g.opError("Einsum", err, operands...)
+		}
+	}
+	if len(xlaOps) == 1 {
+		_, out, err := cfg.dataTypes(acc.op.Shape.DType)
+		if err != nil {
+			return nil, g.opError("Einsum", err, operands...)
+		}
+		if acc.op, err = convertDType(acc.op, out); err != nil {
+			return nil, g.opError("Einsum", err, operands...)
+		}
+	}
+	permutation := make([]int, len(spec.Output))
+	for i, label := range spec.Output {
+		permutation[i] = slices.Index(acc.labels, label)
+	}
+	if !slices.IsSorted(permutation) {
+		if acc.op, err = xlabuilder.Transpose(acc.op, permutation...); err != nil {
+			return nil, g.opError("Einsum", err, operands...)
+		}
+	}
+	return g.newNode(acc.op, operands...).Info("%s", spec), nil
+}
+
+// einsumDot computes the dot product of the accumulated result with the given operand.
+// Labels shared by both sides are batch axes if they are needed later, contracted otherwise.
+func (g *Graph) einsumDot(spec *EinsumSpec, operand int, x, y *labelledOp, cfg *DotConfig) (*labelledOp, error) {
+	var batchAxes, reduceAxes [2][]int
+	var batchLabels []rune
+	for xAxis, label := range x.labels {
+		yAxis := slices.Index(y.labels, label)
+		if yAxis < 0 {
+			continue
+		}
+		if spec.needed(label, operand) {
+			batchAxes[0] = append(batchAxes[0], xAxis)
+			batchAxes[1] = append(batchAxes[1], yAxis)
+			batchLabels = append(batchLabels, label)
+			continue
+		}
+		reduceAxes[0] = append(reduceAxes[0], xAxis)
+		reduceAxes[1] = append(reduceAxes[1], yAxis)
+	}
+	labels := batchLabels
+	for _, side := range []*labelledOp{x, y} {
+		for _, label := range side.labels {
+			if !slices.Contains(x.labels, label) || !slices.Contains(y.labels, label) {
+				labels = append(labels, label)
+			}
+		}
+	}
+	op, err := g.dotGeneral(x.op, y.op, batchAxes, reduceAxes, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &labelledOp{op: op, labels: labels}, nil
+}
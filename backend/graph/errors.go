@@ -0,0 +1,121 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/build/fmterr"
+)
+
+// OpError is an error returned when an operation cannot be added to a graph.
+// Use OpErrorOf to inspect it with its GX source position.
+type OpError struct {
+	// Pos is the position in the GX source of the expression building the operation.
+	// Pos is invalid if the position is unknown.
+	Pos token.Position
+	// Src is the GX expression building the operation if the GX interpreter passed it to the graph.
+	// It is used to resolve Pos once the file set of the expression is known.
+	Src ast.Node
+	// Graph is the name of the graph in which the operation was added.
+	Graph string
+	// Op is the name of the operation.
+	Op string
+	// Operands are the shapes of the operands of the operation, as inferred by XLA.
+	Operands []*shape.Shape
+	// Err is the error returned by XLA.
+	Err error
+}
+
+// Error returns the error in a compiler-style format:
+//
+//	file.gx:line:column: cannot build Op(shapes) in graph: error
+func (err *OpError) Error() string {
+	bld := strings.Builder{}
+	if err.Pos.IsValid() {
+		bld.WriteString(err.Pos.String() + ": ")
+	}
+	operands := make([]string, len(err.Operands))
+	for i, operand := range err.Operands {
+		operands[i] = operand.String()
+	}
+	fmt.Fprintf(&bld, "cannot build %s(%s) in %s: %v", err.Op, strings.Join(operands, ", "), err.Graph, err.Err)
+	return bld.String()
+}
+
+// Unwrap returns the error returned by XLA.
+func (err *OpError) Unwrap() error {
+	return err.Err
+}
+
+// opError wraps an error returned when building an operation.
+func (g *Graph) opError(op string, err error, operands ...ops.Node) error {
+	return g.opErrorAt(nil, op, err, operands...)
+}
+
+// opErrorAt wraps an error returned when building an operation given the GX expression building it.
+func (g *Graph) opErrorAt(src ast.Node, op string, err error, operands ...ops.Node) error {
+	opErr := &OpError{Src: src, Graph: g.builder.Name(), Op: op, Err: err}
+	for _, operand := range operands {
+		if node, ok := operand.(pjrtNode); ok {
+			opErr.Operands = append(opErr.Operands, node.BackendShape())
+		}
+	}
+	return opErr
+}
+
+// AtPosition sets the GX source position of an operation error if it does not have one yet.
+// Errors which are not operation errors are returned unchanged.
+func AtPosition(fset *token.FileSet, src ast.Node, err error) error {
+	var opErr *OpError
+	if fset == nil || src == nil || !errors.As(err, &opErr) || opErr.Pos.IsValid() {
+		return err
+	}
+	if opErr.Src != nil {
+		src = opErr.Src
+	}
+	opErr.Pos = fset.Position(src.Pos())
+	return err
+}
+
+// OpErrorOf returns the operation error wrapped by err, if any.
+//
+// The GX interpreter calls core operations (e.g. Reshape or DotGeneral) without passing
+// their position to the graph. Instead, it attaches the position of the GX expression to the
+// error it returns. OpErrorOf sets the position of the operation error from the closest position
+// attached to the error if the operation error does not have one yet.
+func OpErrorOf(err error) (*OpError, bool) {
+	var withPos fmterr.ErrorWithPos
+	for ; err != nil; err = errors.Unwrap(err) {
+		if errPos, ok := err.(fmterr.ErrorWithPos); ok {
+			withPos = errPos
+		}
+		opErr, ok := err.(*OpError)
+		if !ok {
+			continue
+		}
+		if withPos != nil {
+			AtPosition(withPos.FSet(), withPos.Src(), opErr)
+		}
+		return opErr, true
+	}
+	return nil, false
+}
@@ -0,0 +1,255 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/gx-org/backend/ops"
+	pjrtgx "github.com/gx-org/xlapjrt"
+)
+
+// ExportEnv is the environment variable read when a graph is compiled.
+// If set, the graph is exported in the directory it specifies as
+// <function name>.<n>.dot and <function name>.<n>.json files, where n counts
+// the compilations of the function (for example, for different argument shapes).
+// Path separators in the fully qualified name of the function are replaced by '_'.
+const ExportEnv = "GX_XLA_GRAPH_EXPORT"
+
+// exportCounts counts the graphs exported for each function in each directory
+// such that different compilations of a function do not overwrite each other.
+var exportCounts = struct {
+	sync.Mutex
+	n map[string]int
+}{n: make(map[string]int)}
+
+// fileNameReplacer replaces path separators in the fully qualified names of functions.
+var fileNameReplacer = strings.NewReplacer("/", "_", `\`, "_")
+
+// exportPath returns the path, without extension, of the next export of a function in a directory.
+func exportPath(dir, name string) string {
+	exportCounts.Lock()
+	defer exportCounts.Unlock()
+	prefix := filepath.Join(dir, fileNameReplacer.Replace(name))
+	n := exportCounts.n[prefix]
+	exportCounts.n[prefix]++
+	return prefix + "." + strconv.Itoa(n)
+}
+
+type (
+	// ExportedNode is a node of an exported graph.
+	ExportedNode struct {
+		// ID of the node, unique within the exported graph.
+		ID int `json:"id"`
+		// Graph is the name of the (sub)graph in which the node has been created.
+		Graph string `json:"graph"`
+		// Op is the XLA operation type of the node.
+		Op string `json:"op"`
+		// Info is the debug information attached to the node.
+		Info string `json:"info,omitempty"`
+		// DType is the data type of the node. Empty for tuples.
+		DType string `json:"dtype,omitempty"`
+		// AxisLengths are the axis lengths of the node as inferred by XLA.
+		AxisLengths []int `json:"axisLengths,omitempty"`
+		// Shape is the XLA shape of the node.
+		Shape string `json:"shape"`
+		// Deps are the IDs of the dependencies of the node.
+		Deps []int `json:"deps,omitempty"`
+	}
+
+	// Exported is a graph exported for debugging.
+	// Shared nodes are only exported once.
+	Exported struct {
+		// Name of the graph.
+		Name string `json:"name"`
+		// Nodes of the graph. Dependencies are listed before the nodes using them.
+		Nodes []*ExportedNode `json:"nodes"`
+		// Outputs are the IDs of the output nodes.
+		Outputs []int `json:"outputs"`
+	}
+
+	exporter struct {
+		exp *Exported
+		ids map[any]int
+	}
+)
+
+// Export the graph reachable from a set of output nodes by walking the dependencies of the nodes.
+func (g *Graph) Export(outs []ops.Node) *Exported {
+	e := &exporter{
+		exp: &Exported{Name: g.builder.Name()},
+		ids: make(map[any]int),
+	}
+	for _, out := range outs {
+		e.exp.Outputs = append(e.exp.Outputs, e.visit(out))
+	}
+	return e.exp
+}
+
+func (e *exporter) add(key any, node *ExportedNode, deps []ops.Node) int {
+	// The ID is assigned before visiting the dependencies to stop on cycles.
+	node.ID = len(e.ids)
+	e.ids[key] = node.ID
+	for _, dep := range deps {
+		node.Deps = append(node.Deps, e.visit(dep))
+	}
+	e.exp.Nodes = append(e.exp.Nodes, node)
+	return node.ID
+}
+
+func (e *exporter) visit(node ops.Node) int {
+	if tpl, ok := node.(*tuple); ok {
+		node = tpl.Node
+	}
+	if id, ok := e.ids[node]; ok {
+		return id
+	}
+	switch nodeT := node.(type) {
+	case *Node:
+		exp := &ExportedNode{
+			Graph: nodeT.graph.builder.Name(),
+			Op:    nodeT.op.Type.String(),
+			Info:  nodeT.info,
+			Shape: nodeT.op.Shape.String(),
+		}
+		if nodeT.op.Shape.TupleSize() == 0 {
+			exp.DType = pjrtgx.ToGXDType(nodeT.op.Shape.DType).String()
+			exp.AxisLengths = nodeT.op.Shape.Dimensions
+		}
+		return e.add(node, exp, nodeT.deps)
+	case *subGraph:
+		deps := []ops.Node{nodeT.out}
+		for _, arg := range nodeT.graph.in {
+			deps = append(deps, arg)
+		}
+		return e.add(node, &ExportedNode{
+			Graph: nodeT.graph.builder.Name(),
+			Op:    "Subgraph",
+			Info:  nodeT.graph.builder.Name(),
+		}, deps)
+	default:
+		return e.add(node, &ExportedNode{Op: fmt.Sprintf("%T", node)}, nil)
+	}
+}
+
+// WriteJSON writes the exported graph in JSON.
+func (exp *Exported) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(exp)
+}
+
+// WriteDOT writes the exported graph in the GraphViz DOT format.
+// Nodes are grouped in clusters by (sub)graph.
+func (exp *Exported) WriteDOT(w io.Writer) error {
+	bld := strings.Builder{}
+	fmt.Fprintf(&bld, "digraph %s {\n", strconv.Quote(exp.Name))
+	bld.WriteString("\tnode [shape=box];\n")
+	var graphs []string
+	byGraph := make(map[string][]*ExportedNode)
+	for _, node := range exp.Nodes {
+		if _, ok := byGraph[node.Graph]; !ok {
+			graphs = append(graphs, node.Graph)
+		}
+		byGraph[node.Graph] = append(byGraph[node.Graph], node)
+	}
+	for i, graph := range graphs {
+		indent := "\t"
+		if graph != exp.Name {
+			fmt.Fprintf(&bld, "\tsubgraph cluster_%d {\n\t\tlabel=%s;\n", i, strconv.Quote(graph))
+			indent = "\t\t"
+		}
+		for _, node := range byGraph[graph] {
+			fmt.Fprintf(&bld, "%sn%d [label=%s];\n", indent, node.ID, strconv.Quote(node.label()))
+		}
+		if graph != exp.Name {
+			bld.WriteString("\t}\n")
+		}
+	}
+	for _, node := range exp.Nodes {
+		for _, dep := range node.Deps {
+			fmt.Fprintf(&bld, "\tn%d -> n%d;\n", dep, node.ID)
+		}
+	}
+	for i, out := range exp.Outputs {
+		fmt.Fprintf(&bld, "\tout%d [label=\"out:%d\", shape=oval];\n\tn%d -> out%d;\n", i, i, out, i)
+	}
+	bld.WriteString("}\n")
+	_, err := io.WriteString(w, bld.String())
+	return err
+}
+
+func (node *ExportedNode) label() string {
+	label := node.Op
+	if node.Info != "" {
+		label += ":" + node.Info
+	}
+	if node.Shape != "" {
+		label += "\n" + node.Shape
+	}
+	return label
+}
+
+// ExportTo sets the directory in which the graph is exported when it is compiled.
+// If empty (the default), the directory is read from the ExportEnv environment variable.
+func (g *Graph) ExportTo(dir string) {
+	g.exportDir = dir
+}
+
+// exportCompiled exports the compiled graph if an export directory has been set.
+func (g *Graph) exportCompiled(outs []ops.Node) error {
+	dir := g.exportDir
+	if dir == "" {
+		dir = os.Getenv(ExportEnv)
+	}
+	if dir == "" {
+		return nil
+	}
+	exp := g.Export(outs)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Errorf("cannot export graph %s: %v", exp.Name, err)
+	}
+	path := exportPath(dir, exp.Name)
+	for ext, write := range map[string]func(io.Writer) error{
+		".dot":  exp.WriteDOT,
+		".json": exp.WriteJSON,
+	} {
+		if err := writeFile(path+ext, write); err != nil {
+			return errors.Errorf("cannot export graph %s: %v", exp.Name, err)
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, write func(io.Writer) error) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cErr := f.Close(); err == nil {
+			err = cErr
+		}
+	}()
+	return write(f)
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/gomlx/gopjrt/xlabuilder"
+	"github.com/gx-org/backend/ops"
+)
+
+// MaxFFTAxes is the maximum number of axes transformed by a FFT.
+const MaxFFTAxes = 3
+
+// checkFFTAxes checks that the last axes of an array can be transformed.
+func checkFFTAxes(x *xlabuilder.Op, axes int) error {
+	if _, err := complexDType(x.Shape.DType); err != nil {
+		return err
+	}
+	if axes < 1 || axes > MaxFFTAxes {
+		return errors.Errorf("invalid number of FFT axes %d: must be between 1 and %d", axes, MaxFFTAxes)
+	}
+	if axes > x.Shape.Rank() {
+		return errors.Errorf("cannot transform %d axes of an array with %d axes", axes, x.Shape.Rank())
+	}
+	return nil
+}
+
+func lastAxes(dims []int, axes int) []int {
+	return slices.Clone(dims[len(dims)-axes:])
+}
+
+// FFT returns the real and imaginary parts of the discrete Fourier transform,
+// or its inverse, over the last axes of a complex array.
+func (g *Graph) FFT(re, im ops.Node, axes int, inverse bool) (outRe, outIm ops.Node, err error) {
+	reOp, imOp := g.xlaHandle(re), g.xlaHandle(im)
+	if err := checkFFTAxes(reOp, axes); err != nil {
+		return nil, nil, g.opError("FFT", err, re, im)
+	}
+	x, err := toComplex(reOp, imOp)
+	if err != nil {
+		return nil, nil, g.opError("FFT", err, re, im)
+	}
+	fftType := xlabuilder.FFTType_FFT
+	if inverse {
+		fftType = xlabuilder.FFTType_IFFT
+	}
+	out, err := xlabuilder.FFT(x, fftType, lastAxes(reOp.Shape.Dimensions, axes))
+	if err != nil {
+		return nil, nil, g.opError("FFT", err, re, im)
+	}
+	if outRe, outIm, err = g.fromComplex(out, re, im); err != nil {
+		return nil, nil, g.opError("FFT", err, re, im)
+	}
+	return outRe, outIm, nil
+}
+
+// RFFTAxisLengths returns the axis lengths of the result of a real FFT.
+func RFFTAxisLengths(dims []int) []int {
+	out := slices.Clone(dims)
+	out[len(out)-1] = out[len(out)-1]/2 + 1
+	return out
+}
+
+// RFFT returns the real and imaginary parts of the discrete Fourier transform
+// over the last axes of a real array.
+// Only the non-negative frequencies of the last axis are returned: its length is n/2+1.
+func (g *Graph) RFFT(x ops.Node, axes int) (re, im ops.Node, err error) {
+	xOp := g.xlaHandle(x)
+	if err := checkFFTAxes(xOp, axes); err != nil {
+		return nil, nil, g.opError("RFFT", err, x)
+	}
+	out, err := xlabuilder.FFT(xOp, xlabuilder.FFTType_RFFT, lastAxes(xOp.Shape.Dimensions, axes))
+	if err != nil {
+		return nil, nil, g.opError("RFFT", err, x)
+	}
+	if re, im, err = g.fromComplex(out, x); err != nil {
+		return nil, nil, g.opError("RFFT", err, x)
+	}
+	return re, im, nil
+}
+
+// IRFFT returns the inverse of RFFT given the real and imaginary parts of the non-negative frequencies.
+// length is the length of the last axis of the result: the length of the last axis of the input
+// must be length/2+1.
+func (g *Graph) IRFFT(re, im ops.Node, axes, length int) (ops.Node, error) {
+	reOp, imOp := g.xlaHandle(re), g.xlaHandle(im)
+	if err := checkFFTAxes(reOp, axes); err != nil {
+		return nil, g.opError("IRFFT", err, re, im)
+	}
+	dims := slices.Clone(reOp.Shape.Dimensions)
+	if got, want := dims[len(dims)-1], length/2+1; got != want {
+		return nil, g.opError("IRFFT", errors.Errorf("cannot compute an inverse real FFT of length %d: last axis has length %d but want %d", length, got, want), re, im)
+	}
+	dims[len(dims)-1] = length
+	x, err := toComplex(reOp, imOp)
+	if err != nil {
+		return nil, g.opError("IRFFT", err, re, im)
+	}
+	out, err := xlabuilder.FFT(x, xlabuilder.FFTType_IRFFT, lastAxes(dims, axes))
+	if err != nil {
+		return nil, g.opError("IRFFT", err, re, im)
+	}
+	return g.newNode(out, re, im).Info("irfft"), nil
+}
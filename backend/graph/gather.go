@@ -0,0 +1,394 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/gomlx/gopjrt/xlabuilder"
+	"github.com/gx-org/backend/ops"
+)
+
+// GatherConfig is the configuration of a XLA gather operation.
+// See https://openxla.org/xla/operation_semantics#gather
+type GatherConfig struct {
+	// IndexVectorAxis is the axis of the indices containing the start indices.
+	// If it is equal to the number of axes of the indices, the start indices have a single element.
+	IndexVectorAxis int
+	// OffsetAxes are the axes of the result containing the slices (sorted).
+	OffsetAxes []int
+	// CollapsedSliceAxes are the axes of the operand removed from the slices.
+	// The slice size of these axes must be 1.
+	CollapsedSliceAxes []int
+	// StartIndexMap maps the elements of the start indices to the axes of the operand.
+	StartIndexMap []int
+	// SliceSizes are the sizes of the slices for each axis of the operand.
+	SliceSizes []int
+	// OperandBatchingAxes are the axes of the operand indexed by the position
+	// of the indices along IndicesBatchingAxes. Their slice size must be 1.
+	OperandBatchingAxes []int
+	// IndicesBatchingAxes are the axes of the indices matching OperandBatchingAxes.
+	IndicesBatchingAxes []int
+	// IndicesAreSorted is a hint that the start indices are sorted.
+	IndicesAreSorted bool
+}
+
+func checkAxes(what string, axes []int, rank int) error {
+	seen := make(map[int]bool)
+	for _, axis := range axes {
+		if axis < 0 || axis >= rank {
+			return errors.Errorf("invalid %s axis %d: must be in [0, %d)", what, axis, rank)
+		}
+		if seen[axis] {
+			return errors.Errorf("axis %d repeated in %s axes %v", axis, what, axes)
+		}
+		seen[axis] = true
+	}
+	return nil
+}
+
+// Check returns an error if the configuration is invalid for an operand and indices
+// given their number of axes.
+func (cfg *GatherConfig) Check(operandRank, indicesRank int) error {
+	if cfg.IndexVectorAxis < 0 || cfg.IndexVectorAxis > indicesRank {
+		return errors.Errorf("invalid index vector axis %d: must be in [0, %d]", cfg.IndexVectorAxis, indicesRank)
+	}
+	if len(cfg.SliceSizes) != operandRank {
+		return errors.Errorf("got %d slice sizes but the operand has %d axes", len(cfg.SliceSizes), operandRank)
+	}
+	removed := append(slices.Clone(cfg.CollapsedSliceAxes), cfg.OperandBatchingAxes...)
+	if err := checkAxes("collapsed slice or operand batching", removed, operandRank); err != nil {
+		return err
+	}
+	for _, axis := range removed {
+		if cfg.SliceSizes[axis] != 1 {
+			return errors.Errorf("invalid slice size %d for the collapsed or batching axis %d: must be 1", cfg.SliceSizes[axis], axis)
+		}
+	}
+	if err := checkAxes("start index map", cfg.StartIndexMap, operandRank); err != nil {
+		return err
+	}
+	for _, axis := range cfg.StartIndexMap {
+		if slices.Contains(cfg.OperandBatchingAxes, axis) {
+			return errors.Errorf("operand batching axis %d cannot be in the start index map %v", axis, cfg.StartIndexMap)
+		}
+	}
+	if len(cfg.OperandBatchingAxes) != len(cfg.IndicesBatchingAxes) {
+		return errors.Errorf("got %d operand batching axes but %d indices batching axes", len(cfg.OperandBatchingAxes), len(cfg.IndicesBatchingAxes))
+	}
+	if err := checkAxes("indices batching", cfg.IndicesBatchingAxes, indicesRank); err != nil {
+		return err
+	}
+	if slices.Contains(cfg.IndicesBatchingAxes, cfg.IndexVectorAxis) {
+		return errors.Errorf("index vector axis %d cannot be an indices batching axis", cfg.IndexVectorAxis)
+	}
+	numOffsets := operandRank - len(removed)
+	if len(cfg.OffsetAxes) != numOffsets {
+		return errors.Errorf("got %d offset axes but want %d (number of operand axes not collapsed or batching)", len(cfg.OffsetAxes), numOffsets)
+	}
+	if !slices.IsSorted(cfg.OffsetAxes) {
+		return errors.Errorf("offset axes %v must be sorted", cfg.OffsetAxes)
+	}
+	numBatch := indicesRank
+	if cfg.IndexVectorAxis < indicesRank {
+		numBatch--
+	}
+	return checkAxes("offset", cfg.OffsetAxes, numBatch+numOffsets)
+}
+
+// GatherOutputAxes returns the axes of the result of a gather given the axes of the indices.
+// Batch axes of the result are axes of the indices and offset axes are given by size
+// from an axis of the operand.
+// The configuration needs to have been checked first.
+func GatherOutputAxes[T any](indices []T, cfg *GatherConfig, size func(operandAxis int) T) []T {
+	var batch []T
+	for i, axis := range indices {
+		if i != cfg.IndexVectorAxis {
+			batch = append(batch, axis)
+		}
+	}
+	var offsets []T
+	for axis := range cfg.SliceSizes {
+		if slices.Contains(cfg.CollapsedSliceAxes, axis) || slices.Contains(cfg.OperandBatchingAxes, axis) {
+			continue
+		}
+		offsets = append(offsets, size(axis))
+	}
+	out := make([]T, len(batch)+len(offsets))
+	offsetAxes := cfg.OffsetAxes
+	for i := range out {
+		if len(offsetAxes) > 0 && offsetAxes[0] == i {
+			out[i], offsets, offsetAxes = offsets[0], offsets[1:], offsetAxes[1:]
+			continue
+		}
+		out[i], batch = batch[0], batch[1:]
+	}
+	return out
+}
+
+// GatherAxisLengths checks a gather configuration given the axis lengths of the operand
+// and of the indices. It returns the axis lengths of the result.
+func GatherAxisLengths(operand, indices []int, cfg *GatherConfig) ([]int, error) {
+	if err := cfg.Check(len(operand), len(indices)); err != nil {
+		return nil, err
+	}
+	indexVectorSize := 1
+	if cfg.IndexVectorAxis < len(indices) {
+		indexVectorSize = indices[cfg.IndexVectorAxis]
+	}
+	if len(cfg.StartIndexMap) != indexVectorSize {
+		return nil, errors.Errorf("got %d axes in the start index map but the index vector has %d elements", len(cfg.StartIndexMap), indexVectorSize)
+	}
+	for axis, size := range cfg.SliceSizes {
+		if size < 0 || size > operand[axis] {
+			return nil, errors.Errorf("invalid slice size %d for axis %d of length %d", size, axis, operand[axis])
+		}
+	}
+	for i, axis := range cfg.OperandBatchingAxes {
+		if got, want := indices[cfg.IndicesBatchingAxes[i]], operand[axis]; got != want {
+			return nil, errors.Errorf("indices batching axis %d has length %d but operand batching axis %d has length %d", cfg.IndicesBatchingAxes[i], got, axis, want)
+		}
+	}
+	return GatherOutputAxes(indices, cfg, func(axis int) int { return cfg.SliceSizes[axis] }), nil
+}
+
+// gather builds a XLA gather operation.
+// gopjrt does not support batching axes: they are emulated by appending the positions
+// along the indices batching axes to the start indices.
+func gather(x, indices *xlabuilder.Op, cfg *GatherConfig) (*xlabuilder.Op, error) {
+	if len(cfg.OperandBatchingAxes) == 0 {
+		return xlabuilder.Gather(x, indices, cfg.IndexVectorAxis, cfg.OffsetAxes, cfg.CollapsedSliceAxes, cfg.StartIndexMap, cfg.SliceSizes, cfg.IndicesAreSorted)
+	}
+	dims := indices.Shape.Dimensions
+	if cfg.IndexVectorAxis == len(dims) {
+		var err error
+		if indices, err = xlabuilder.Reshape(indices, append(slices.Clone(dims), 1)...); err != nil {
+			return nil, err
+		}
+		dims = indices.Shape.Dimensions
+	}
+	positionDims := slices.Clone(dims)
+	positionDims[cfg.IndexVectorAxis] = 1
+	positionShape := xlabuilder.MakeShape(indices.Shape.DType, positionDims...)
+	startIndices := []*xlabuilder.Op{indices}
+	startIndexMap := slices.Clone(cfg.StartIndexMap)
+	collapsed := slices.Clone(cfg.CollapsedSliceAxes)
+	for i, axis := range cfg.IndicesBatchingAxes {
+		positions, err := xlabuilder.Iota(indices.Builder(), positionShape, axis)
+		if err != nil {
+			return nil, err
+		}
+		startIndices = append(startIndices, positions)
+		startIndexMap = append(startIndexMap, cfg.OperandBatchingAxes[i])
+		collapsed = append(collapsed, cfg.OperandBatchingAxes[i])
+	}
+	slices.Sort(collapsed)
+	indices, err := xlabuilder.Concatenate(cfg.IndexVectorAxis, startIndices...)
+	if err != nil {
+		return nil, err
+	}
+	// The positions along the batching axes are not sorted with the start indices.
+	const indicesAreSorted = false
+	return xlabuilder.Gather(x, indices, cfg.IndexVectorAxis, cfg.OffsetAxes, collapsed, startIndexMap, cfg.SliceSizes, indicesAreSorted)
+}
+
+// GatherWithConfig returns slices of x at the positions given by start indices.
+// Unlike Gather, batching axes are supported.
+func (g *Graph) GatherWithConfig(x, indices ops.Node, cfg *GatherConfig) (ops.Node, error) {
+	xOp, indicesOp := g.xlaHandle(x), g.xlaHandle(indices)
+	if !indicesOp.Shape.DType.IsInt() {
+		return nil, g.opError("Gather", errors.Errorf("invalid gather indices %s: want integers", indicesOp.Shape), x, indices)
+	}
+	if _, err := GatherAxisLengths(xOp.Shape.Dimensions, indicesOp.Shape.Dimensions, cfg); err != nil {
+		return nil, g.opError("Gather", err, x, indices)
+	}
+	op, err := gather(xOp, indicesOp, cfg)
+	if err != nil {
+		return nil, g.opError("Gather", err, x, indices)
+	}
+	return g.newNode(op, x, indices), nil
+}
+
+// GatherMode is the behavior of Take and TakeAlongAxis for indices out of bounds.
+type GatherMode int
+
+const (
+	// GatherClip clamps the indices to the bounds of the axis.
+	GatherClip GatherMode = iota
+	// GatherFill returns zeros for the indices out of bounds.
+	GatherFill
+)
+
+// boundIndices clamps indices to [0, length) and returns for each index if it was in bounds.
+func boundIndices(indices *xlabuilder.Op, length int) (clamped, inBounds *xlabuilder.Op, err error) {
+	dt, dims := indices.Shape.DType, indices.Shape.Dimensions
+	constant := func(v float64) (*xlabuilder.Op, error) {
+		lit, err := xlabuilder.NewScalarLiteralFromFloat64(v, dt)
+		if err != nil {
+			return nil, err
+		}
+		op, err := xlabuilder.Constant(indices.Builder(), lit)
+		if err != nil {
+			return nil, err
+		}
+		return xlabuilder.Broadcast(op, dims...)
+	}
+	lower, err := constant(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	upper, err := constant(float64(length - 1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if clamped, err = xlabuilder.Max(indices, lower); err != nil {
+		return nil, nil, err
+	}
+	if clamped, err = xlabuilder.Min(clamped, upper); err != nil {
+		return nil, nil, err
+	}
+	if inBounds, err = xlabuilder.Equal(clamped, indices); err != nil {
+		return nil, nil, err
+	}
+	return clamped, inBounds, nil
+}
+
+// takeGather gathers x with indices bound to the length of an axis.
+// inBoundsAxes are the axes of the result matching the axes of the indices.
+func (g *Graph) takeGather(x, indices ops.Node, axis int, mode GatherMode, cfg *GatherConfig, inBoundsAxes []int) (ops.Node, error) {
+	xOp, indicesOp := g.xlaHandle(x), g.xlaHandle(indices)
+	if !indicesOp.Shape.DType.IsInt() {
+		return nil, errors.Errorf("invalid indices %s: want integers", indicesOp.Shape)
+	}
+	length := xOp.Shape.Dimensions[axis]
+	if length == 0 {
+		return nil, errors.Errorf("cannot take elements from the axis %d of %s: axis is empty", axis, xOp.Shape)
+	}
+	if _, err := GatherAxisLengths(xOp.Shape.Dimensions, indicesOp.Shape.Dimensions, cfg); err != nil {
+		return nil, err
+	}
+	clamped, inBounds, err := boundIndices(indicesOp, length)
+	if err != nil {
+		return nil, err
+	}
+	op, err := gather(xOp, clamped, cfg)
+	if err != nil {
+		return nil, err
+	}
+	switch mode {
+	case GatherClip:
+	case GatherFill:
+		if inBounds, err = xlabuilder.BroadcastInDim(inBounds, xlabuilder.MakeShape(inBounds.Shape.DType, op.Shape.Dimensions...), inBoundsAxes); err != nil {
+			return nil, err
+		}
+		zero, err := xlabuilder.ScalarZero(op.Builder(), op.Shape.DType)
+		if err != nil {
+			return nil, err
+		}
+		if zero, err = xlabuilder.Broadcast(zero, op.Shape.Dimensions...); err != nil {
+			return nil, err
+		}
+		if op, err = xlabuilder.Where(inBounds, op, zero); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("unknown gather mode %d", mode)
+	}
+	return g.newNode(op, x, indices), nil
+}
+
+func checkAxis(axis, rank int) error {
+	if axis < 0 || axis >= rank {
+		return errors.Errorf("invalid axis %d: must be in [0, %d)", axis, rank)
+	}
+	return nil
+}
+
+func axesRange(start, end int) []int {
+	axes := make([]int, 0, end-start)
+	for i := start; i < end; i++ {
+		axes = append(axes, i)
+	}
+	return axes
+}
+
+// TakeConfig returns the gather configuration taking the elements of an axis of an operand at the given indices.
+func TakeConfig(operand []int, indicesRank, axis int) *GatherConfig {
+	sliceSizes := slices.Clone(operand)
+	sliceSizes[axis] = 1
+	return &GatherConfig{
+		IndexVectorAxis:    indicesRank,
+		OffsetAxes:         append(axesRange(0, axis), axesRange(axis+indicesRank, len(operand)-1+indicesRank)...),
+		CollapsedSliceAxes: []int{axis},
+		StartIndexMap:      []int{axis},
+		SliceSizes:         sliceSizes,
+	}
+}
+
+// Take returns the elements of x at the given indices along an axis.
+// The axes of the result are the axes of x before axis, the axes of the indices,
+// and the axes of x after axis.
+func (g *Graph) Take(x, indices ops.Node, axis int, mode GatherMode) (ops.Node, error) {
+	xOp, indicesOp := g.xlaHandle(x), g.xlaHandle(indices)
+	if err := checkAxis(axis, xOp.Shape.Rank()); err != nil {
+		return nil, g.opError("Take", err, x, indices)
+	}
+	indicesRank := indicesOp.Shape.Rank()
+	cfg := TakeConfig(xOp.Shape.Dimensions, indicesRank, axis)
+	out, err := g.takeGather(x, indices, axis, mode, cfg, axesRange(axis, axis+indicesRank))
+	if err != nil {
+		return nil, g.opError("Take", err, x, indices)
+	}
+	return out, nil
+}
+
+// TakeAlongAxisConfig returns the gather configuration taking the elements of an axis of an operand
+// at the indices at the same position along the other axes.
+func TakeAlongAxisConfig(rank, axis int) *GatherConfig {
+	sliceSizes := make([]int, rank)
+	for i := range sliceSizes {
+		sliceSizes[i] = 1
+	}
+	batching := slices.Delete(axesRange(0, rank), axis, axis+1)
+	return &GatherConfig{
+		IndexVectorAxis:     rank,
+		OffsetAxes:          []int{},
+		CollapsedSliceAxes:  []int{axis},
+		StartIndexMap:       []int{axis},
+		SliceSizes:          sliceSizes,
+		OperandBatchingAxes: batching,
+		IndicesBatchingAxes: slices.Clone(batching),
+	}
+}
+
+// TakeAlongAxis returns the elements of x at the given indices along an axis.
+// The indices have the axes of x but for the axis, which can have any length.
+// The result has the axes of the indices.
+func (g *Graph) TakeAlongAxis(x, indices ops.Node, axis int, mode GatherMode) (ops.Node, error) {
+	xOp, indicesOp := g.xlaHandle(x), g.xlaHandle(indices)
+	rank := xOp.Shape.Rank()
+	if err := checkAxis(axis, rank); err != nil {
+		return nil, g.opError("TakeAlongAxis", err, x, indices)
+	}
+	if indicesOp.Shape.Rank() != rank {
+		return nil, g.opError("TakeAlongAxis", errors.Errorf("indices %s and array %s must have the same number of axes", indicesOp.Shape, xOp.Shape), x, indices)
+	}
+	out, err := g.takeGather(x, indices, axis, mode, TakeAlongAxisConfig(rank, axis), axesRange(0, rank))
+	if err != nil {
+		return nil, g.opError("TakeAlongAxis", err, x, indices)
+	}
+	return out, nil
+}
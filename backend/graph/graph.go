@@ -13,13 +13,21 @@
 // limitations under the License.
 
 // Package graph builds a PJRT graph.
+//
+// Random bits are generated by the XLA RngBitGenerator operation. gopjrt always builds
+// it with the Philox algorithm and a uint64[3] state: the other XLA algorithms
+// (default and threefry) cannot be selected until gopjrt exposes them.
 package graph
 
 import (
+	"context"
+	"crypto/sha256"
 	"fmt"
 	"go/ast"
 	"go/token"
+	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/gomlx/gopjrt/dtypes/bfloat16"
@@ -48,6 +56,21 @@ type (
 		in     []*Node
 		out    []*shape.Shape
 		traced []*shape.Shape
+
+		hoistThreshold int
+		hoisted        []*hoistedConstant
+
+		// subcomps is shared by a graph and all its subgraphs.
+		subcomps *subcomputations
+
+		exportDir string
+
+		// checkShapes is set to cross-check the shapes inferred by GX with the shapes inferred by XLA.
+		checkShapes bool
+
+		// root is the tuple of all the outputs once the graph has been compiled.
+		root       *xlabuilder.Op
+		onCompiled func(*Graph)
 	}
 
 	pjrtNode interface {
@@ -65,13 +88,14 @@ var (
 
 // New returns a new graph.
 func New(plat *pjrtplatform.Platform, funcName string, shapes []*shape.Shape) (ops.Graph, error) {
-	return newGraph(plat, shapes, xlabuilder.New(funcName))
+	return newGraph(plat, shapes, xlabuilder.New(funcName), newSubcomputations())
 }
 
-func newGraph(plat *pjrtplatform.Platform, shapes []*shape.Shape, builder *xlabuilder.XlaBuilder) (ops.Graph, error) {
+func newGraph(plat *pjrtplatform.Platform, shapes []*shape.Shape, builder *xlabuilder.XlaBuilder, subcomps *subcomputations) (ops.Graph, error) {
 	g := &Graph{
-		plat:    plat,
-		builder: builder,
+		plat:     plat,
+		builder:  builder,
+		subcomps: subcomps,
 	}
 	var err error
 	g.inputs, err = g.buildTupleArgument(shapes)
@@ -121,24 +145,76 @@ func unpackOutput(outs []*ops.OutputNode) ([]ops.Node, []*shape.Shape) {
 
 // Compile a node given a set of parameters and using this node as an output.
 // Returns a function that will be run on a device given some inputs.
+// The runner implements io.Closer to free the device buffers of hoisted constants
+// (see HoistConstants).
 func (g *Graph) Compile(dev platform.Device, out, traced []*ops.OutputNode, params []*shape.Shape) (ops.Runner, error) {
 	var outNodes, tracedNodes []ops.Node
 	outNodes, g.out = unpackOutput(out)
 	tracedNodes, g.traced = unpackOutput(traced)
 	all := append(append([]ops.Node{}, outNodes...), tracedNodes...)
+	// Export the graph before compiling it to help debugging compilation errors.
+	// The export is a debugging aid: failing to export does not prevent the compilation.
+	if err := g.exportCompiled(all); err != nil {
+		g.plat.Logger().LogAttrs(context.Background(), slog.LevelWarn, "graph export",
+			slog.String("function", g.builder.Name()),
+			slog.String("error", err.Error()),
+		)
+	}
 	allTuple, err := g.Tuple(all)
 	if err != nil {
 		return nil, err
 	}
-	computation, err := g.builder.Build(g.xlaHandle(allTuple))
+	start := time.Now()
+	g.root = g.xlaHandle(allTuple)
+	computation, err := g.builder.Build(g.root)
 	if err != nil {
-		return nil, errors.Errorf("cannot compile graph node %T for function %s: %v", all, g.builder.Name(), err)
+		return nil, pjrtplatform.NewError(pjrtplatform.ErrCompile, err, "cannot build the XLA computation of function %s", g.builder.Name())
 	}
 	g.executable, err = g.plat.Client().Compile().WithComputation(computation).Done()
 	if err != nil {
-		return nil, errors.Errorf("cannot compile graph node %T for function %s: %v", all, g.builder.Name(), err)
+		return nil, pjrtplatform.NewError(pjrtplatform.ErrCompile, err, "cannot compile function %s", g.builder.Name())
+	}
+	pjrtDev, ok := dev.(*pjrtplatform.Device)
+	if !ok {
+		return nil, pjrtplatform.NewError(pjrtplatform.ErrUnsupportedTransfer, nil, "cannot compile function %s for a %T device", g.builder.Name(), dev)
 	}
-	return g.newNodeRunner(dev.(*pjrtplatform.Device)), nil
+	// Uploading the hoisted constants is not part of the compilation time.
+	duration := time.Since(start)
+	hoisted, err := g.uploadHoisted(pjrtDev)
+	if err != nil {
+		return nil, err
+	}
+	g.plat.Metrics().Compiled(g.builder.Name(), duration)
+	g.logCompiled(computation, duration)
+	if g.onCompiled != nil {
+		g.onCompiled(g)
+	}
+	return g.newNodeRunner(pjrtDev, hoisted), nil
+}
+
+// logCompiled emits a debug event once the graph has been compiled.
+func (g *Graph) logCompiled(computation *xlabuilder.XlaComputation, duration time.Duration) {
+	logger := g.plat.Logger()
+	ctx := context.Background()
+	if !logger.Enabled(ctx, slog.LevelDebug) {
+		// Do not generate the HLO text if it is not logged.
+		return
+	}
+	logger.LogAttrs(ctx, slog.LevelDebug, "graph compilation",
+		slog.String("function", g.builder.Name()),
+		slog.Int("hlo_text_bytes", len(computation.TextHLO())),
+		slog.Duration("duration", duration),
+		slog.Int("outputs", len(g.out)),
+		slog.Int("traced", len(g.traced)),
+		slog.Int("hoisted", len(g.hoisted)),
+		slog.Int("subcomputations_built", g.subcomps.stats.Built),
+		slog.Int("subcomputations_reused", g.subcomps.stats.Reused),
+	)
+}
+
+// OnCompiled sets a function called once the graph has been compiled.
+func (g *Graph) OnCompiled(f func(*Graph)) {
+	g.onCompiled = f
 }
 
 // OutShapes returns the expected shapes of the out nodes.
@@ -216,11 +292,12 @@ func (n *Node) BackendShape() *shape.Shape {
 }
 
 // PJRTDims returns the dimension of the node computed by PJRT.
+// The GX interpreter still calls it to compute the axis lengths of einsum expressions.
 // TODO(degris): remove once the interpreter can compute the axis lengths.
 //
-// Deprecated: temporary function used as a workaround.
+// Deprecated: use BackendShape instead.
 func (n *Node) PJRTDims() []int {
-	return n.xlaOp().Shape.Dimensions
+	return n.BackendShape().AxisLengths
 }
 
 func (n *Node) xlaOp() *xlabuilder.Op {
@@ -256,6 +333,9 @@ func (g *Graph) Constant(buffer platform.HostBuffer) (ops.Node, error) {
 	data := buffer.Acquire()
 	defer buffer.Release()
 	shap := buffer.Shape()
+	if g.shouldHoist(len(data)) {
+		return g.hoistConstant(data, shap)
+	}
 	var literal *xlabuilder.Literal
 	var err error
 	switch shap.DType {
@@ -276,7 +356,7 @@ func (g *Graph) Constant(buffer platform.HostBuffer) (ops.Node, error) {
 	case dtype.Uint64:
 		literal, err = newLiteral(dtype.ToSlice[uint64](data), shap.AxisLengths)
 	default:
-		err = errors.Errorf("cannot create a PJRT literal: data type %v not supported", shap.DType)
+		err = pjrtplatform.NewError(pjrtplatform.ErrUnsupportedDType, nil, "cannot create a PJRT literal of data type %v", shap.DType)
 	}
 	if err != nil {
 		return nil, err
@@ -288,11 +368,31 @@ func (g *Graph) Constant(buffer platform.HostBuffer) (ops.Node, error) {
 	return g.newNode(op), nil
 }
 
+// Scalar returns a node representing a scalar constant of the given data type.
+func (g *Graph) Scalar(value float64, dt dtype.DataType) (ops.Node, error) {
+	xlaDType := pjrtgx.ToDType(dt)
+	if xlaDType == dtypes.InvalidDType {
+		return nil, pjrtplatform.NewError(pjrtplatform.ErrUnsupportedDType, nil, "cannot convert %s to a XLA data type", dt.String())
+	}
+	literal, err := xlabuilder.NewScalarLiteralFromFloat64(value, xlaDType)
+	if err != nil {
+		return nil, err
+	}
+	op, err := xlabuilder.Constant(g.builder, literal)
+	if err != nil {
+		return nil, err
+	}
+	return g.newNode(op), nil
+}
+
 // Argument returns a node set by a caller when calling the function.
 func (g *Graph) Argument(name string, shape *shape.Shape, index int) (node ops.Node, err error) {
 	if g.inputs != nil {
 		return g.tupleArgument(shape, name, index)
 	}
+	if len(g.hoisted) > 0 {
+		return nil, errors.Errorf("cannot create argument %d:%s: %d constants have already been hoisted into parameters", index, name, len(g.hoisted))
+	}
 	xlaOp, err := xlabuilder.Parameter(g.builder, name, index, pjrtgx.ToShape(shape))
 	if err != nil {
 		return nil, err
@@ -306,7 +406,7 @@ func (g *Graph) Argument(name string, shape *shape.Shape, index int) (node ops.N
 func (g *Graph) UnaryFunc(x ops.Node, f func(*xlabuilder.Op) (*xlabuilder.Op, error)) (ops.Node, error) {
 	result, err := f(g.xlaHandle(x))
 	if err != nil {
-		return nil, err
+		return nil, g.opError("UnaryFunc", err, x)
 	}
 	return g.newNode(result, x), nil
 }
@@ -315,7 +415,7 @@ func (g *Graph) UnaryFunc(x ops.Node, f func(*xlabuilder.Op) (*xlabuilder.Op, er
 func (g *Graph) BinaryFunc(x ops.Node, y ops.Node, f func(x *xlabuilder.Op, y *xlabuilder.Op) (*xlabuilder.Op, error)) (ops.Node, error) {
 	result, err := f(g.xlaHandle(x), g.xlaHandle(y))
 	if err != nil {
-		return nil, err
+		return nil, g.opError("BinaryFunc", err, x, y)
 	}
 	return g.newNode(result, x, y), nil
 }
@@ -328,7 +428,7 @@ func (g *Graph) ReduceFunc(x ops.Node, axes []int, f func(*xlabuilder.Op, ...int
 	}
 	xlaOp, err := f(g.xlaHandle(x), axes...)
 	if err != nil {
-		return nil, err
+		return nil, g.opError("Reduce", err, x)
 	}
 	return g.newNode(xlaOp), nil
 }
@@ -345,10 +445,10 @@ func (g *Graph) Unary(op *ast.UnaryExpr, x ops.Node) (ops.Node, error) {
 	case token.NOT:
 		xlaOp, err = xlabuilder.LogicalNot(g.xlaHandle(x))
 	default:
-		return nil, errors.Errorf("operator %s not supported", op.Op)
+		return nil, g.opErrorAt(op, op.Op.String(), pjrtplatform.NewError(pjrtplatform.ErrUnsupported, nil, "unary operator %s", op.Op), x)
 	}
 	if err != nil {
-		return nil, err
+		return nil, g.opErrorAt(op, op.Op.String(), err, x)
 	}
 	return g.newNode(xlaOp), nil
 }
@@ -402,10 +502,10 @@ func (g *Graph) Binary(op *ast.BinaryExpr, x, y ops.Node) (ops.Node, error) {
 	case token.LOR:
 		xlaOp, err = xlabuilder.LogicalOr(g.xlaHandle(x), g.xlaHandle(y))
 	default:
-		return nil, errors.Errorf("operator %s not supported", op.Op)
+		return nil, g.opErrorAt(op, op.Op.String(), pjrtplatform.NewError(pjrtplatform.ErrUnsupported, nil, "binary operator %s", op.Op), x, y)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("%v error: %w", g, err)
+		return nil, g.opErrorAt(op, op.Op.String(), err, x, y)
 	}
 	return g.newNode(xlaOp, x, y), nil
 }
@@ -414,7 +514,7 @@ func (g *Graph) Binary(op *ast.BinaryExpr, x, y ops.Node) (ops.Node, error) {
 func (g *Graph) Reshape(x ops.Node, axisLengths []int) (ops.Node, error) {
 	xlaOp, err := xlabuilder.Reshape(g.xlaHandle(x), axisLengths...)
 	if err != nil {
-		return nil, err
+		return nil, g.opError("Reshape", err, x)
 	}
 	return g.newNode(xlaOp), nil
 }
@@ -423,11 +523,11 @@ func (g *Graph) Reshape(x ops.Node, axisLengths []int) (ops.Node, error) {
 func (g *Graph) Cast(x ops.Node, target dtype.DataType) (ops.Node, error) {
 	xlaDType := pjrtgx.ToDType(target)
 	if xlaDType == dtypes.InvalidDType {
-		return nil, errors.Errorf("cannot convert %s to a XLA data type", target.String())
+		return nil, g.opError("Cast", pjrtplatform.NewError(pjrtplatform.ErrUnsupportedDType, nil, "cannot convert %s to a XLA data type", target.String()), x)
 	}
 	xlaOp, err := xlabuilder.ConvertDType(g.xlaHandle(x), xlaDType)
 	if err != nil {
-		return nil, err
+		return nil, g.opError("Cast", err, x)
 	}
 	return g.newNode(xlaOp), nil
 }
@@ -436,11 +536,11 @@ func (g *Graph) Cast(x ops.Node, target dtype.DataType) (ops.Node, error) {
 func (g *Graph) Bitcast(x ops.Node, target dtype.DataType) (ops.Node, error) {
 	xlaDType := pjrtgx.ToDType(target)
 	if xlaDType == dtypes.InvalidDType {
-		return nil, errors.Errorf("cannot convert %s to a XLA data type", target.String())
+		return nil, g.opError("Bitcast", pjrtplatform.NewError(pjrtplatform.ErrUnsupportedDType, nil, "cannot convert %s to a XLA data type", target.String()), x)
 	}
 	xlaOp, err := xlabuilder.Bitcast(g.xlaHandle(x), xlaDType)
 	if err != nil {
-		return nil, err
+		return nil, g.opError("Bitcast", err, x)
 	}
 	return g.newNode(xlaOp), nil
 }
@@ -510,7 +610,7 @@ func (g *Graph) Concat(axis int, nodes []ops.Node) (ops.Node, error) {
 
 	xlaOp, err := xlabuilder.Concatenate(axis, inputs...)
 	if err != nil {
-		return nil, err
+		return nil, g.opError("Concat", err, nodes...)
 	}
 	return g.newNode(xlaOp), nil
 }
@@ -534,12 +634,12 @@ func (g *Graph) Slice(x ops.Node, i int) (ops.Node, error) {
 
 	sliceOp, err := xlabuilder.Slice(g.xlaHandle(x), starts, limits, strides)
 	if err != nil {
-		return nil, err
+		return nil, g.opError("Slice", err, x)
 	}
 	// Slice doesn't reduce rank, so insert an additional Reshape to handle it.
 	reshapeOp, err := xlabuilder.Reshape(sliceOp, shape.AxisLengths[1:]...)
 	if err != nil {
-		return nil, err
+		return nil, g.opError("Slice", err, x)
 	}
 	return g.newNode(reshapeOp), nil
 }
@@ -548,16 +648,111 @@ func (g *Graph) Slice(x ops.Node, i int) (ops.Node, error) {
 func (g *Graph) Transpose(x ops.Node, permutation []int) (ops.Node, error) {
 	xlaOp, err := xlabuilder.Transpose(g.xlaHandle(x), permutation...)
 	if err != nil {
-		return nil, err
+		return nil, g.opError("Transpose", err, x)
 	}
 	return g.newNode(xlaOp), nil
 }
 
+// PadAxis configures the padding of an axis.
+type PadAxis struct {
+	// Low and High are the number of elements inserted before the first element
+	// and after the last element of the axis. Negative values remove elements.
+	Low, High int
+	// Interior is the number of elements inserted between two elements of the axis.
+	Interior int
+}
+
+// Pad inserts a fill value before, after, and between the elements of each axis of x.
+func (g *Graph) Pad(x, fill ops.Node, axes []PadAxis) (ops.Node, error) {
+	padAxes := make([]xlabuilder.PadAxis, len(axes))
+	for i, axis := range axes {
+		if axis.Interior < 0 {
+			return nil, errors.Errorf("invalid interior padding %d for axis %d: cannot be negative", axis.Interior, i)
+		}
+		padAxes[i] = xlabuilder.PadAxis{Start: axis.Low, End: axis.High, Interior: axis.Interior}
+	}
+	xlaOp, err := xlabuilder.Pad(g.xlaHandle(x), g.xlaHandle(fill), padAxes...)
+	if err != nil {
+		return nil, g.opError("Pad", err, x, fill)
+	}
+	return g.newNode(xlaOp, x, fill), nil
+}
+
+// Reverse reverses the order of the elements of x along the given axes.
+func (g *Graph) Reverse(x ops.Node, axes []int) (ops.Node, error) {
+	if len(axes) == 0 {
+		return x, nil
+	}
+	xlaOp, err := xlabuilder.Reverse(g.xlaHandle(x), axes...)
+	if err != nil {
+		return nil, g.opError("Reverse", err, x)
+	}
+	return g.newNode(xlaOp, x), nil
+}
+
+// broadcastScalars broadcasts the scalar nodes to the axis lengths of the other nodes.
+func (g *Graph) broadcastScalars(nodes ...ops.Node) ([]*xlabuilder.Op, error) {
+	hdls, err := g.xlaHandles(nodes)
+	if err != nil {
+		return nil, err
+	}
+	var dims []int
+	for _, hdl := range hdls {
+		if !hdl.Shape.IsScalar() {
+			dims = hdl.Shape.Dimensions
+			break
+		}
+	}
+	if dims == nil {
+		return hdls, nil
+	}
+	for i, hdl := range hdls {
+		if !hdl.Shape.IsScalar() {
+			continue
+		}
+		if hdls[i], err = xlabuilder.Broadcast(hdl, dims...); err != nil {
+			return nil, err
+		}
+	}
+	return hdls, nil
+}
+
+// Clamp restricts the elements of x to the [lower, upper] interval.
+// Scalar nodes are broadcast to the axis lengths of the other nodes.
+func (g *Graph) Clamp(x, lower, upper ops.Node) (ops.Node, error) {
+	hdls, err := g.broadcastScalars(x, lower, upper)
+	if err != nil {
+		return nil, err
+	}
+	xlaOp, err := xlabuilder.Min(hdls[0], hdls[2])
+	if err != nil {
+		return nil, g.opError("Clamp", err, x, lower, upper)
+	}
+	if xlaOp, err = xlabuilder.Max(xlaOp, hdls[1]); err != nil {
+		return nil, g.opError("Clamp", err, x, lower, upper)
+	}
+	return g.newNode(xlaOp, x, lower, upper), nil
+}
+
+// Select returns, element-wise, the element of onTrue if cond is true or the element of onFalse otherwise.
+// Scalar nodes are broadcast to the axis lengths of the other nodes.
+func (g *Graph) Select(cond, onTrue, onFalse ops.Node) (ops.Node, error) {
+	hdls, err := g.broadcastScalars(cond, onTrue, onFalse)
+	if err != nil {
+		return nil, err
+	}
+	xlaOp, err := xlabuilder.Where(hdls[0], hdls[1], hdls[2])
+	if err != nil {
+		return nil, g.opError("Select", err, cond, onTrue, onFalse)
+	}
+	return g.newNode(xlaOp, cond, onTrue, onFalse), nil
+}
+
 // ArgMinMax returns a new argmin/argmax node.
 func (g *Graph) ArgMinMax(x ops.Node, axis int, outputKind ir.Kind, isMin bool) (ops.Node, error) {
 	xlaOp, err := xlabuilder.ArgMinMax(g.xlaHandle(x), axis, pjrtgx.ToDType(outputKind.DType()), isMin)
 	if err != nil {
-		return nil, err
+		return nil, g.opError("ArgMinMax", err, x)
 	}
 	return g.newNode(xlaOp), nil
 }
@@ -566,7 +761,7 @@ func (g *Graph) ArgMinMax(x ops.Node, axis int, outputKind ir.Kind, isMin bool)
 func (g *Graph) BroadcastInDim(x ops.Node, shape *shape.Shape, broadcastAxes []int) (ops.Node, error) {
 	xlaOp, err := xlabuilder.BroadcastInDim(g.xlaHandle(x), pjrtgx.ToShape(shape), broadcastAxes)
 	if err != nil {
-		return nil, err
+		return nil, g.opError("BroadcastInDim", err, x)
 	}
 	return g.newNode(xlaOp), nil
 }
@@ -575,7 +770,7 @@ func (g *Graph) BroadcastInDim(x ops.Node, shape *shape.Shape, broadcastAxes []i
 func (g *Graph) Gather(x ops.Node, startIndices ops.Node, indexVectorAxis int, offsetAxes []int, collapsedSliceAxes []int, startIndexMap []int, sliceSizes []int, indicesAreSorted bool) (ops.Node, error) {
 	xlaOp, err := xlabuilder.Gather(g.xlaHandle(x), g.xlaHandle(startIndices), indexVectorAxis, offsetAxes, collapsedSliceAxes, startIndexMap, sliceSizes, indicesAreSorted)
 	if err != nil {
-		return nil, err
+		return nil, g.opError("Gather", err, x, startIndices)
 	}
 	return g.newNode(xlaOp), nil
 }
@@ -586,10 +781,10 @@ func (g *Graph) Split(x ops.Node, axis int, numSplits int) (ops.Node, error) {
 	rank := len(shap.AxisLengths)
 
 	if axis < 0 || axis >= rank {
-		return nil, errors.Errorf("axis %d is out of bounds for rank %d", axis, rank)
+		return nil, g.opError("Split", errors.Errorf("axis %d is out of bounds for rank %d", axis, rank), x)
 	}
 	if shap.AxisLengths[axis]%numSplits != 0 {
-		return nil, errors.Errorf("axis %d has size %d which is not divisible by %d numSplits", axis, shap.AxisLengths[axis], numSplits)
+		return nil, g.opError("Split", errors.Errorf("axis %d has size %d which is not divisible by %d numSplits", axis, shap.AxisLengths[axis], numSplits), x)
 	}
 	stride := shap.AxisLengths[axis] / numSplits
 	slicedNodes := make([]ops.Node, numSplits)
@@ -606,7 +801,7 @@ func (g *Graph) Split(x ops.Node, axis int, numSplits int) (ops.Node, error) {
 		limits[axis] = i*stride + stride
 		xlaOp, err := xlabuilder.Slice(g.xlaHandle(x), starts, limits, strides)
 		if err != nil {
-			return nil, err
+			return nil, g.opError("Split", err, x)
 		}
 
 		slicedNodes[i] = g.newNode(xlaOp)
@@ -649,7 +844,7 @@ func (g *Graph) Set(x, updates, position ops.Node) (ops.Node, error) {
 		indexVectorDim, updateWindowDims, insertedWindowDims, scatterDimsToOperandDims,
 		indicesAreSorted, uniqueIndices)
 	if err != nil {
-		return nil, err
+		return nil, g.opError("Set", err, x, updates, position)
 	}
 	return g.newNode(xlaOp), nil
 }
@@ -661,7 +856,7 @@ func (g *Graph) DotGeneral(x, y ops.Node, batchAxes, reduceAxes [2][]int) (ops.N
 		g.xlaHandle(x), reduceAxes[0], batchAxes[0],
 		g.xlaHandle(y), reduceAxes[1], batchAxes[1])
 	if err != nil {
-		return nil, err
+		return nil, g.opError("DotGeneral", err, x, y)
 	}
 	return g.newNode(xlaOp), nil
 }
@@ -679,7 +874,7 @@ func (g *Graph) Call(sg *ops.Subgraph, args ...ops.Node) (ops.Node, error) {
 
 	xlaOp, err := xlabuilder.Call(g.builder, subcomp.comp, argOps...)
 	if err != nil {
-		return nil, err
+		return nil, g.opError("Call", err, args...)
 	}
 	var result ops.Node = g.newNode(xlaOp, subcomp)
 	if _, ok := sg.Result.Node.(ops.Tuple); ok {
@@ -693,7 +888,24 @@ func (g *Graph) Call(sg *ops.Subgraph, args ...ops.Node) (ops.Node, error) {
 func (g *Graph) Subgraph(name string, inputs []*shape.Shape) (ops.Graph, error) {
 	subName := g.builder.Name() + "." + name
 	builder := g.builder.CreateSubBuilder(subName)
-	return newGraph(g.plat, inputs, builder)
+	sub, err := newGraph(g.plat, inputs, builder, g.subcomps)
+	if err != nil {
+		return nil, err
+	}
+	sub.(*Graph).checkShapes = g.checkShapes
+	return sub, nil
+}
+
+// CheckShapes enables or disables the debug mode cross-checking the shapes inferred by GX
+// builtins with the shapes inferred by XLA. Subgraphs inherit the mode of their parent.
+func (g *Graph) CheckShapes(enabled bool) {
+	g.checkShapes = enabled
+}
+
+// ShapeChecks returns true if the shapes inferred by GX builtins need to be cross-checked
+// with the shapes inferred by XLA.
+func (g *Graph) ShapeChecks() bool {
+	return g.checkShapes
 }
 
 // RngBitGenerator takes RNG state and generates the given shape filled with random values, and
@@ -701,7 +913,7 @@ func (g *Graph) Subgraph(name string, inputs []*shape.Shape) (ops.Graph, error)
 func (g *Graph) RngBitGenerator(state ops.Node, shape *shape.Shape) (ops.Node, ops.Node, error) {
 	newState, values, err := xlabuilder.RngBitGenerator(g.xlaHandle(state), pjrtgx.ToShape(shape))
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, g.opError("RngBitGenerator", err, state)
 	}
 	return g.newNode(newState), g.newNode(values), nil
 }
@@ -712,15 +924,113 @@ type subGraph struct {
 	graph *Graph
 }
 
+type (
+	// SubcomputationStats are statistics about the XLA subcomputations of a graph.
+	SubcomputationStats struct {
+		// Built is the number of subcomputations built.
+		Built int
+		// Reused is the number of times a subcomputation already built has been reused.
+		Reused int
+	}
+
+	// subcomputationKey identifies a subcomputation by its operations.
+	// Subgraphs built at different call sites from the same GX function with
+	// the same argument shapes have the same key.
+	subcomputationKey [sha256.Size]byte
+
+	// subcomputations caches the XLA subcomputations built for the subgraphs
+	// of a graph, such that a function called at several call sites is only
+	// built once and shared in the HLO.
+	subcomputations struct {
+		built map[subcomputationKey]*subGraph
+		keys  map[*xlabuilder.XlaComputation]subcomputationKey
+		stats SubcomputationStats
+	}
+)
+
+func newSubcomputations() *subcomputations {
+	return &subcomputations{
+		built: make(map[subcomputationKey]*subGraph),
+		keys:  make(map[*xlabuilder.XlaComputation]subcomputationKey),
+	}
+}
+
+// SubcomputationStats returns the statistics about the subcomputations
+// built for the graph and its subgraphs.
+func (g *Graph) SubcomputationStats() SubcomputationStats {
+	return g.subcomps.stats
+}
+
+// keyOf returns the key of the subcomputation computing an operation given the tuple parameter
+// of the subcomputation (nil if the subcomputation has no argument).
+// The key hashes the type, shape, static arguments, and inputs of all the operations
+// of the subcomputation, including its parameters. The tuple parameter is always hashed,
+// even if the operation does not depend on it, such that subcomputations ignoring their
+// arguments are not reused for arguments of different shapes.
+// Subcomputations called by the operations are identified by their own key if they
+// have been built by the cache, by their address otherwise.
+func (sc *subcomputations) keyOf(params, out *xlabuilder.Op) subcomputationKey {
+	h := sha256.New()
+	ids := make(map[*xlabuilder.Op]int)
+	var visit func(op *xlabuilder.Op) int
+	visit = func(op *xlabuilder.Op) int {
+		if id, ok := ids[op]; ok {
+			return id
+		}
+		inputs := make([]int, len(op.OpInputs))
+		for i, input := range op.OpInputs {
+			inputs[i] = visit(input)
+		}
+		id := len(ids)
+		ids[op] = id
+		fmt.Fprintf(h, "%d=%v%v:%s;%d;%q;%v;%v;%s;%v;",
+			id, op.Type, inputs, op.Shape,
+			op.IntArg, op.StrArg, op.IntsArg, op.FloatArg, op.ShapeArg, op.ReduceType)
+		if op.LiteralArg != nil && !op.LiteralArg.IsNil() {
+			op.LiteralArg.Data(func(data []byte) { h.Write(data) })
+		}
+		for _, comp := range []*xlabuilder.XlaComputation{op.ComputationArg, op.SecondComputationArg} {
+			if comp == nil {
+				continue
+			}
+			if key, ok := sc.keys[comp]; ok {
+				h.Write(key[:])
+			} else {
+				fmt.Fprintf(h, "%p", comp)
+			}
+		}
+		return id
+	}
+	if params != nil {
+		visit(params)
+	}
+	visit(out)
+	var key subcomputationKey
+	h.Sum(key[:0])
+	return key
+}
+
 func (g *Graph) xlaSubcomputation(sg *ops.Subgraph) (*subGraph, error) {
 	pjrtsg := sg.Graph.(*Graph)
 	op := sg.Result.Node
+	var params *xlabuilder.Op
+	if pjrtsg.inputs != nil {
+		params = g.xlaHandle(pjrtsg.inputs)
+	}
+	key := g.subcomps.keyOf(params, g.xlaHandle(op))
+	if sub, ok := g.subcomps.built[key]; ok {
+		g.subcomps.stats.Reused++
+		return sub, nil
+	}
 	sub := &subGraph{graph: pjrtsg, out: op}
 	var err error
 	sub.comp, err = pjrtsg.builder.Build(g.xlaHandle(op))
 	if err != nil {
 		return nil, errors.Errorf("cannot build a subgraph: %v\nSubgraph:\n%s", err, sub.String())
 	}
+	g.subcomps.built[key] = sub
+	g.subcomps.keys[sub.comp] = key
+	g.subcomps.stats.Built++
 	return sub, nil
 }
 
@@ -752,7 +1062,7 @@ func (g *Graph) While(cond, body *ops.Subgraph, state ops.Node) (ops.Node, error
 
 	xlaOp, err := xlabuilder.While(g.xlaHandle(state), condSG.comp, bodySG.comp)
 	if err != nil {
-		return nil, err
+		return nil, g.opError("While", err, state)
 	}
 	var result ops.Node = g.newNode(xlaOp, condSG, bodySG)
 	if _, ok := state.(ops.Tuple); ok {
@@ -0,0 +1,98 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/gomlx/gopjrt/pjrt"
+	"github.com/gomlx/gopjrt/xlabuilder"
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/backend/shape"
+	pjrtplatform "github.com/gx-org/xlapjrt/backend/platform"
+	pjrtgx "github.com/gx-org/xlapjrt"
+)
+
+// hoistedConstant is a constant passed to the executable as a hidden parameter
+// instead of being embedded in the XLA program.
+type hoistedConstant struct {
+	data  []byte
+	shape *shape.Shape
+}
+
+// HoistConstants turns constants of at least threshold bytes into hidden parameters
+// of the executable. The constants are uploaded once to the device when the graph
+// is compiled and passed to the executable on every run.
+// The device buffers of the constants are freed when the runner returned by Compile
+// is closed or garbage collected.
+// A threshold of 0 or less embeds all constants in the XLA program (the default).
+//
+// Hidden parameters follow the arguments of the graph: all arguments need to be
+// created before the first constant is hoisted.
+func (g *Graph) HoistConstants(threshold int) {
+	g.hoistThreshold = threshold
+}
+
+// shouldHoist returns true if a constant of a given size is hoisted.
+// Constants of subgraphs are never hoisted: subcomputations take their arguments from a tuple.
+func (g *Graph) shouldHoist(size int) bool {
+	return g.hoistThreshold > 0 && g.inputs == nil && size >= g.hoistThreshold
+}
+
+// hoistConstant returns a hidden parameter for a constant.
+func (g *Graph) hoistConstant(data []byte, sh *shape.Shape) (ops.Node, error) {
+	index := len(g.in) + len(g.hoisted)
+	op, err := xlabuilder.Parameter(g.builder, "hoisted", index, pjrtgx.ToShape(sh))
+	if err != nil {
+		return nil, err
+	}
+	g.hoisted = append(g.hoisted, &hoistedConstant{
+		// The host buffer may be modified or freed after the graph has been built.
+		data:  slices.Clone(data),
+		shape: sh,
+	})
+	return g.newNode(op).Info("hoisted:%d", index), nil
+}
+
+// uploadHoisted sends the hoisted constants to a device.
+func (g *Graph) uploadHoisted(dev *pjrtplatform.Device) ([]*pjrt.Buffer, error) {
+	buffers := make([]*pjrt.Buffer, len(g.hoisted))
+	for i, cst := range g.hoisted {
+		handle, err := dev.Send(cst.data, cst.shape)
+		if err != nil {
+			destroyBuffers(buffers[:i])
+			return nil, errors.WithMessagef(err, "cannot upload hoisted constant %d", i)
+		}
+		buffers[i] = handle.(*pjrtplatform.Handle).OnDeviceBuffer()
+	}
+	return buffers, nil
+}
+
+// destroyBuffers frees the device memory of hoisted constants.
+func destroyBuffers(buffers []*pjrt.Buffer) error {
+	var firstErr error
+	for _, buffer := range buffers {
+		if err := buffer.Destroy(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// NumHoisted returns the number of constants hoisted into hidden parameters.
+func (g *Graph) NumHoisted() int {
+	return len(g.hoisted)
+}
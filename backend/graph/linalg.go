@@ -0,0 +1,570 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"math"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/xlabuilder"
+	"github.com/gx-org/backend/ops"
+	pjrtplatform "github.com/gx-org/xlapjrt/backend/platform"
+)
+
+// The XLA builder does not provide linear algebra decompositions.
+// They are computed with elementary XLA operations, unrolling the loops
+// over the rows and columns of the matrices when the graph is built.
+// The size of the graph grows with the size of the matrices:
+// these operations are meant for small matrices.
+
+const (
+	// EighSweeps is the number of Jacobi sweeps computed by Eigh.
+	EighSweeps = 10
+	// EighMaxSize is the largest size of the matrices accepted by Eigh.
+	// The number of rotations unrolled in the graph grows with the square of the size
+	// and EighSweeps sweeps are not guaranteed to converge for larger matrices:
+	// Eigh returns a pjrtplatform.ErrUnsupported error instead of inaccurate results.
+	EighMaxSize = 16
+)
+
+// linalg builds operations on batches of matrices: the last two axes of an array
+// are the axes of the matrices and the other axes are batch axes.
+//
+// The first error stops the construction of the graph: all the following
+// operations return nil and the error is returned by done.
+type linalg struct {
+	b     *xlabuilder.XlaBuilder
+	dtype dtypes.DType
+	batch []int
+	err   error
+}
+
+// newLinalg returns a linear algebra builder for x and the number of rows and columns of its matrices.
+func newLinalg(x *xlabuilder.Op) (l *linalg, rows, cols int, err error) {
+	dims := x.Shape.Dimensions
+	if len(dims) < 2 {
+		return nil, 0, 0, errors.Errorf("linear algebra operations require arrays with at least 2 axes, got %v", dims)
+	}
+	switch x.Shape.DType {
+	case dtypes.Float32, dtypes.Float64:
+	default:
+		return nil, 0, 0, errors.Errorf("linear algebra operations do not support %s", x.Shape.DType)
+	}
+	return &linalg{
+		b:     x.Builder(),
+		dtype: x.Shape.DType,
+		batch: dims[:len(dims)-2],
+	}, dims[len(dims)-2], dims[len(dims)-1], nil
+}
+
+// done returns the result of the construction or the first error.
+func (l *linalg) done(xlaOps ...*xlabuilder.Op) ([]*xlabuilder.Op, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+	return xlaOps, nil
+}
+
+func (l *linalg) apply(f func() (*xlabuilder.Op, error)) *xlabuilder.Op {
+	if l.err != nil {
+		return nil
+	}
+	var op *xlabuilder.Op
+	op, l.err = f()
+	return op
+}
+
+// constant returns a rows x cols matrix, without batch axes, given a function computing its elements.
+func (l *linalg) constant(rows, cols int, f func(i, j int) float64) *xlabuilder.Op {
+	return l.apply(func() (*xlabuilder.Op, error) {
+		data := make([]float64, rows*cols)
+		for i := range rows {
+			for j := range cols {
+				data[i*cols+j] = f(i, j)
+			}
+		}
+		lit, err := xlabuilder.NewArrayLiteral(data, rows, cols)
+		if err != nil {
+			return nil, err
+		}
+		op, err := xlabuilder.Constant(l.b, lit)
+		if err != nil {
+			return nil, err
+		}
+		return convertDType(op, l.dtype)
+	})
+}
+
+// scalar returns a 1x1 matrix.
+func (l *linalg) scalar(v float64) *xlabuilder.Op {
+	return l.constant(1, 1, func(int, int) float64 { return v })
+}
+
+// eye returns the identity matrix.
+func (l *linalg) eye(n int) *xlabuilder.Op {
+	return l.constant(n, n, func(i, j int) float64 {
+		if i == j {
+			return 1
+		}
+		return 0
+	})
+}
+
+// unit returns a rows x cols matrix with a single 1 at (i, j).
+func (l *linalg) unit(rows, cols, i, j int) *xlabuilder.Op {
+	return l.constant(rows, cols, func(r, c int) float64 {
+		if r == i && c == j {
+			return 1
+		}
+		return 0
+	})
+}
+
+// broadcast broadcasts x to the given axis lengths.
+// Axes are aligned on the last axis and axes of length 1 are broadcast.
+func (l *linalg) broadcast(x *xlabuilder.Op, dims []int) *xlabuilder.Op {
+	return l.apply(func() (*xlabuilder.Op, error) {
+		if slices.Equal(x.Shape.Dimensions, dims) {
+			return x, nil
+		}
+		axes := make([]int, x.Shape.Rank())
+		for i := range axes {
+			axes[i] = len(dims) - len(axes) + i
+		}
+		return xlabuilder.BroadcastInDim(x, xlabuilder.MakeShape(x.Shape.DType, dims...), axes)
+	})
+}
+
+// broadcastAll broadcasts all the operands to a common shape.
+func (l *linalg) broadcastAll(xs ...*xlabuilder.Op) []*xlabuilder.Op {
+	if l.err != nil {
+		return nil
+	}
+	var dims []int
+	for _, x := range xs {
+		xDims := x.Shape.Dimensions
+		if len(xDims) > len(dims) {
+			dims = append(make([]int, len(xDims)-len(dims)), dims...)
+		}
+		for i, length := range xDims {
+			j := len(dims) - len(xDims) + i
+			dims[j] = max(dims[j], length)
+		}
+	}
+	out := make([]*xlabuilder.Op, len(xs))
+	for i, x := range xs {
+		out[i] = l.broadcast(x, dims)
+	}
+	return out
+}
+
+func (l *linalg) binary(f func(x, y *xlabuilder.Op) (*xlabuilder.Op, error), x, y *xlabuilder.Op) *xlabuilder.Op {
+	xy := l.broadcastAll(x, y)
+	return l.apply(func() (*xlabuilder.Op, error) { return f(xy[0], xy[1]) })
+}
+
+func (l *linalg) unary(f func(x *xlabuilder.Op) (*xlabuilder.Op, error), x *xlabuilder.Op) *xlabuilder.Op {
+	return l.apply(func() (*xlabuilder.Op, error) { return f(x) })
+}
+
+func (l *linalg) add(x, y *xlabuilder.Op) *xlabuilder.Op { return l.binary(xlabuilder.Add, x, y) }
+func (l *linalg) sub(x, y *xlabuilder.Op) *xlabuilder.Op { return l.binary(xlabuilder.Sub, x, y) }
+func (l *linalg) mul(x, y *xlabuilder.Op) *xlabuilder.Op { return l.binary(xlabuilder.Mul, x, y) }
+func (l *linalg) div(x, y *xlabuilder.Op) *xlabuilder.Op { return l.binary(xlabuilder.Div, x, y) }
+
+// where returns onTrue where cond is true, onFalse otherwise.
+func (l *linalg) where(cond, onTrue, onFalse *xlabuilder.Op) *xlabuilder.Op {
+	all := l.broadcastAll(cond, onTrue, onFalse)
+	return l.apply(func() (*xlabuilder.Op, error) { return xlabuilder.Where(all[0], all[1], all[2]) })
+}
+
+// isZero returns true where x is zero.
+func (l *linalg) isZero(x *xlabuilder.Op) *xlabuilder.Op {
+	return l.binary(xlabuilder.Equal, x, l.scalar(0))
+}
+
+// withBatch broadcasts a matrix to the batch axes.
+func (l *linalg) withBatch(x *xlabuilder.Op) *xlabuilder.Op {
+	if l.err != nil {
+		return nil
+	}
+	dims := x.Shape.Dimensions
+	return l.broadcast(x, append(slices.Clone(l.batch), dims[len(dims)-2:]...))
+}
+
+// slice returns the block [r0:r1, c0:c1] of the matrices.
+func (l *linalg) slice(x *xlabuilder.Op, r0, r1, c0, c1 int) *xlabuilder.Op {
+	return l.apply(func() (*xlabuilder.Op, error) {
+		nb := len(l.batch)
+		starts := make([]int, nb+2)
+		starts[nb], starts[nb+1] = r0, c0
+		limits := append(slices.Clone(l.batch), r1, c1)
+		strides := make([]int, nb+2)
+		for i := range strides {
+			strides[i] = 1
+		}
+		return xlabuilder.Slice(x, starts, limits, strides)
+	})
+}
+
+// at returns the element (i, j) of the matrices as 1x1 matrices.
+func (l *linalg) at(x *xlabuilder.Op, i, j int) *xlabuilder.Op {
+	return l.slice(x, i, i+1, j, j+1)
+}
+
+// transpose transposes the matrices.
+func (l *linalg) transpose(x *xlabuilder.Op) *xlabuilder.Op {
+	x = l.withBatch(x)
+	return l.apply(func() (*xlabuilder.Op, error) {
+		nb := len(l.batch)
+		perm := make([]int, nb+2)
+		for i := range perm {
+			perm[i] = i
+		}
+		perm[nb], perm[nb+1] = nb+1, nb
+		return xlabuilder.Transpose(x, perm...)
+	})
+}
+
+// matmul multiplies the matrices.
+func (l *linalg) matmul(x, y *xlabuilder.Op) *xlabuilder.Op {
+	x, y = l.withBatch(x), l.withBatch(y)
+	return l.apply(func() (*xlabuilder.Op, error) {
+		nb := len(l.batch)
+		batchAxes := make([]int, nb)
+		for i := range batchAxes {
+			batchAxes[i] = i
+		}
+		return xlabuilder.DotGeneral(x, []int{nb + 1}, batchAxes, y, []int{nb}, batchAxes)
+	})
+}
+
+// diagonal returns the diagonal of square matrices as vectors.
+func (l *linalg) diagonal(x *xlabuilder.Op, n int) *xlabuilder.Op {
+	masked := l.mul(x, l.eye(n))
+	return l.apply(func() (*xlabuilder.Op, error) { return xlabuilder.ReduceSum(masked, masked.Shape.Rank()-1) })
+}
+
+// cholesky computes the lower triangular matrix L such that x = L L^T.
+// Only the lower triangle of x is read.
+func (l *linalg) cholesky(x *xlabuilder.Op, n int) *xlabuilder.Op {
+	lower := l.withBatch(l.constant(n, n, func(int, int) float64 { return 0 }))
+	for j := range n {
+		row := l.slice(lower, j, j+1, 0, n)
+		v := l.sub(l.slice(x, 0, n, j, j+1), l.matmul(lower, l.transpose(row)))
+		d := l.unary(xlabuilder.Sqrt, l.at(v, j, 0))
+		col := l.mul(l.div(v, d), l.constant(n, 1, func(i, _ int) float64 {
+			if i >= j {
+				return 1
+			}
+			return 0
+		}))
+		lower = l.add(lower, l.mul(col, l.unit(1, n, 0, j)))
+	}
+	return lower
+}
+
+// triangularSolve solves a x = b where a is a lower (or upper) triangular n x n matrix.
+// Only the lower (or upper) triangle of a is read.
+func (l *linalg) triangularSolve(a, b *xlabuilder.Op, n, k int, lower bool) *xlabuilder.Op {
+	x := l.withBatch(l.constant(n, k, func(int, int) float64 { return 0 }))
+	for step := range n {
+		i := step
+		if !lower {
+			i = n - 1 - step
+		}
+		r := l.sub(l.slice(b, i, i+1, 0, k), l.matmul(l.slice(a, i, i+1, 0, n), x))
+		xi := l.div(r, l.at(a, i, i))
+		x = l.add(x, l.mul(l.unit(n, 1, i, 0), xi))
+	}
+	return x
+}
+
+// qr computes the complete QR decomposition of m x n matrices with Householder reflections.
+// It returns q (m x m), r (m x n), and the sign of the determinant of q.
+func (l *linalg) qr(x *xlabuilder.Op, m, n int) (q, r, sign *xlabuilder.Op) {
+	r = x
+	q = l.withBatch(l.eye(m))
+	sign = l.withBatch(l.scalar(1))
+	for j := range min(m-1, n) {
+		col := l.mul(l.slice(r, 0, m, j, j+1), l.constant(m, 1, func(i, _ int) float64 {
+			if i >= j {
+				return 1
+			}
+			return 0
+		}))
+		norm := l.unary(xlabuilder.Sqrt, l.matmul(l.transpose(col), col))
+		negative := l.binary(xlabuilder.LessThan, l.at(col, j, 0), l.scalar(0))
+		alpha := l.where(negative, l.unary(xlabuilder.Neg, norm), norm)
+		v := l.add(col, l.mul(alpha, l.unit(m, 1, j, 0)))
+		vv := l.matmul(l.transpose(v), v)
+		zero := l.isZero(vv)
+		tau := l.where(zero, l.scalar(0), l.div(l.scalar(2), l.where(zero, l.scalar(1), vv)))
+		r = l.sub(r, l.mul(tau, l.matmul(v, l.matmul(l.transpose(v), r))))
+		q = l.sub(q, l.mul(tau, l.matmul(l.matmul(q, v), l.transpose(v))))
+		sign = l.mul(sign, l.where(zero, l.scalar(1), l.scalar(-1)))
+	}
+	r = l.mul(r, l.constant(m, n, func(i, j int) float64 {
+		if j >= i {
+			return 1
+		}
+		return 0
+	}))
+	return q, r, sign
+}
+
+// eigh computes the eigenvalues, in ascending order, and the eigenvectors of symmetric n x n matrices
+// with the cyclic Jacobi algorithm.
+func (l *linalg) eigh(x *xlabuilder.Op, n int) (w, v *xlabuilder.Op) {
+	a := x
+	v = l.withBatch(l.eye(n))
+	for range EighSweeps {
+		for p := range n {
+			for q := p + 1; q < n; q++ {
+				app, aqq, apq := l.at(a, p, p), l.at(a, q, q), l.at(a, p, q)
+				zero := l.isZero(apq)
+				theta := l.div(l.sub(aqq, app), l.mul(l.scalar(2), l.where(zero, l.scalar(1), apq)))
+				thetaSign := l.where(l.binary(xlabuilder.LessThan, theta, l.scalar(0)), l.scalar(-1), l.scalar(1))
+				hyp := l.unary(xlabuilder.Sqrt, l.add(l.mul(theta, theta), l.scalar(1)))
+				t := l.div(thetaSign, l.add(l.unary(xlabuilder.Abs, theta), hyp))
+				t = l.where(zero, l.scalar(0), t)
+				c := l.unary(xlabuilder.Rsqrt, l.add(l.mul(t, t), l.scalar(1)))
+				s := l.mul(t, c)
+				rot := l.add(l.eye(n), l.mul(l.sub(c, l.scalar(1)), l.add(l.unit(n, n, p, p), l.unit(n, n, q, q))))
+				rot = l.add(rot, l.mul(s, l.sub(l.unit(n, n, p, q), l.unit(n, n, q, p))))
+				a = l.matmul(l.transpose(rot), l.matmul(a, rot))
+				v = l.matmul(v, rot)
+			}
+		}
+	}
+	return l.sortEigen(l.diagonal(a, n), v, n)
+}
+
+// sortEigen sorts eigenvalues, and their eigenvectors, in ascending order.
+func (l *linalg) sortEigen(w, v *xlabuilder.Op, n int) (sortedW, sortedV *xlabuilder.Op) {
+	if l.err != nil {
+		return nil, nil
+	}
+	last := w.Shape.Rank() - 1
+	iota := l.apply(func() (*xlabuilder.Op, error) {
+		return xlabuilder.Iota(l.b, xlabuilder.MakeShape(dtypes.Int32, w.Shape.Dimensions...), last)
+	})
+	inf := l.apply(func() (*xlabuilder.Op, error) {
+		return xlabuilder.Reshape(l.scalar(math.Inf(1)), 1)
+	})
+	ws := make([]*xlabuilder.Op, n)
+	vs := make([]*xlabuilder.Op, n)
+	for k := range n {
+		idx := l.apply(func() (*xlabuilder.Op, error) {
+			idx, err := xlabuilder.ArgMinMax(w, last, dtypes.Int32, true)
+			if err != nil {
+				return nil, err
+			}
+			return xlabuilder.Reshape(idx, append(slices.Clone(l.batch), 1)...)
+		})
+		selected := l.binary(xlabuilder.Equal, iota, idx)
+		oneHot := l.unary(func(x *xlabuilder.Op) (*xlabuilder.Op, error) { return xlabuilder.ConvertDType(x, l.dtype) }, selected)
+		masked := l.mul(w, oneHot)
+		ws[k] = l.apply(func() (*xlabuilder.Op, error) {
+			wk, err := xlabuilder.ReduceSum(masked, last)
+			if err != nil {
+				return nil, err
+			}
+			return xlabuilder.Reshape(wk, append(slices.Clone(l.batch), 1)...)
+		})
+		vs[k] = l.matmul(v, l.apply(func() (*xlabuilder.Op, error) {
+			return xlabuilder.Reshape(oneHot, append(slices.Clone(l.batch), n, 1)...)
+		}))
+		w = l.where(selected, inf, w)
+	}
+	sortedW = l.apply(func() (*xlabuilder.Op, error) { return xlabuilder.Concatenate(last, ws...) })
+	sortedV = l.apply(func() (*xlabuilder.Op, error) { return xlabuilder.Concatenate(last+1, vs...) })
+	return sortedW, sortedV
+}
+
+// squareLinalg returns a linear algebra builder for square matrices and their size.
+func squareLinalg(x *xlabuilder.Op) (*linalg, int, error) {
+	l, rows, cols, err := newLinalg(x)
+	if err != nil {
+		return nil, 0, err
+	}
+	if rows != cols {
+		return nil, 0, errors.Errorf("matrices must be square, got %d x %d", rows, cols)
+	}
+	return l, rows, nil
+}
+
+func (g *Graph) linalgNodes(xlaOps []*xlabuilder.Op, deps ...ops.Node) []ops.Node {
+	nodes := make([]ops.Node, len(xlaOps))
+	for i, op := range xlaOps {
+		nodes[i] = g.newNode(op, deps...)
+	}
+	return nodes
+}
+
+// Cholesky returns the lower triangular matrices L such that x = L L^T
+// for a batch of symmetric positive definite matrices x.
+// Only the lower triangle of x is read. The result contains NaNs
+// if a matrix is not positive definite.
+func (g *Graph) Cholesky(x ops.Node) (ops.Node, error) {
+	l, n, err := squareLinalg(g.xlaHandle(x))
+	if err != nil {
+		return nil, g.opError("Cholesky", err, x)
+	}
+	out, err := l.done(l.cholesky(g.xlaHandle(x), n))
+	if err != nil {
+		return nil, g.opError("Cholesky", err, x)
+	}
+	return g.newNode(out[0], x).Info("cholesky"), nil
+}
+
+// TriangularSolve returns x solving a x = b, where a is a batch of lower triangular matrices
+// if lower is true, upper triangular matrices otherwise. b has the same batch axes as a.
+// Only the lower (or upper) triangle of a is read.
+func (g *Graph) TriangularSolve(a, b ops.Node, lower bool) (ops.Node, error) {
+	l, n, err := squareLinalg(g.xlaHandle(a))
+	if err != nil {
+		return nil, g.opError("TriangularSolve", err, a, b)
+	}
+	bOp := g.xlaHandle(b)
+	bDims := bOp.Shape.Dimensions
+	if len(bDims) != len(l.batch)+2 || !slices.Equal(bDims[:len(l.batch)], l.batch) || bDims[len(bDims)-2] != n {
+		return nil, g.opError("TriangularSolve", errors.Errorf("cannot solve a system with matrices of shape %v and right-hand sides of shape %v", g.xlaHandle(a).Shape.Dimensions, bDims), a, b)
+	}
+	if bOp.Shape.DType != l.dtype {
+		return nil, g.opError("TriangularSolve", errors.Errorf("mismatched data types %s and %s", l.dtype, bOp.Shape.DType), a, b)
+	}
+	out, err := l.done(l.triangularSolve(g.xlaHandle(a), bOp, n, bDims[len(bDims)-1], lower))
+	if err != nil {
+		return nil, g.opError("TriangularSolve", err, a, b)
+	}
+	return g.newNode(out[0], a, b).Info("triangular solve"), nil
+}
+
+// QR returns the reduced QR decomposition of a batch of m x n matrices x:
+// q (m x k) has orthonormal columns, r (k x n) is upper triangular, x = q r, and k = min(m, n).
+func (g *Graph) QR(x ops.Node) (q, r ops.Node, err error) {
+	xOp := g.xlaHandle(x)
+	l, m, n, err := newLinalg(xOp)
+	if err != nil {
+		return nil, nil, g.opError("QR", err, x)
+	}
+	qOp, rOp, _ := l.qr(xOp, m, n)
+	k := min(m, n)
+	out, err := l.done(l.slice(qOp, 0, m, 0, k), l.slice(rOp, 0, k, 0, n))
+	if err != nil {
+		return nil, nil, g.opError("QR", err, x)
+	}
+	nodes := g.linalgNodes(out, x)
+	return nodes[0], nodes[1], nil
+}
+
+// Eigh returns the eigenvalues, in ascending order, and the eigenvectors, as columns,
+// of a batch of symmetric matrices of size at most EighMaxSize.
+func (g *Graph) Eigh(x ops.Node) (w, v ops.Node, err error) {
+	xOp := g.xlaHandle(x)
+	l, n, err := squareLinalg(xOp)
+	if err != nil {
+		return nil, nil, g.opError("Eigh", err, x)
+	}
+	if n > EighMaxSize {
+		return nil, nil, g.opError("Eigh", pjrtplatform.NewError(pjrtplatform.ErrUnsupported, nil, "cannot compute the eigendecomposition of %d x %d matrices: the size of the matrices is limited to %d", n, n, EighMaxSize), x)
+	}
+	out, err := l.done(l.eigh(xOp, n))
+	if err != nil {
+		return nil, nil, g.opError("Eigh", err, x)
+	}
+	nodes := g.linalgNodes(out, x)
+	return nodes[0], nodes[1], nil
+}
+
+// determinant returns the sign of the determinant of square matrices and the absolute value
+// of the diagonal of r such that the absolute value of the determinant is the product of that diagonal.
+func (l *linalg) determinant(x *xlabuilder.Op, n int) (sign, diag *xlabuilder.Op) {
+	_, r, qSign := l.qr(x, n, n)
+	diag = l.diagonal(r, n)
+	last := len(l.batch)
+	rSign := l.apply(func() (*xlabuilder.Op, error) {
+		signs, err := xlabuilder.Sign(diag)
+		if err != nil {
+			return nil, err
+		}
+		return xlabuilder.ReduceProduct(signs, last)
+	})
+	qSign = l.apply(func() (*xlabuilder.Op, error) { return xlabuilder.Reshape(qSign, l.batch...) })
+	sign = l.apply(func() (*xlabuilder.Op, error) { return xlabuilder.Mul(rSign, qSign) })
+	return sign, l.unary(xlabuilder.Abs, diag)
+}
+
+// Det returns the determinant of a batch of square matrices.
+func (g *Graph) Det(x ops.Node) (ops.Node, error) {
+	xOp := g.xlaHandle(x)
+	l, n, err := squareLinalg(xOp)
+	if err != nil {
+		return nil, g.opError("Det", err, x)
+	}
+	sign, diag := l.determinant(xOp, n)
+	det := l.apply(func() (*xlabuilder.Op, error) {
+		abs, err := xlabuilder.ReduceProduct(diag, len(l.batch))
+		if err != nil {
+			return nil, err
+		}
+		return xlabuilder.Mul(sign, abs)
+	})
+	out, err := l.done(det)
+	if err != nil {
+		return nil, g.opError("Det", err, x)
+	}
+	return g.newNode(out[0], x).Info("det"), nil
+}
+
+// LogDet returns the logarithm of the absolute value of the determinant of a batch of square matrices.
+func (g *Graph) LogDet(x ops.Node) (ops.Node, error) {
+	xOp := g.xlaHandle(x)
+	l, n, err := squareLinalg(xOp)
+	if err != nil {
+		return nil, g.opError("LogDet", err, x)
+	}
+	_, diag := l.determinant(xOp, n)
+	logDet := l.apply(func() (*xlabuilder.Op, error) {
+		logs, err := xlabuilder.Log(diag)
+		if err != nil {
+			return nil, err
+		}
+		return xlabuilder.ReduceSum(logs, len(l.batch))
+	})
+	out, err := l.done(logDet)
+	if err != nil {
+		return nil, g.opError("LogDet", err, x)
+	}
+	return g.newNode(out[0], x).Info("logdet"), nil
+}
+
+// Inv returns the inverse of a batch of square matrices computed from their QR decomposition.
+func (g *Graph) Inv(x ops.Node) (ops.Node, error) {
+	xOp := g.xlaHandle(x)
+	l, n, err := squareLinalg(xOp)
+	if err != nil {
+		return nil, g.opError("Inv", err, x)
+	}
+	q, r, _ := l.qr(xOp, n, n)
+	out, err := l.done(l.triangularSolve(r, l.transpose(q), n, n, false))
+	if err != nil {
+		return nil, g.opError("Inv", err, x)
+	}
+	return g.newNode(out[0], x).Info("inv"), nil
+}
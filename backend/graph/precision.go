@@ -0,0 +1,57 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/xlabuilder"
+	"github.com/gx-org/backend/ops"
+)
+
+// LowPrecisionFloats maps the names of low precision floating-point formats to XLA data types.
+// Apart from bfloat16, GX does not have these data types: arrays are stored with a GX
+// floating-point data type and their values rounded to the low precision format.
+var LowPrecisionFloats = map[string]dtypes.DType{
+	"bfloat16":   dtypes.BFloat16,
+	"float16":    dtypes.Float16,
+	"float8e4m3": dtypes.F8E4M3FN,
+	"float8e5m2": dtypes.F8E5M2,
+}
+
+// RoundToPrecision returns a node rounding the elements of x to the nearest value
+// representable by a low precision floating-point data type.
+// The values are converted to the target data type, then back to the data type of x.
+func (g *Graph) RoundToPrecision(x ops.Node, target dtypes.DType) (ops.Node, error) {
+	xOp := g.xlaHandle(x)
+	if !xOp.Shape.DType.IsFloat() {
+		return nil, g.opError("RoundToPrecision", errors.Errorf("cannot round %s values: only floating-point values can be rounded", xOp.Shape.DType), x)
+	}
+	if !slices.Contains(slices.Collect(maps.Values(LowPrecisionFloats)), target) {
+		return nil, g.opError("RoundToPrecision", errors.Errorf("cannot round %s values to %s: not a low precision floating-point data type", xOp.Shape.DType, target), x)
+	}
+	low, err := xlabuilder.ConvertDType(xOp, target)
+	if err != nil {
+		return nil, g.opError("RoundToPrecision", err, x)
+	}
+	out, err := xlabuilder.ConvertDType(low, xOp.Shape.DType)
+	if err != nil {
+		return nil, g.opError("RoundToPrecision", err, x)
+	}
+	return g.newNode(out, x).Info("round to %s", target), nil
+}
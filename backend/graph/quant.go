@@ -0,0 +1,149 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/xlabuilder"
+	"github.com/gx-org/backend/ops"
+)
+
+// Range of the values of int8 quantized arrays.
+//
+// GX does not have an int8 data type: quantized values are stored in int32 arrays.
+// Their dot products are exact when computed in int32.
+const (
+	QuantizedMin = math.MinInt8
+	QuantizedMax = math.MaxInt8
+)
+
+// broadcastQuantParam broadcasts a quantization scale or zero-point to the axis lengths of an array.
+// A scalar applies to the whole array (per-tensor quantization).
+// Otherwise, the parameter has a single axis of the length of the quantized axis (per-axis quantization).
+func broadcastQuantParam(param *xlabuilder.Op, dims []int, axis int) (*xlabuilder.Op, error) {
+	if param.Shape.IsScalar() {
+		return xlabuilder.Broadcast(param, dims...)
+	}
+	if axis < 0 || axis >= len(dims) {
+		return nil, errors.Errorf("invalid quantization axis %d for an array with %d axes", axis, len(dims))
+	}
+	if param.Shape.Rank() != 1 || param.Shape.Dimensions[0] != dims[axis] {
+		return nil, errors.Errorf("cannot quantize axis %d of length %d with parameters of shape %s", axis, dims[axis], param.Shape)
+	}
+	return xlabuilder.BroadcastInDim(param, xlabuilder.MakeShape(param.Shape.DType, dims...), []int{axis})
+}
+
+// quantParams returns the scale and zero-point broadcast to the axis lengths of an array,
+// with the zero-point converted to the data type of the scale.
+func quantParams(scale, zeroPoint *xlabuilder.Op, dims []int, axis int) (s, z *xlabuilder.Op, err error) {
+	if !scale.Shape.DType.IsFloat() {
+		return nil, nil, errors.Errorf("invalid quantization scale data type %s: must be a floating-point data type", scale.Shape.DType)
+	}
+	if !zeroPoint.Shape.DType.IsInt() {
+		return nil, nil, errors.Errorf("invalid quantization zero-point data type %s: must be an integer data type", zeroPoint.Shape.DType)
+	}
+	if s, err = broadcastQuantParam(scale, dims, axis); err != nil {
+		return nil, nil, err
+	}
+	if z, err = broadcastQuantParam(zeroPoint, dims, axis); err != nil {
+		return nil, nil, err
+	}
+	if z, err = xlabuilder.ConvertDType(z, scale.Shape.DType); err != nil {
+		return nil, nil, err
+	}
+	return s, z, nil
+}
+
+// Quantize returns a node quantizing floating-point values to int8 values, stored as int32:
+//
+//	q = clamp(round(x/scale) + zeroPoint, QuantizedMin, QuantizedMax)
+//
+// Halfway values are rounded away from zero.
+// scale has the data type of x. scale and zeroPoint are either scalars or have a single axis
+// of the length of the given axis of x.
+func (g *Graph) Quantize(x, scale, zeroPoint ops.Node, axis int) (ops.Node, error) {
+	xOp := g.xlaHandle(x)
+	if xOp.Shape.DType != g.xlaHandle(scale).Shape.DType {
+		return nil, g.opError("Quantize", errors.Errorf("cannot quantize %s values with a %s scale", xOp.Shape.DType, g.xlaHandle(scale).Shape.DType), x, scale, zeroPoint)
+	}
+	s, z, err := quantParams(g.xlaHandle(scale), g.xlaHandle(zeroPoint), xOp.Shape.Dimensions, axis)
+	if err != nil {
+		return nil, g.opError("Quantize", err, x, scale, zeroPoint)
+	}
+	q, err := xlabuilder.Div(xOp, s)
+	if err != nil {
+		return nil, g.opError("Quantize", err, x, scale, zeroPoint)
+	}
+	if q, err = xlabuilder.Round(q); err != nil {
+		return nil, g.opError("Quantize", err, x, scale, zeroPoint)
+	}
+	if q, err = xlabuilder.Add(q, z); err != nil {
+		return nil, g.opError("Quantize", err, x, scale, zeroPoint)
+	}
+	bounds := [2]*xlabuilder.Op{}
+	for i, val := range []float64{QuantizedMin, QuantizedMax} {
+		literal, err := xlabuilder.NewScalarLiteralFromFloat64(val, xOp.Shape.DType)
+		if err != nil {
+			return nil, g.opError("Quantize", err, x, scale, zeroPoint)
+		}
+		if bounds[i], err = xlabuilder.Constant(xOp.Builder(), literal); err != nil {
+			return nil, g.opError("Quantize", err, x, scale, zeroPoint)
+		}
+		if bounds[i], err = xlabuilder.Broadcast(bounds[i], xOp.Shape.Dimensions...); err != nil {
+			return nil, g.opError("Quantize", err, x, scale, zeroPoint)
+		}
+	}
+	if q, err = xlabuilder.Max(q, bounds[0]); err != nil {
+		return nil, g.opError("Quantize", err, x, scale, zeroPoint)
+	}
+	if q, err = xlabuilder.Min(q, bounds[1]); err != nil {
+		return nil, g.opError("Quantize", err, x, scale, zeroPoint)
+	}
+	if q, err = xlabuilder.ConvertDType(q, dtypes.Int32); err != nil {
+		return nil, g.opError("Quantize", err, x, scale, zeroPoint)
+	}
+	return g.newNode(q, x, scale, zeroPoint), nil
+}
+
+// Dequantize returns a node computing floating-point values from quantized values:
+//
+//	x = (q - zeroPoint) * scale
+//
+// The result has the data type of scale. scale and zeroPoint are either scalars or have
+// a single axis of the length of the given axis of q.
+func (g *Graph) Dequantize(q, scale, zeroPoint ops.Node, axis int) (ops.Node, error) {
+	qOp, scaleOp := g.xlaHandle(q), g.xlaHandle(scale)
+	if !qOp.Shape.DType.IsInt() {
+		return nil, g.opError("Dequantize", errors.Errorf("cannot dequantize %s values: quantized values must be integers", qOp.Shape.DType), q, scale, zeroPoint)
+	}
+	s, z, err := quantParams(scaleOp, g.xlaHandle(zeroPoint), qOp.Shape.Dimensions, axis)
+	if err != nil {
+		return nil, g.opError("Dequantize", err, q, scale, zeroPoint)
+	}
+	x, err := xlabuilder.ConvertDType(qOp, scaleOp.Shape.DType)
+	if err != nil {
+		return nil, g.opError("Dequantize", err, q, scale, zeroPoint)
+	}
+	if x, err = xlabuilder.Sub(x, z); err != nil {
+		return nil, g.opError("Dequantize", err, q, scale, zeroPoint)
+	}
+	if x, err = xlabuilder.Mul(x, s); err != nil {
+		return nil, g.opError("Dequantize", err, q, scale, zeroPoint)
+	}
+	return g.newNode(x, q, scale, zeroPoint), nil
+}
@@ -0,0 +1,434 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"math"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/xlabuilder"
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/backend/shape"
+	pjrtgx "github.com/gx-org/xlapjrt"
+)
+
+// sampler samples random values from the bits generated by the XLA RngBitGenerator.
+// Each sample consumes the current state and replaces it with the new state.
+//
+// The first error stops the construction of the graph: all the following
+// operations return nil and the error is returned by done.
+type sampler struct {
+	b     *xlabuilder.XlaBuilder
+	state *xlabuilder.Op
+	err   error
+}
+
+func (g *Graph) newSampler(state ops.Node) *sampler {
+	stateOp := g.xlaHandle(state)
+	return &sampler{b: stateOp.Builder(), state: stateOp}
+}
+
+// done returns a node for the new state and the values or the first error.
+func (s *sampler) done(g *Graph, state ops.Node, values *xlabuilder.Op, deps ...ops.Node) (newState, vals ops.Node, err error) {
+	if s.err != nil {
+		return nil, nil, s.err
+	}
+	return g.newNode(s.state, state), g.newNode(values, append([]ops.Node{state}, deps...)...), nil
+}
+
+func (s *sampler) apply(f func() (*xlabuilder.Op, error)) *xlabuilder.Op {
+	if s.err != nil {
+		return nil
+	}
+	var op *xlabuilder.Op
+	op, s.err = f()
+	return op
+}
+
+// bits returns random bits of a given unsigned integer data type.
+func (s *sampler) bits(dtype dtypes.DType, dims []int) *xlabuilder.Op {
+	return s.apply(func() (*xlabuilder.Op, error) {
+		newState, values, err := xlabuilder.RngBitGenerator(s.state, xlabuilder.MakeShape(dtype, dims...))
+		if err != nil {
+			return nil, err
+		}
+		s.state = newState
+		return values, nil
+	})
+}
+
+// full returns an array of the given data type and axis lengths filled with a value.
+func (s *sampler) full(v float64, dtype dtypes.DType, dims []int) *xlabuilder.Op {
+	return s.apply(func() (*xlabuilder.Op, error) {
+		lit, err := xlabuilder.NewScalarLiteralFromFloat64(v, dtype)
+		if err != nil {
+			return nil, err
+		}
+		op, err := xlabuilder.Constant(s.b, lit)
+		if err != nil {
+			return nil, err
+		}
+		return xlabuilder.Broadcast(op, dims...)
+	})
+}
+
+func (s *sampler) binary(f func(x, y *xlabuilder.Op) (*xlabuilder.Op, error), x, y *xlabuilder.Op) *xlabuilder.Op {
+	return s.apply(func() (*xlabuilder.Op, error) { return f(x, y) })
+}
+
+func (s *sampler) unary(f func(x *xlabuilder.Op) (*xlabuilder.Op, error), x *xlabuilder.Op) *xlabuilder.Op {
+	return s.apply(func() (*xlabuilder.Op, error) { return f(x) })
+}
+
+// broadcast broadcasts a scalar to the given axis lengths.
+func (s *sampler) broadcast(x *xlabuilder.Op, dims []int) *xlabuilder.Op {
+	return s.apply(func() (*xlabuilder.Op, error) {
+		if !x.Shape.IsScalar() {
+			return nil, errors.Errorf("distribution parameter must be a scalar, got %s", x.Shape)
+		}
+		return xlabuilder.Broadcast(x, dims...)
+	})
+}
+
+// uniform returns floating-point values uniformly distributed in [0, 1).
+// All the bits of the mantissa are random: the values are multiples of 2^-24 for float32
+// and 2^-53 for float64.
+func (s *sampler) uniform(dtype dtypes.DType, dims []int) *xlabuilder.Op {
+	var bitsType dtypes.DType
+	var mantissa int
+	switch dtype {
+	case dtypes.Float32:
+		bitsType, mantissa = dtypes.Uint32, 24
+	case dtypes.Float64:
+		bitsType, mantissa = dtypes.Uint64, 53
+	default:
+		if s.err == nil {
+			s.err = errors.Errorf("cannot sample random %s values: only float32 and float64 are supported", dtype)
+		}
+		return nil
+	}
+	bits := s.bits(bitsType, dims)
+	shift := s.full(float64(bitsType.Bits()-mantissa), bitsType, dims)
+	bits = s.binary(xlabuilder.ShiftRightLogical, bits, shift)
+	floats := s.apply(func() (*xlabuilder.Op, error) { return xlabuilder.ConvertDType(bits, dtype) })
+	return s.binary(xlabuilder.Mul, floats, s.full(math.Ldexp(1, -mantissa), dtype, dims))
+}
+
+// normal returns standard normal values computed with the Box-Muller transform.
+func (s *sampler) normal(dtype dtypes.DType, dims []int) *xlabuilder.Op {
+	u1 := s.uniform(dtype, dims)
+	u2 := s.uniform(dtype, dims)
+	// 1-u1 is in (0, 1]: its logarithm is finite.
+	one := s.full(1, dtype, dims)
+	radius := s.unary(xlabuilder.Log, s.binary(xlabuilder.Sub, one, u1))
+	radius = s.unary(xlabuilder.Sqrt, s.binary(xlabuilder.Mul, s.full(-2, dtype, dims), radius))
+	angle := s.unary(xlabuilder.Cos, s.binary(xlabuilder.Mul, s.full(2*math.Pi, dtype, dims), u2))
+	return s.binary(xlabuilder.Mul, radius, angle)
+}
+
+// erfInv returns the inverse of the error function.
+// The initial approximation, from M. Giles, "Approximating the erfinv function",
+// is refined with Newton steps.
+func (s *sampler) erfInv(x *xlabuilder.Op) *xlabuilder.Op {
+	if s.err != nil {
+		return nil
+	}
+	dtype, dims := x.Shape.DType, x.Shape.Dimensions
+	c := func(v float64) *xlabuilder.Op { return s.full(v, dtype, dims) }
+	add := func(x, y *xlabuilder.Op) *xlabuilder.Op { return s.binary(xlabuilder.Add, x, y) }
+	mul := func(x, y *xlabuilder.Op) *xlabuilder.Op { return s.binary(xlabuilder.Mul, x, y) }
+	sub := func(x, y *xlabuilder.Op) *xlabuilder.Op { return s.binary(xlabuilder.Sub, x, y) }
+	polynomial := func(w *xlabuilder.Op, coeffs []float64) *xlabuilder.Op {
+		p := c(coeffs[0])
+		for _, coeff := range coeffs[1:] {
+			p = add(c(coeff), mul(p, w))
+		}
+		return p
+	}
+	w := s.unary(xlabuilder.Neg, s.unary(xlabuilder.Log, mul(sub(c(1), x), add(c(1), x))))
+	central := polynomial(sub(w, c(2.5)), []float64{
+		2.81022636e-08, 3.43273939e-07, -3.5233877e-06, -4.39150654e-06, 0.00021858087,
+		-0.00125372503, -0.00417768164, 0.246640727, 1.50140941,
+	})
+	tail := polynomial(sub(s.unary(xlabuilder.Sqrt, w), c(3)), []float64{
+		-0.000200214257, 0.000100950558, 0.00134934322, -0.00367342844, 0.00573950773,
+		-0.0076224613, 0.00943887047, 1.00167406, 2.83297682,
+	})
+	isCentral := s.binary(xlabuilder.LessThan, w, c(5))
+	p := s.apply(func() (*xlabuilder.Op, error) { return xlabuilder.Where(isCentral, central, tail) })
+	z := mul(p, x)
+	for range 2 {
+		// Newton step: z -= (erf(z) - x) / erf'(z), skipped when erf'(z) underflows.
+		deriv := mul(c(2/math.Sqrt(math.Pi)), s.unary(xlabuilder.Exp, s.unary(xlabuilder.Neg, mul(z, z))))
+		step := s.binary(xlabuilder.Div, sub(s.unary(xlabuilder.Erf, z), x), deriv)
+		hasDeriv := s.binary(xlabuilder.GreaterThan, deriv, c(0))
+		step = s.apply(func() (*xlabuilder.Op, error) { return xlabuilder.Where(hasDeriv, step, c(0)) })
+		z = sub(z, step)
+	}
+	return z
+}
+
+// normalCDF returns the cumulative distribution function of the standard normal distribution.
+func (s *sampler) normalCDF(x *xlabuilder.Op) *xlabuilder.Op {
+	dtype, dims := x.Shape.DType, x.Shape.Dimensions
+	erf := s.unary(xlabuilder.Erf, s.binary(xlabuilder.Mul, x, s.full(1/math.Sqrt2, dtype, dims)))
+	return s.binary(xlabuilder.Mul, s.full(0.5, dtype, dims), s.binary(xlabuilder.Add, s.full(1, dtype, dims), erf))
+}
+
+// randomDType returns the XLA data type of a GX shape.
+func randomDType(sh *shape.Shape) (dtypes.DType, error) {
+	dtype := pjrtgx.ToDType(sh.DType)
+	if dtype != dtypes.Float32 && dtype != dtypes.Float64 {
+		return dtypes.InvalidDType, errors.Errorf("cannot sample random %s values: only float32 and float64 are supported", sh.DType)
+	}
+	return dtype, nil
+}
+
+// RandomUniform returns the new Philox state and floating-point values uniformly distributed in [0, 1).
+func (g *Graph) RandomUniform(state ops.Node, sh *shape.Shape) (newState, values ops.Node, err error) {
+	dtype, err := randomDType(sh)
+	if err != nil {
+		return nil, nil, g.opError("RandomUniform", err, state)
+	}
+	s := g.newSampler(state)
+	if newState, values, err = s.done(g, state, s.uniform(dtype, sh.AxisLengths)); err != nil {
+		return nil, nil, g.opError("RandomUniform", err, state)
+	}
+	return newState, values, nil
+}
+
+// RandomNormal returns the new Philox state and values from the standard normal distribution.
+func (g *Graph) RandomNormal(state ops.Node, sh *shape.Shape) (newState, values ops.Node, err error) {
+	dtype, err := randomDType(sh)
+	if err != nil {
+		return nil, nil, g.opError("RandomNormal", err, state)
+	}
+	s := g.newSampler(state)
+	if newState, values, err = s.done(g, state, s.normal(dtype, sh.AxisLengths)); err != nil {
+		return nil, nil, g.opError("RandomNormal", err, state)
+	}
+	return newState, values, nil
+}
+
+// RandomTruncatedNormal returns the new Philox state and values from the standard normal distribution
+// truncated to [lower, upper]. lower and upper are scalars with the data type of the values.
+// Values are computed by inverting the cumulative distribution function.
+func (g *Graph) RandomTruncatedNormal(state, lower, upper ops.Node, sh *shape.Shape) (newState, values ops.Node, err error) {
+	dtype, err := randomDType(sh)
+	if err != nil {
+		return nil, nil, g.opError("RandomTruncatedNormal", err, state, lower, upper)
+	}
+	dims := sh.AxisLengths
+	s := g.newSampler(state)
+	lo := s.broadcast(g.xlaHandle(lower), dims)
+	hi := s.broadcast(g.xlaHandle(upper), dims)
+	cdfLo, cdfHi := s.normalCDF(lo), s.normalCDF(hi)
+	u := s.uniform(dtype, dims)
+	p := s.binary(xlabuilder.Add, cdfLo, s.binary(xlabuilder.Mul, u, s.binary(xlabuilder.Sub, cdfHi, cdfLo)))
+	x := s.binary(xlabuilder.Sub, s.binary(xlabuilder.Mul, s.full(2, dtype, dims), p), s.full(1, dtype, dims))
+	z := s.binary(xlabuilder.Mul, s.full(math.Sqrt2, dtype, dims), s.erfInv(x))
+	// Clamp to the interval to remove rounding errors.
+	z = s.binary(xlabuilder.Min, s.binary(xlabuilder.Max, z, lo), hi)
+	if newState, values, err = s.done(g, state, z, lower, upper); err != nil {
+		return nil, nil, g.opError("RandomTruncatedNormal", err, state, lower, upper)
+	}
+	return newState, values, nil
+}
+
+// RandomBernoulli returns the new Philox state and booleans equal to true with probability p.
+// p is a float32 or float64 scalar.
+func (g *Graph) RandomBernoulli(state, p ops.Node, dims []int) (newState, values ops.Node, err error) {
+	pOp := g.xlaHandle(p)
+	s := g.newSampler(state)
+	u := s.uniform(pOp.Shape.DType, dims)
+	if newState, values, err = s.done(g, state, s.binary(xlabuilder.LessThan, u, s.broadcast(pOp, dims)), p); err != nil {
+		return nil, nil, g.opError("RandomBernoulli", err, state, p)
+	}
+	return newState, values, nil
+}
+
+// RandomCategorical returns the new Philox state and int64 indices sampled from the
+// categorical distribution defined by logits, an array with a single axis.
+// Indices are computed with the Gumbel-max trick.
+func (g *Graph) RandomCategorical(state, logits ops.Node, dims []int) (newState, values ops.Node, err error) {
+	logitsOp := g.xlaHandle(logits)
+	if logitsOp.Shape.Rank() != 1 {
+		return nil, nil, g.opError("RandomCategorical", errors.Errorf("categorical logits must have a single axis, got %s", logitsOp.Shape), state, logits)
+	}
+	dtype := logitsOp.Shape.DType
+	scoreDims := append(slices.Clone(dims), logitsOp.Shape.Dimensions[0])
+	s := g.newSampler(state)
+	u := s.uniform(dtype, scoreDims)
+	// Gumbel noise: -log(-log(u)). u == 0 gives -Inf which is never selected.
+	gumbel := s.unary(xlabuilder.Neg, s.unary(xlabuilder.Log, s.unary(xlabuilder.Neg, s.unary(xlabuilder.Log, u))))
+	broadcastLogits := s.apply(func() (*xlabuilder.Op, error) {
+		return xlabuilder.BroadcastInDim(logitsOp, xlabuilder.MakeShape(dtype, scoreDims...), []int{len(dims)})
+	})
+	scores := s.binary(xlabuilder.Add, broadcastLogits, gumbel)
+	indices := s.apply(func() (*xlabuilder.Op, error) {
+		return xlabuilder.ArgMinMax(scores, len(dims), dtypes.Int64, false)
+	})
+	if newState, values, err = s.done(g, state, indices, logits); err != nil {
+		return nil, nil, g.opError("RandomCategorical", err, state, logits)
+	}
+	return newState, values, nil
+}
+
+// RandomPermutation returns the new Philox state and a random permutation of [0, n) as int64.
+//
+// The XLA builder does not provide a sort operation: the permutation is computed by ranking
+// random keys with n^2 comparisons. It is meant for small values of n.
+func (g *Graph) RandomPermutation(state ops.Node, n int) (newState, values ops.Node, err error) {
+	s := g.newSampler(state)
+	square := []int{n, n}
+	keys := s.bits(dtypes.Uint64, []int{n})
+	along := func(x *xlabuilder.Op, axis int) *xlabuilder.Op {
+		return s.apply(func() (*xlabuilder.Op, error) {
+			return xlabuilder.BroadcastInDim(x, xlabuilder.MakeShape(x.Shape.DType, square...), []int{axis})
+		})
+	}
+	iota := func(axis int) *xlabuilder.Op {
+		return s.apply(func() (*xlabuilder.Op, error) {
+			return xlabuilder.Iota(s.b, xlabuilder.MakeShape(dtypes.Int64, square...), axis)
+		})
+	}
+	keyI, keyJ := along(keys, 0), along(keys, 1)
+	i, j := iota(0), iota(1)
+	// rank[i] is the number of keys before key i, ties being broken by index.
+	before := s.binary(xlabuilder.LogicalOr,
+		s.binary(xlabuilder.LessThan, keyJ, keyI),
+		s.binary(xlabuilder.LogicalAnd, s.binary(xlabuilder.Equal, keyJ, keyI), s.binary(xlabuilder.LessThan, j, i)),
+	)
+	rank := s.apply(func() (*xlabuilder.Op, error) {
+		counts, err := xlabuilder.ConvertDType(before, dtypes.Int64)
+		if err != nil {
+			return nil, err
+		}
+		return xlabuilder.ReduceSum(counts, 1)
+	})
+	// perm[r] is the index i such that rank[i] == r.
+	atRank := s.binary(xlabuilder.Equal, along(rank, 0), j)
+	perm := s.apply(func() (*xlabuilder.Op, error) {
+		zero, err := xlabuilder.ScalarZero(s.b, dtypes.Int64)
+		if err != nil {
+			return nil, err
+		}
+		if zero, err = xlabuilder.Broadcast(zero, square...); err != nil {
+			return nil, err
+		}
+		indices, err := xlabuilder.Where(atRank, i, zero)
+		if err != nil {
+			return nil, err
+		}
+		return xlabuilder.ReduceSum(indices, 0)
+	})
+	if newState, values, err = s.done(g, state, perm); err != nil {
+		return nil, nil, g.opError("RandomPermutation", err, state)
+	}
+	return newState, values, nil
+}
+
+// uint64Constant returns an array of the given axis lengths filled with a uint64 value.
+func (s *sampler) uint64Constant(v uint64, dims []int) *xlabuilder.Op {
+	return s.apply(func() (*xlabuilder.Op, error) {
+		op, err := xlabuilder.Constant(s.b, xlabuilder.NewScalarLiteral(v))
+		if err != nil {
+			return nil, err
+		}
+		return xlabuilder.Broadcast(op, dims...)
+	})
+}
+
+// splitMix64 mixes the bits of uint64 values with the SplitMix64 generator.
+func (s *sampler) splitMix64(z *xlabuilder.Op) *xlabuilder.Op {
+	if s.err != nil {
+		return nil
+	}
+	dims := z.Shape.Dimensions
+	xorShift := func(z *xlabuilder.Op, shift uint64) *xlabuilder.Op {
+		return s.binary(xlabuilder.BitwiseXor, z, s.binary(xlabuilder.ShiftRightLogical, z, s.uint64Constant(shift, dims)))
+	}
+	z = s.binary(xlabuilder.Add, z, s.uint64Constant(0x9e3779b97f4a7c15, dims))
+	z = s.binary(xlabuilder.Mul, xorShift(z, 30), s.uint64Constant(0xbf58476d1ce4e5b9, dims))
+	z = s.binary(xlabuilder.Mul, xorShift(z, 27), s.uint64Constant(0x94d049bb133111eb, dims))
+	return xorShift(z, 31)
+}
+
+// deriveStates returns the states, uint64[n, 3], derived from the current state and uint64[n] data.
+//
+// With s the current state and k = splitMix64(data), the derived state is:
+//
+//	d[0] = splitMix64(s[0] ^ k)
+//	d[1] = splitMix64(s[1] ^ d[0])
+//	d[2] = splitMix64(s[2] ^ d[1])
+func (s *sampler) deriveStates(data *xlabuilder.Op) *xlabuilder.Op {
+	if s.err != nil {
+		return nil
+	}
+	if s.state.Shape.DType != dtypes.Uint64 || !slices.Equal(s.state.Shape.Dimensions, []int{3}) {
+		s.err = errors.Errorf("invalid random state shape %s: want uint64[3]", s.state.Shape)
+		return nil
+	}
+	dims := data.Shape.Dimensions
+	prev := s.splitMix64(data)
+	derived := make([]*xlabuilder.Op, 3)
+	for i := range derived {
+		component := s.apply(func() (*xlabuilder.Op, error) {
+			c, err := xlabuilder.Slice(s.state, []int{i}, []int{i + 1}, []int{1})
+			if err != nil {
+				return nil, err
+			}
+			if c, err = xlabuilder.Reshape(c); err != nil {
+				return nil, err
+			}
+			return xlabuilder.Broadcast(c, dims...)
+		})
+		prev = s.splitMix64(s.binary(xlabuilder.BitwiseXor, component, prev))
+		derived[i] = s.apply(func() (*xlabuilder.Op, error) { return xlabuilder.Reshape(prev, append(slices.Clone(dims), 1)...) })
+	}
+	return s.apply(func() (*xlabuilder.Op, error) { return xlabuilder.Concatenate(len(dims), derived...) })
+}
+
+// RandomSplit returns n Philox states, as uint64[n, 3], derived from a state.
+// The ith derived state only depends on the state and on i, so that splitting a state
+// in more states does not change the first states.
+func (g *Graph) RandomSplit(state ops.Node, n int) (ops.Node, error) {
+	s := g.newSampler(state)
+	indices := s.apply(func() (*xlabuilder.Op, error) {
+		return xlabuilder.Iota(s.b, xlabuilder.MakeShape(dtypes.Uint64, n), 0)
+	})
+	states := s.deriveStates(indices)
+	if s.err != nil {
+		return nil, g.opError("RandomSplit", s.err, state)
+	}
+	return g.newNode(states, state), nil
+}
+
+// RandomFoldIn returns a Philox state derived from a state and a uint64 scalar.
+func (g *Graph) RandomFoldIn(state, data ops.Node) (ops.Node, error) {
+	dataOp := g.xlaHandle(data)
+	if dataOp.Shape.DType != dtypes.Uint64 || !dataOp.Shape.IsScalar() {
+		return nil, g.opError("RandomFoldIn", errors.Errorf("cannot fold %s data in a random state: want a uint64 scalar", dataOp.Shape), state, data)
+	}
+	s := g.newSampler(state)
+	derived := s.deriveStates(s.apply(func() (*xlabuilder.Op, error) { return xlabuilder.Reshape(dataOp, 1) }))
+	derived = s.apply(func() (*xlabuilder.Op, error) { return xlabuilder.Reshape(derived, 3) })
+	if s.err != nil {
+		return nil, g.opError("RandomFoldIn", s.err, state, data)
+	}
+	return g.newNode(derived, state, data), nil
+}
@@ -15,7 +15,13 @@
 package graph
 
 import (
-	"github.com/pkg/errors"
+	"context"
+	"log/slog"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
 	"github.com/gomlx/gopjrt/pjrt"
 	"github.com/gx-org/backend/ops"
 	"github.com/gx-org/backend/platform"
@@ -27,6 +33,11 @@ import (
 type nodeRunner struct {
 	device *pjrtplatform.Device
 	graph  *Graph
+
+	mu sync.RWMutex
+	// hoisted are the buffers of the hoisted constants passed after the arguments.
+	hoisted []*pjrt.Buffer
+	closed  bool
 }
 
 func bufferShape(buffer *pjrt.Buffer) (*shape.Shape, error) {
@@ -46,10 +57,10 @@ func bufferShape(buffer *pjrt.Buffer) (*shape.Shape, error) {
 
 func checkShape(got, want *shape.Shape) error {
 	if got.DType != want.DType {
-		return errors.Errorf("PJRT backend returned a buffer with a %s data type but GX expects a %s data type", got.DType, want.DType)
+		return pjrtplatform.NewError(pjrtplatform.ErrShapeMismatch, nil, "PJRT backend returned a buffer with a %s data type but GX expects a %s data type", got.DType, want.DType)
 	}
 	if got.Size() != want.Size() {
-		return errors.Errorf("PJRT backend returned a buffer with axis lengths %v but GX expects %v", got.AxisLengths, want.AxisLengths)
+		return pjrtplatform.NewError(pjrtplatform.ErrShapeMismatch, nil, "PJRT backend returned a buffer with axis lengths %v but GX expects %v", got.AxisLengths, want.AxisLengths)
 	}
 	return nil
 }
@@ -74,22 +85,67 @@ func toHandles(dev *pjrtplatform.Device, buffers []*pjrt.Buffer, shapes []*shape
 }
 
 // newNodeRunner returns a new node runner given a function and a graph.
-func (graph *Graph) newNodeRunner(dev *pjrtplatform.Device) ops.Runner {
-	return &nodeRunner{device: dev, graph: graph}
+// The buffers of the hoisted constants are owned by the runner.
+func (graph *Graph) newNodeRunner(dev *pjrtplatform.Device, hoisted []*pjrt.Buffer) ops.Runner {
+	r := &nodeRunner{device: dev, graph: graph, hoisted: hoisted}
+	if len(hoisted) > 0 {
+		runtime.AddCleanup(r, func(buffers []*pjrt.Buffer) {
+			destroyBuffers(buffers)
+		}, hoisted)
+	}
+	return r
+}
+
+// Close frees the device buffers of the hoisted constants.
+// The runner cannot be run once it has been closed.
+func (r *nodeRunner) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return destroyBuffers(r.hoisted)
 }
+
 func (r *nodeRunner) Run(args []platform.Handle) (out, traced []platform.DeviceHandle, err error) {
-	deviceBuffers := make([]*pjrt.Buffer, len(args))
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return nil, nil, pjrtplatform.NewError(pjrtplatform.ErrExecution, nil, "cannot run function %s: runner has been closed", r.graph.builder.Name())
+	}
+	deviceBuffers := make([]*pjrt.Buffer, len(args), len(args)+len(r.hoisted))
+	argumentBytes := 0
 	for i, arg := range args {
-		deviceBuffers[i] = arg.(*pjrtplatform.Handle).OnDeviceBuffer()
+		handle, ok := arg.(*pjrtplatform.Handle)
+		if !ok {
+			return nil, nil, pjrtplatform.NewError(pjrtplatform.ErrUnsupportedTransfer, nil, "argument %d:%T is not a PJRT handle", i, arg)
+		}
+		argumentBytes += handle.Shape().ByteSize()
+		deviceBuffers[i] = handle.OnDeviceBuffer()
 		// Check that the buffer is valid...
 		if _, err := deviceBuffers[i].DType(); err != nil {
-			return nil, nil, errors.Errorf("argument %d:%T is an invalid pjrt buffer", i, arg)
+			return nil, nil, pjrtplatform.NewError(pjrtplatform.ErrInvalidHandle, err, "argument %d:%T is an invalid pjrt buffer", i, arg)
 		}
 	}
+	deviceBuffers = append(deviceBuffers, r.hoisted...)
+	start := time.Now()
 	results, err := r.graph.Executable().Execute(deviceBuffers...).Done()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, pjrtplatform.NewError(pjrtplatform.ErrExecution, err, "cannot run function %s", r.graph.builder.Name())
+	}
+	latency := time.Since(start)
+	outputBytes := 0
+	for _, sh := range slices.Concat(r.graph.OutShapes(), r.graph.TracedShapes()) {
+		outputBytes += sh.ByteSize()
 	}
+	r.graph.plat.Metrics().Ran(r.graph.builder.Name(), latency, argumentBytes, outputBytes)
+	r.graph.plat.Logger().LogAttrs(context.Background(), slog.LevelDebug, "graph run",
+		slog.String("function", r.graph.builder.Name()),
+		slog.Int("device", r.device.Ordinal()),
+		slog.Int("arguments", len(args)),
+		slog.Duration("latency", latency),
+	)
 	outShapes := r.graph.OutShapes()
 	numOut := len(outShapes)
 	out, err = toHandles(r.device, results[:numOut], outShapes)
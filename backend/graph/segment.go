@@ -0,0 +1,210 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/gomlx/gopjrt/xlabuilder"
+	"github.com/gx-org/backend/ops"
+)
+
+// SegmentReduction is the reduction applied to the values of a segment.
+type SegmentReduction int
+
+const (
+	// SegmentSum sums the values of a segment. The sum of an empty segment is 0.
+	SegmentSum SegmentReduction = iota
+	// SegmentMax computes the maximum of a segment. The maximum of an empty segment
+	// is the lowest value of the data type (-inf for floating-point values).
+	SegmentMax
+	// SegmentMin computes the minimum of a segment. The minimum of an empty segment
+	// is the highest value of the data type (+inf for floating-point values).
+	SegmentMin
+	// SegmentMean computes the mean of a segment. The mean of an empty segment is 0.
+	SegmentMean
+)
+
+type scatterFunc func(operand, scatterIndices, updates *xlabuilder.Op,
+	indexVectorAxis int, updateWindowAxes, insertedWindowAxes, scatterAxesToOperandAxes []int,
+	indicesAreSorted, uniqueIndices bool) (*xlabuilder.Op, error)
+
+// scalarConstant returns a scalar constant given a value of a Go type supported by XLA.
+func scalarConstant(b *xlabuilder.XlaBuilder, value any) (*xlabuilder.Op, error) {
+	lit, err := xlabuilder.NewScalarLiteralFromAny(value)
+	if err != nil {
+		return nil, err
+	}
+	return xlabuilder.Constant(b, lit)
+}
+
+// scatterSegments reduces the values of data along its first axis into numSegments segments
+// starting from a scalar initial value.
+// The ith value along the first axis is reduced into the segment ids[i].
+// Values with an id outside [0, numSegments) are ignored.
+func scatterSegments(scatter scatterFunc, init, data, ids *xlabuilder.Op, numSegments int, sorted bool) (*xlabuilder.Op, error) {
+	dims := append([]int{numSegments}, data.Shape.Dimensions[1:]...)
+	operand, err := xlabuilder.Broadcast(init, dims...)
+	if err != nil {
+		return nil, err
+	}
+	indices, err := xlabuilder.Reshape(ids, ids.Shape.Dimensions[0], 1)
+	if err != nil {
+		return nil, err
+	}
+	window := make([]int, data.Shape.Rank()-1)
+	for i := range window {
+		window[i] = i + 1
+	}
+	return scatter(operand, indices, data, 1, window, []int{0}, []int{0}, sorted, false)
+}
+
+func checkSegments(data, ids *xlabuilder.Op, numSegments int) error {
+	if data.Shape.Rank() == 0 {
+		return errors.Errorf("cannot reduce the segments of a scalar")
+	}
+	if ids.Shape.Rank() != 1 || !ids.Shape.DType.IsInt() {
+		return errors.Errorf("invalid segment ids %s: want an array of integers with a single axis", ids.Shape)
+	}
+	if got, want := ids.Shape.Dimensions[0], data.Shape.Dimensions[0]; got != want {
+		return errors.Errorf("cannot reduce the segments of %s with %d segment ids: want %d ids", data.Shape, got, want)
+	}
+	if numSegments < 0 {
+		return errors.Errorf("invalid number of segments %d", numSegments)
+	}
+	return nil
+}
+
+// segmentMean divides the sum of each segment by its number of values.
+func segmentMean(data, ids *xlabuilder.Op, numSegments int, sorted bool) (*xlabuilder.Op, error) {
+	b, dt := data.Builder(), data.Shape.DType
+	zero, err := xlabuilder.ScalarZero(b, dt)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := scatterSegments(xlabuilder.ScatterSum, zero, data, ids, numSegments, sorted)
+	if err != nil {
+		return nil, err
+	}
+	one, err := xlabuilder.ScalarOne(b, dt)
+	if err != nil {
+		return nil, err
+	}
+	ones, err := xlabuilder.Broadcast(one, ids.Shape.Dimensions...)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := scatterSegments(xlabuilder.ScatterSum, zero, ones, ids, numSegments, sorted)
+	if err != nil {
+		return nil, err
+	}
+	// Empty segments are divided by 1 to give a mean of 0.
+	if one, err = xlabuilder.Broadcast(one, numSegments); err != nil {
+		return nil, err
+	}
+	if counts, err = xlabuilder.Max(counts, one); err != nil {
+		return nil, err
+	}
+	if counts, err = xlabuilder.BroadcastInDim(counts, sum.Shape, []int{0}); err != nil {
+		return nil, err
+	}
+	return xlabuilder.Div(sum, counts)
+}
+
+// SegmentReduce reduces the values of data along its first axis into numSegments segments.
+// The result has the shape of data with a first axis of length numSegments.
+// The ith value of data along its first axis belongs to the segment segmentIDs[i]:
+// the segment ids do not need to be sorted and values with an id outside [0, numSegments)
+// are ignored. sorted is a hint that the segment ids are sorted.
+func (g *Graph) SegmentReduce(data, segmentIDs ops.Node, numSegments int, reduction SegmentReduction, sorted bool) (ops.Node, error) {
+	dataOp, idsOp := g.xlaHandle(data), g.xlaHandle(segmentIDs)
+	if err := checkSegments(dataOp, idsOp, numSegments); err != nil {
+		return nil, g.opError("SegmentReduce", err, data, segmentIDs)
+	}
+	b, dt := dataOp.Builder(), dataOp.Shape.DType
+	var op, init *xlabuilder.Op
+	var err error
+	switch reduction {
+	case SegmentSum:
+		if init, err = xlabuilder.ScalarZero(b, dt); err == nil {
+			op, err = scatterSegments(xlabuilder.ScatterSum, init, dataOp, idsOp, numSegments, sorted)
+		}
+	case SegmentMax:
+		if init, err = scalarConstant(b, dt.LowestValue()); err == nil {
+			op, err = scatterSegments(xlabuilder.ScatterMax, init, dataOp, idsOp, numSegments, sorted)
+		}
+	case SegmentMin:
+		if init, err = scalarConstant(b, dt.HighestValue()); err == nil {
+			op, err = scatterSegments(xlabuilder.ScatterMin, init, dataOp, idsOp, numSegments, sorted)
+		}
+	case SegmentMean:
+		op, err = segmentMean(dataOp, idsOp, numSegments, sorted)
+	default:
+		return nil, g.opError("SegmentReduce", errors.Errorf("unknown segment reduction %d", reduction), data, segmentIDs)
+	}
+	if err != nil {
+		return nil, g.opError("SegmentReduce", err, data, segmentIDs)
+	}
+	return g.newNode(op, data, segmentIDs), nil
+}
+
+// OneHot returns the one-hot encoding of integer indices.
+// The result has the axes of indices followed by an axis of length depth:
+// the jth element of the encoding of an index i is on if i == j, off otherwise.
+// on and off are scalars of the same data type.
+// Indices outside [0, depth) are encoded with off values only.
+func (g *Graph) OneHot(indices ops.Node, depth int, on, off ops.Node) (ops.Node, error) {
+	idxOp, onOp, offOp := g.xlaHandle(indices), g.xlaHandle(on), g.xlaHandle(off)
+	if !idxOp.Shape.DType.IsInt() {
+		return nil, g.opError("OneHot", errors.Errorf("cannot compute the one-hot encoding of %s: want integer indices", idxOp.Shape), indices, on, off)
+	}
+	if depth <= 0 {
+		return nil, g.opError("OneHot", errors.Errorf("invalid one-hot depth %d: must be positive", depth), indices, on, off)
+	}
+	if !onOp.Shape.IsScalar() || !offOp.Shape.IsScalar() || onOp.Shape.DType != offOp.Shape.DType {
+		return nil, g.opError("OneHot", errors.Errorf("invalid one-hot values %s and %s: want two scalars of the same data type", onOp.Shape, offOp.Shape), indices, on, off)
+	}
+	rank := idxOp.Shape.Rank()
+	dims := append(slices.Clone(idxOp.Shape.Dimensions), depth)
+	encodingShape := xlabuilder.MakeShape(idxOp.Shape.DType, dims...)
+	positions, err := xlabuilder.Iota(idxOp.Builder(), encodingShape, rank)
+	if err != nil {
+		return nil, g.opError("OneHot", err, indices, on, off)
+	}
+	broadcastAxes := make([]int, rank)
+	for i := range broadcastAxes {
+		broadcastAxes[i] = i
+	}
+	idx, err := xlabuilder.BroadcastInDim(idxOp, encodingShape, broadcastAxes)
+	if err != nil {
+		return nil, g.opError("OneHot", err, indices, on, off)
+	}
+	hot, err := xlabuilder.Equal(idx, positions)
+	if err != nil {
+		return nil, g.opError("OneHot", err, indices, on, off)
+	}
+	if onOp, err = xlabuilder.Broadcast(onOp, dims...); err != nil {
+		return nil, g.opError("OneHot", err, indices, on, off)
+	}
+	if offOp, err = xlabuilder.Broadcast(offOp, dims...); err != nil {
+		return nil, g.opError("OneHot", err, indices, on, off)
+	}
+	op, err := xlabuilder.Where(hot, onOp, offOp)
+	if err != nil {
+		return nil, g.opError("OneHot", err, indices, on, off)
+	}
+	return g.newNode(op, indices, on, off), nil
+}
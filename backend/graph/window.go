@@ -0,0 +1,158 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package graph
+
+import (
+	"github.com/pkg/errors"
+	"github.com/gomlx/gopjrt/xlabuilder"
+	"github.com/gx-org/backend/ops"
+)
+
+// WindowReducer is a reduction applied to all the elements in a window.
+type WindowReducer int
+
+const (
+	// WindowSum sums the elements in a window.
+	WindowSum WindowReducer = iota
+	// WindowMax takes the maximum of the elements in a window.
+	WindowMax
+	// WindowMin takes the minimum of the elements in a window.
+	WindowMin
+)
+
+func (r WindowReducer) String() string {
+	switch r {
+	case WindowSum:
+		return "sum"
+	case WindowMax:
+		return "max"
+	case WindowMin:
+		return "min"
+	}
+	return "invalid"
+}
+
+// Window configures a window sliding over all the axes of an array.
+// All slices have one element per axis of the array.
+type Window struct {
+	// Dimensions is the size of the window.
+	Dimensions []int
+	// Strides is the step between two windows.
+	Strides []int
+	// BaseDilations is the dilation applied to the input array.
+	BaseDilations []int
+	// WindowDilations is the dilation applied to the window.
+	WindowDilations []int
+	// Padding is the padding (low, high) added to the input array.
+	Padding [][2]int
+}
+
+// Validate checks that the window configuration is valid for an array of the given rank.
+func (w *Window) Validate(rank int) error {
+	for _, cfg := range []struct {
+		name string
+		vals []int
+	}{
+		{"dimensions", w.Dimensions},
+		{"strides", w.Strides},
+		{"base dilations", w.BaseDilations},
+		{"window dilations", w.WindowDilations},
+	} {
+		if len(cfg.vals) != rank {
+			return errors.Errorf("window has %d %s but the array has %d axes", len(cfg.vals), cfg.name, rank)
+		}
+		for axis, val := range cfg.vals {
+			if val < 1 {
+				return errors.Errorf("invalid window %s %d for axis %d: must be at least 1", cfg.name, val, axis)
+			}
+		}
+	}
+	if len(w.Padding) != rank {
+		return errors.Errorf("window has %d paddings but the array has %d axes", len(w.Padding), rank)
+	}
+	return nil
+}
+
+// OutputAxisLengths returns the axis lengths of the array computed by sliding the window over
+// an array of the given axis lengths.
+func (w *Window) OutputAxisLengths(axisLengths []int) ([]int, error) {
+	if err := w.Validate(len(axisLengths)); err != nil {
+		return nil, err
+	}
+	out := make([]int, len(axisLengths))
+	for axis, length := range axisLengths {
+		dilatedLength := 0
+		if length > 0 {
+			dilatedLength = (length-1)*w.BaseDilations[axis] + 1
+		}
+		paddedLength := dilatedLength + w.Padding[axis][0] + w.Padding[axis][1]
+		dilatedWindow := (w.Dimensions[axis]-1)*w.WindowDilations[axis] + 1
+		if paddedLength < dilatedWindow {
+			continue
+		}
+		out[axis] = (paddedLength-dilatedWindow)/w.Strides[axis] + 1
+	}
+	return out, nil
+}
+
+// ReduceWindow returns a node reducing all the elements in each window sliding over x.
+func (g *Graph) ReduceWindow(x ops.Node, reducer WindowReducer, window *Window) (ops.Node, error) {
+	cfg := xlabuilder.ReduceWindow(g.xlaHandle(x), window.Dimensions)
+	switch reducer {
+	case WindowSum:
+		cfg = cfg.Sum()
+	case WindowMax:
+		cfg = cfg.Max()
+	case WindowMin:
+		cfg = cfg.Min()
+	default:
+		return nil, g.opError("ReduceWindow", errors.Errorf("window reducer %s not supported", reducer), x)
+	}
+	xlaOp, err := cfg.
+		WithStrides(window.Strides).
+		WithBaseDilations(window.BaseDilations).
+		WithWindowDilations(window.WindowDilations).
+		WithPadding(window.Padding).
+		Done()
+	if err != nil {
+		return nil, g.opError("ReduceWindow", err, x)
+	}
+	return g.newNode(xlaOp, x).Info("%s", reducer), nil
+}
+
+// SelectAndScatter returns a node scattering the values of source to the elements of x
+// selected by the reducer in each window. Scattered values falling on the same element are summed.
+// This is the gradient of ReduceWindow for max and min reducers.
+func (g *Graph) SelectAndScatter(x, source ops.Node, reducer WindowReducer, window *Window) (ops.Node, error) {
+	for axis := range window.Dimensions {
+		if window.BaseDilations[axis] != 1 || window.WindowDilations[axis] != 1 {
+			return nil, g.opError("SelectAndScatter", errors.Errorf("select and scatter does not support dilations"), x, source)
+		}
+	}
+	var xlaOp *xlabuilder.Op
+	var err error
+	switch reducer {
+	case WindowMax:
+		xlaOp, err = xlabuilder.SelectAndScatterMax(g.xlaHandle(x), g.xlaHandle(source), window.Dimensions, window.Strides, window.Padding)
+	case WindowMin:
+		xlaOp, err = xlabuilder.SelectAndScatterMin(g.xlaHandle(x), g.xlaHandle(source), window.Dimensions, window.Strides, window.Padding)
+	default:
+		return nil, g.opError("SelectAndScatter", errors.Errorf("window reducer %s not supported by select and scatter", reducer), x, source)
+	}
+	if err != nil {
+		return nil, g.opError("SelectAndScatter", err, x, source)
+	}
+	return g.newNode(xlaOp, x, source).Info("%s", reducer), nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"expvar"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultLatencyBuckets are the upper bounds of the buckets of the run latency histograms
+// used when NewExpvar is given no bounds.
+var defaultLatencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+// Expvar publishes metrics as expvar variables, under a single map:
+//
+//	functions: map of function names to:
+//	  compilations, compile_seconds, runs, run_seconds, run_latency (histogram),
+//	  argument_bytes, output_bytes (device bytes passed to and returned by the runs)
+//	transfers: device<ordinal>.host_to_device_bytes, device<ordinal>.device_to_host_bytes
+type Expvar struct {
+	root      *expvar.Map
+	functions *expvar.Map
+	transfers *expvar.Map
+	latency   []time.Duration
+
+	mu    sync.Mutex
+	funcs map[string]*funcVars
+}
+
+type funcVars struct {
+	compilations   expvar.Int
+	compileSeconds expvar.Float
+	runs           expvar.Int
+	runSeconds     expvar.Float
+	latency        *expvar.Map
+	argumentBytes  expvar.Int
+	outputBytes    expvar.Int
+}
+
+var _ Metrics = (*Expvar)(nil)
+
+// NewExpvar returns metrics published as an expvar map with the given name.
+// latencyBuckets are the upper bounds of the buckets of the run latency histograms:
+// runs slower than the largest bound are counted in an "inf" bucket.
+// Default bounds, from 100µs to 10s, are used if latencyBuckets is empty.
+// Like expvar.Publish, it panics if the name is already in use.
+func NewExpvar(name string, latencyBuckets []time.Duration) *Expvar {
+	if len(latencyBuckets) == 0 {
+		latencyBuckets = defaultLatencyBuckets
+	}
+	latency := slices.Clone(latencyBuckets)
+	slices.Sort(latency)
+	m := &Expvar{
+		root:      expvar.NewMap(name),
+		functions: new(expvar.Map),
+		transfers: new(expvar.Map),
+		latency:   slices.Compact(latency),
+		funcs:     make(map[string]*funcVars),
+	}
+	m.root.Set("functions", m.functions)
+	m.root.Set("transfers", m.transfers)
+	return m
+}
+
+func (m *Expvar) function(name string) *funcVars {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if vars, ok := m.funcs[name]; ok {
+		return vars
+	}
+	vars := &funcVars{latency: new(expvar.Map)}
+	for _, bound := range m.latency {
+		vars.latency.Add(bound.String(), 0)
+	}
+	vars.latency.Add("inf", 0)
+	fn := new(expvar.Map)
+	fn.Set("compilations", &vars.compilations)
+	fn.Set("compile_seconds", &vars.compileSeconds)
+	fn.Set("runs", &vars.runs)
+	fn.Set("run_seconds", &vars.runSeconds)
+	fn.Set("run_latency", vars.latency)
+	fn.Set("argument_bytes", &vars.argumentBytes)
+	fn.Set("output_bytes", &vars.outputBytes)
+	m.functions.Set(name, fn)
+	m.funcs[name] = vars
+	return vars
+}
+
+// Compiled records the compilation of a function.
+func (m *Expvar) Compiled(funcName string, duration time.Duration) {
+	vars := m.function(funcName)
+	vars.compilations.Add(1)
+	vars.compileSeconds.Add(duration.Seconds())
+}
+
+// Ran records a run of a compiled function.
+func (m *Expvar) Ran(funcName string, latency time.Duration, argumentBytes, outputBytes int) {
+	vars := m.function(funcName)
+	vars.runs.Add(1)
+	vars.runSeconds.Add(latency.Seconds())
+	vars.latency.Add(m.latencyBucket(latency), 1)
+	vars.argumentBytes.Add(int64(argumentBytes))
+	vars.outputBytes.Add(int64(outputBytes))
+}
+
+func (m *Expvar) latencyBucket(latency time.Duration) string {
+	for _, bound := range m.latency {
+		if latency <= bound {
+			return bound.String()
+		}
+	}
+	return "inf"
+}
+
+// Transferred records a transfer between the host and a device.
+func (m *Expvar) Transferred(dir Direction, ordinal int, bytes int) {
+	m.transfers.Add("device"+strconv.Itoa(ordinal)+"."+dir.String()+"_bytes", int64(bytes))
+}
+
+// Map returns the expvar map in which the metrics are published.
+func (m *Expvar) Map() *expvar.Map {
+	return m.root
+}
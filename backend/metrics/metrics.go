@@ -0,0 +1,64 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics records execution metrics of the PJRT backend.
+//
+// Compilations and runs, including the device bytes passed to and returned by
+// each run, are recorded per compiled GX function (using the fully qualified
+// name of the function). Transfers between the host and devices are recorded
+// per device.
+package metrics
+
+import "time"
+
+// Direction of a transfer between the host and a device.
+type Direction int
+
+const (
+	// HostToDevice is a transfer from the host to a device.
+	HostToDevice Direction = iota
+	// DeviceToHost is a transfer from a device to the host.
+	DeviceToHost
+)
+
+// String representation of the direction.
+func (d Direction) String() string {
+	if d == HostToDevice {
+		return "host_to_device"
+	}
+	return "device_to_host"
+}
+
+// Metrics receives the events of the PJRT backend.
+// Implementations need to be safe for concurrent use.
+type Metrics interface {
+	// Compiled records the compilation of a function.
+	Compiled(funcName string, duration time.Duration)
+	// Ran records a run of a compiled function given the size, in bytes, of its
+	// arguments and of its outputs on the device.
+	Ran(funcName string, latency time.Duration, argumentBytes, outputBytes int)
+	// Transferred records a transfer between the host and a device.
+	Transferred(dir Direction, ordinal int, bytes int)
+}
+
+type noop struct{}
+
+func (noop) Compiled(string, time.Duration)      {}
+func (noop) Ran(string, time.Duration, int, int) {}
+func (noop) Transferred(Direction, int, int)     {}
+
+// Noop returns metrics ignoring all the events.
+func Noop() Metrics {
+	return noop{}
+}
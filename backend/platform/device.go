@@ -15,10 +15,10 @@
 package platform
 
 import (
-	"github.com/pkg/errors"
 	"github.com/gomlx/gopjrt/dtypes"
 	"github.com/gx-org/backend/platform"
 	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/xlapjrt/backend/metrics"
 	pjrtgx "github.com/gx-org/xlapjrt"
 )
 
@@ -42,12 +42,17 @@ func (dev *Device) Ordinal() int {
 func (dev *Device) send(data []byte, sh *shape.Shape) (*Handle, error) {
 	dt := pjrtgx.ToDType(sh.DType)
 	if dt == dtypes.InvalidDType {
-		return nil, errors.Errorf("GX %s data type not supported by pjrt", sh.DType.String())
+		return nil, NewError(ErrUnsupportedDType, nil, "cannot send GX %s data type to device %d", sh.DType.String(), dev.ord)
+	}
+	if len(data) != sh.ByteSize() {
+		return nil, NewError(ErrShapeMismatch, nil, "cannot send %d bytes to device %d as %s (%d bytes)", len(data), dev.ord, sh.String(), sh.ByteSize())
 	}
 	buffer, err := dev.plat.clt.BufferFromHost().FromRawData(data, dt, sh.AxisLengths).Done()
 	if err != nil {
-		return nil, err
+		return nil, NewError(ErrTransfer, err, "cannot send %s to device %d", sh.String(), dev.ord)
 	}
+	dev.plat.metrics.Transferred(metrics.HostToDevice, dev.ord, len(data))
+	dev.plat.logTransfer(metrics.HostToDevice, dev.ord, sh, len(data))
 	return NewHandle(dev, buffer, sh)
 }
 
@@ -0,0 +1,89 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package platform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Failure modes of the PJRT backend. Use errors.Is to test the failure mode of an error.
+var (
+	// ErrUnsupportedDType is returned when a GX data type has no PJRT equivalent.
+	ErrUnsupportedDType = errors.New("data type not supported by PJRT")
+	// ErrShapeMismatch is returned when the shape of some data does not match the expected shape.
+	ErrShapeMismatch = errors.New("shape mismatch")
+	// ErrUnsupported is returned when an operation or one of its options is not supported by PJRT.
+	ErrUnsupported = errors.New("not supported by PJRT")
+	// ErrUnsupportedTransfer is returned when a handle cannot be transferred to or from a device.
+	ErrUnsupportedTransfer = errors.New("unsupported transfer")
+	// ErrInvalidHandle is returned when a handle refers to a device buffer which is not valid anymore.
+	ErrInvalidHandle = errors.New("invalid device handle")
+	// ErrTransfer is returned when a transfer between the host and a device fails.
+	ErrTransfer = errors.New("transfer failure")
+	// ErrOutOfMemory is returned when a device runs out of memory.
+	ErrOutOfMemory = errors.New("device out of memory")
+	// ErrCompile is returned when XLA fails to compile a graph.
+	ErrCompile = errors.New("compilation failure")
+	// ErrExecution is returned when the execution of a compiled graph fails.
+	ErrExecution = errors.New("execution failure")
+)
+
+// Error is an error returned by the PJRT backend.
+type Error struct {
+	// Kind is the failure mode of the error, one of the Err variables of this package.
+	Kind error
+	// Msg describes the operation which failed.
+	Msg string
+	// Err is the underlying error returned by PJRT. Nil if the error has been detected by GX.
+	Err error
+}
+
+// NewError returns a new backend error given a failure mode and the underlying PJRT error, if any.
+// Errors reporting that the device ran out of memory are returned as ErrOutOfMemory errors
+// regardless of the failure mode.
+func NewError(kind error, err error, format string, a ...any) error {
+	if err != nil && isOutOfMemory(err) {
+		kind = ErrOutOfMemory
+	}
+	return &Error{Kind: kind, Msg: fmt.Sprintf(format, a...), Err: err}
+}
+
+// isOutOfMemory returns true if a PJRT error reports that a device ran out of memory.
+func isOutOfMemory(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "resource_exhausted") || strings.Contains(msg, "out of memory")
+}
+
+// Error returns the error message.
+func (err *Error) Error() string {
+	msg := err.Msg + ": " + err.Kind.Error()
+	if err.Err != nil {
+		msg += ": " + err.Err.Error()
+	}
+	return msg
+}
+
+// Is returns true if target is the failure mode of the error.
+func (err *Error) Is(target error) bool {
+	return target == err.Kind
+}
+
+// Unwrap returns the underlying PJRT error.
+func (err *Error) Unwrap() error {
+	return err.Err
+}
@@ -17,11 +17,11 @@ package platform
 import (
 	"fmt"
 
-	"github.com/pkg/errors"
 	"github.com/gomlx/gopjrt/pjrt"
 	"github.com/gomlx/gopjrt/xlabuilder"
 	"github.com/gx-org/backend/platform"
 	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/xlapjrt/backend/metrics"
 )
 
 type (
@@ -65,7 +65,7 @@ func (h *Handle) ToDevice(dev platform.Device) (platform.DeviceHandle, error) {
 	if ok {
 		return ToDevice(pjrtDev, h)
 	}
-	return nil, errors.Errorf("not implemented")
+	return nil, NewError(ErrUnsupportedTransfer, nil, "cannot transfer %s to a %T device", h.shape.String(), dev)
 }
 
 func (h *Handle) toDevice(dev *Device) (*Handle, error) {
@@ -74,8 +74,10 @@ func (h *Handle) toDevice(dev *Device) (*Handle, error) {
 	}
 	data := make([]byte, h.shape.ByteSize())
 	if err := h.buffer.ToHost(data); err != nil {
-		return nil, err
+		return nil, NewError(ErrTransfer, err, "cannot fetch %s from device %d", h.shape.String(), h.device.ord)
 	}
+	h.device.plat.metrics.Transferred(metrics.DeviceToHost, h.device.ord, len(data))
+	h.device.plat.logTransfer(metrics.DeviceToHost, h.device.ord, h.shape, len(data))
 	return dev.send(data, h.Shape())
 }
 
@@ -83,7 +85,15 @@ func (h *Handle) toDevice(dev *Device) (*Handle, error) {
 func (h *Handle) ToHost(buf platform.HostBuffer) error {
 	data := buf.Acquire()
 	defer buf.Release()
-	return h.buffer.ToHost(data)
+	if len(data) != h.shape.ByteSize() {
+		return NewError(ErrShapeMismatch, nil, "cannot fetch %s (%d bytes) from device %d into a buffer of %d bytes", h.shape.String(), h.shape.ByteSize(), h.device.ord, len(data))
+	}
+	if err := h.buffer.ToHost(data); err != nil {
+		return NewError(ErrTransfer, err, "cannot fetch %s from device %d", h.shape.String(), h.device.ord)
+	}
+	h.device.plat.metrics.Transferred(metrics.DeviceToHost, h.device.ord, len(data))
+	h.device.plat.logTransfer(metrics.DeviceToHost, h.device.ord, h.shape, len(data))
+	return nil
 }
 
 // Device on which the array is located.
@@ -104,5 +114,5 @@ func ToDevice(dev *Device, handle platform.Handle) (*Handle, error) {
 	case platform.HostBuffer:
 		return dev.sendFromHost(handleT)
 	}
-	return nil, errors.Errorf("not implemented")
+	return nil, NewError(ErrUnsupportedTransfer, nil, "cannot transfer a %T handle to device %d", handle, dev.ord)
 }
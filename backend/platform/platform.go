@@ -16,19 +16,30 @@
 package platform
 
 import (
+	"context"
+	"log/slog"
+
 	"github.com/gomlx/gopjrt/pjrt"
 	"github.com/gx-org/backend/platform"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/xlapjrt/backend/metrics"
 )
 
 // Platform is the PJRT platform.
 type Platform struct {
-	clt    *pjrt.Client
-	device *Device
+	clt     *pjrt.Client
+	device  *Device
+	metrics metrics.Metrics
+	logger  *slog.Logger
 }
 
 // New PJRT platform.
 func New(clt *pjrt.Client) *Platform {
-	plat := &Platform{clt: clt}
+	plat := &Platform{
+		clt:     clt,
+		metrics: metrics.Noop(),
+		logger:  slog.New(slog.DiscardHandler),
+	}
 	plat.device = &Device{plat: plat}
 	return plat
 }
@@ -45,11 +56,41 @@ func (plat *Platform) Device(ordinal int) (platform.Device, error) {
 	return plat.device, nil
 }
 
+// SetMetrics sets the metrics recording the events of the platform.
+func (plat *Platform) SetMetrics(m metrics.Metrics) {
+	plat.metrics = m
+}
+
+// Metrics returns the metrics recording the events of the platform.
+func (plat *Platform) Metrics() metrics.Metrics {
+	return plat.metrics
+}
+
+// SetLogger sets the logger to which the platform emits debug events.
+func (plat *Platform) SetLogger(logger *slog.Logger) {
+	plat.logger = logger
+}
+
+// Logger returns the logger to which the platform emits debug events.
+func (plat *Platform) Logger() *slog.Logger {
+	return plat.logger
+}
+
 // Client returns the PJRT client.
 func (plat *Platform) Client() *pjrt.Client {
 	return plat.clt
 }
 
+// logTransfer emits a debug event for a transfer between the host and a device.
+func (plat *Platform) logTransfer(dir metrics.Direction, ordinal int, sh *shape.Shape, bytes int) {
+	plat.logger.LogAttrs(context.Background(), slog.LevelDebug, "buffer transfer",
+		slog.String("direction", dir.String()),
+		slog.Int("device", ordinal),
+		slog.String("shape", sh.String()),
+		slog.Int("bytes", bytes),
+	)
+}
+
 func toInt32(input []int) []int32 {
 	result := make([]int32, len(input))
 	for i, n := range input {
@@ -0,0 +1,192 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command gxanalyze compiles GX functions with the PJRT backend and prints
+// their cost and memory analysis as a table.
+//
+// Usage:
+//
+//	gxanalyze -package=<GX package path> -func=<function> [-arg=float32[2,3] ...]
+//
+// An argument is given for each parameter of the function as a data type
+// followed by the axis lengths. Arrays are filled with zeros.
+//
+// The columns marked "est." are estimated by GX from the XLA operations.
+// The cost of loops is reported for a single iteration since the number of
+// iterations is only known at run time. Memory columns are reported by PJRT.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/gx-org/backend/dtype"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/api"
+	"github.com/gx-org/gx/api/tracer"
+	"github.com/gx-org/gx/api/values"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/golang/backend/kernels"
+	"github.com/gx-org/xlapjrt/backend"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+	"github.com/gx-org/xlapjrt/plugin"
+)
+
+var (
+	pluginName = flag.String("plugin", "cpu", "name of the PJRT plugin")
+	pkgPath    = flag.String("package", "", "path of the GX package")
+	funcNames  = flag.String("func", "", "comma separated list of functions to analyse")
+	args       argFlags
+)
+
+type argFlags []string
+
+func (a *argFlags) String() string {
+	return strings.Join(*a, " ")
+}
+
+func (a *argFlags) Set(s string) error {
+	*a = append(*a, s)
+	return nil
+}
+
+func init() {
+	flag.Var(&args, "arg", "shape of an argument as dtype[axis lengths], e.g. float32[2,3] or int64[] (repeated for each parameter)")
+}
+
+var dataTypes = map[string]dtype.DataType{
+	"bool":     dtype.Bool,
+	"bfloat16": dtype.Bfloat16,
+	"float32":  dtype.Float32,
+	"float64":  dtype.Float64,
+	"int32":    dtype.Int32,
+	"int64":    dtype.Int64,
+	"uint32":   dtype.Uint32,
+	"uint64":   dtype.Uint64,
+}
+
+// parseShape parses a shape given as dtype[axis lengths].
+func parseShape(s string) (*shape.Shape, error) {
+	name, lengths, ok := strings.Cut(strings.TrimSuffix(s, "]"), "[")
+	if !ok {
+		return nil, errors.Errorf("invalid argument %q: want dtype[axis lengths]", s)
+	}
+	dt, ok := dataTypes[name]
+	if !ok {
+		return nil, errors.Errorf("invalid argument %q: unknown data type %s", s, name)
+	}
+	sh := &shape.Shape{DType: dt}
+	if lengths == "" {
+		return sh, nil
+	}
+	for _, length := range strings.Split(lengths, ",") {
+		l, err := strconv.Atoi(strings.TrimSpace(length))
+		if err != nil {
+			return nil, errors.Errorf("invalid argument %q: %v", s, err)
+		}
+		sh.AxisLengths = append(sh.AxisLengths, l)
+	}
+	return sh, nil
+}
+
+// zeros returns a host array of zeros given the type of a parameter and a shape.
+func zeros(typ ir.Type, sh *shape.Shape) (values.Value, error) {
+	buffer, err := kernels.Allocator().Allocate(sh)
+	if err != nil {
+		return nil, err
+	}
+	return values.NewHostArray(typ, buffer)
+}
+
+// compile traces and compiles a function for arguments of the given shapes.
+func compile(dev *api.Device, fn *ir.FuncDecl, shapes []*shape.Shape) error {
+	params := fn.FType.Params.Fields()
+	if len(params) != len(shapes) {
+		return errors.Errorf("function %s has %d parameters but %d arguments have been given", fn.Name(), len(params), len(shapes))
+	}
+	vals := make([]values.Value, len(params))
+	for i, param := range params {
+		var err error
+		if vals[i], err = zeros(param.Type(), shapes[i]); err != nil {
+			return err
+		}
+	}
+	_, err := tracer.Trace(dev, fn, nil, vals, nil)
+	return err
+}
+
+func printAnalyses(analyses []*pjrtgraph.Analysis) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Function\tFLOPs (est.)\tBytes accessed (est.)\tLoop FLOPs/iter (est.)\tLoop bytes/iter (est.)\tArguments\tOutputs\tTemp\tCode\tPeak\t#Outputs\t#Traced\t#Hoisted\t#Subcomputations\t#Reused\t")
+	for _, a := range analyses {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t\n",
+			a.Name, a.Flops, a.BytesAccessed, a.LoopFlopsPerIteration, a.LoopBytesAccessedPerIteration,
+			a.ArgumentBytes, a.OutputBytes, a.TempBytes, a.GeneratedCodeBytes, a.PeakBytes,
+			a.NumOutputs, a.NumTraced, a.NumHoisted,
+			a.NumSubcomputations, a.NumReusedSubcomputations)
+	}
+	return w.Flush()
+}
+
+func run() error {
+	if *pkgPath == "" || *funcNames == "" {
+		return errors.Errorf("both -package and -func need to be set")
+	}
+	shapes := make([]*shape.Shape, len(args))
+	for i, arg := range args {
+		var err error
+		if shapes[i], err = parseShape(arg); err != nil {
+			return err
+		}
+	}
+	rtm, err := plugin.New(*pluginName)
+	if err != nil {
+		return err
+	}
+	pkg, err := rtm.Builder().Build(*pkgPath)
+	if err != nil {
+		return err
+	}
+	dev, err := rtm.Device(0)
+	if err != nil {
+		return err
+	}
+	for _, name := range strings.Split(*funcNames, ",") {
+		fn, ok := pkg.IR().FindFunc(name).(*ir.FuncDecl)
+		if !ok {
+			return errors.Errorf("function %s not found in package %s", name, *pkgPath)
+		}
+		if err := compile(dev, fn, shapes); err != nil {
+			return err
+		}
+	}
+	analyzer, ok := rtm.Backend().(backend.Analyzer)
+	if !ok {
+		return errors.Errorf("backend %T does not support analyses", rtm.Backend())
+	}
+	return printAnalyses(analyzer.Analyses())
+}
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		log.Fatalf("%+v", err)
+	}
+}
@@ -29,8 +29,8 @@ import (
 	pjrtstdlib "github.com/gx-org/xlapjrt/stdlib"
 )
 
-// New returns a new PJRT runtime given a plugin name.
-func New(name string) (*api.Runtime, error) {
+// New returns a new PJRT runtime given a plugin name and backend options.
+func New(name string, opts ...backend.Option) (*api.Runtime, error) {
 	localImporter, err := localfs.New("")
 	if err != nil {
 		return nil, err
@@ -45,18 +45,19 @@ func New(name string) (*api.Runtime, error) {
 	}
 	bld := builder.New(importers.NewCacheLoader(
 		stdlib.Importer(pjrtstdlib.Stdlib),
+		pjrtstdlib.Importer(),
 		importer,
 	))
-	return NewWithBuilder(name, bld)
+	return NewWithBuilder(name, bld, opts...)
 }
 
-// NewWithBuilder creates PJRT GX runtime given a GX builder, a plugin name, and client options.
-func NewWithBuilder(name string, bld *builder.Builder) (*api.Runtime, error) {
+// NewWithBuilder creates PJRT GX runtime given a GX builder, a plugin name, and backend options.
+func NewWithBuilder(name string, bld *builder.Builder, opts ...backend.Option) (*api.Runtime, error) {
 	plugin, err := pjrt.GetPlugin(name)
 	if err != nil {
 		return nil, fmt.Errorf("cannot load PJRT plugin %q: %v", name, err)
 	}
-	pjrtBackend, err := backend.New(bld, plugin)
+	pjrtBackend, err := backend.New(bld, plugin, opts...)
 	if err != nil {
 		return nil, err
 	}
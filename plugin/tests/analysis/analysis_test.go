@@ -0,0 +1,141 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/gx-org/gx/api/tracer"
+	"github.com/gx-org/gx/api/values"
+	"github.com/gx-org/gx/build/builder"
+	"github.com/gx-org/gx/build/importers"
+	"github.com/gx-org/gx/build/ir"
+	gxstdlib "github.com/gx-org/gx/stdlib"
+	"github.com/gx-org/xlapjrt/backend"
+	"github.com/gx-org/xlapjrt/plugin"
+	"github.com/gx-org/xlapjrt/stdlib"
+)
+
+const src = `
+package analysistest
+
+func MulAdd(x, y [4]float32) [4]float32 {
+	return x*y + x
+}
+`
+
+func TestAnalysis(t *testing.T) {
+	bld := builder.New(importers.NewCacheLoader(
+		gxstdlib.Importer(stdlib.Stdlib),
+		stdlib.Importer(),
+	))
+	rtm, err := plugin.NewWithBuilder("cpu", bld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := bld.NewIncrementalPackage("analysistest")
+	if err := pkg.Build(src); err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	dev, err := rtm.Device(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ := ir.NewArrayType(nil, ir.Float32Type(), nil)
+	x, err := values.ArrayFloatValue(typ, []float32{1, 2, 3, 4}, []int{4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := pkg.IR().FindFunc("MulAdd").(*ir.FuncDecl)
+	if _, err := tracer.Trace(dev, fn, nil, []values.Value{x, x}, nil); err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	analyses := rtm.Backend().(backend.Analyzer).Analyses()
+	if len(analyses) != 1 {
+		t.Fatalf("got %d analyses but want 1", len(analyses))
+	}
+	got := analyses[0]
+	if _, err := rtm.Backend().(backend.Analyzer).Analysis(got.Name); err != nil {
+		t.Error(err)
+	}
+	// One multiplication and one addition per element.
+	if want := int64(8); got.Flops != want {
+		t.Errorf("got %d FLOPs but want %d", got.Flops, want)
+	}
+	// Each operation reads two arrays and writes one.
+	if want := int64(2 * 3 * 4 * 4); got.BytesAccessed != want {
+		t.Errorf("got %d bytes accessed but want %d", got.BytesAccessed, want)
+	}
+	if got.NumOutputs != 1 || got.NumTraced != 0 {
+		t.Errorf("got %d outputs and %d traced values but want 1 and 0", got.NumOutputs, got.NumTraced)
+	}
+	if got.PeakBytes <= 0 {
+		t.Errorf("got a peak memory of %d bytes but want a positive value", got.PeakBytes)
+	}
+}
+
+const loopSrc = `
+package analysisloop
+
+import "control"
+
+func CountTo10(x int32) int32 {
+	return control.While(x,
+		func(s int32) bool { return s < 10 },
+		func(s int32) int32 { return s + 1 })
+}
+`
+
+func TestAnalysisLoop(t *testing.T) {
+	bld := builder.New(importers.NewCacheLoader(
+		gxstdlib.Importer(stdlib.Stdlib),
+		stdlib.Importer(),
+	))
+	rtm, err := plugin.NewWithBuilder("cpu", bld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := bld.NewIncrementalPackage("analysisloop")
+	if err := pkg.Build(loopSrc); err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	dev, err := rtm.Device(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := values.AtomIntegerValue[int32](ir.Int32Type(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := pkg.IR().FindFunc("CountTo10").(*ir.FuncDecl)
+	if _, err := tracer.Trace(dev, fn, nil, []values.Value{x}, nil); err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	got, err := rtm.Backend().(backend.Analyzer).Analysis(fn.FullyQualifiedName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// All the operations are in the loop.
+	if got.Flops != 0 {
+		t.Errorf("got %d FLOPs outside the loop but want 0", got.Flops)
+	}
+	// One comparison in the condition and one addition in the body.
+	if want := int64(2); got.LoopFlopsPerIteration != want {
+		t.Errorf("got %d FLOPs per iteration but want %d", got.LoopFlopsPerIteration, want)
+	}
+	if got.LoopBytesAccessedPerIteration <= 0 {
+		t.Errorf("got %d bytes accessed per iteration but want a positive value", got.LoopBytesAccessedPerIteration)
+	}
+}
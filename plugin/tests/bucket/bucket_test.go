@@ -0,0 +1,160 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bucket_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gx-org/backend/dtype"
+	"github.com/gx-org/gx/api/values"
+	"github.com/gx-org/gx/build/builder"
+	"github.com/gx-org/gx/build/importers"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/golang/backend/kernels"
+	gxstdlib "github.com/gx-org/gx/stdlib"
+	"github.com/gx-org/xlapjrt/backend"
+	"github.com/gx-org/xlapjrt/backend/bucket"
+	"github.com/gx-org/xlapjrt/plugin"
+	"github.com/gx-org/xlapjrt/stdlib"
+)
+
+const src = `
+package buckettest
+
+import "num"
+
+func Double(x [_n]float32, length int64) [n]float32 {
+	return x * 2
+}
+
+func Mean(x [_n]float32, length int64) float32 {
+	// Padded values are zeros: they do not change the sum.
+	return num.Sum(x, []intidx{0}) / float32(length)
+}
+
+func Spread(x [_n]float32, length int64) [4]float32 {
+	// The result has the length of the smallest bucket but does not depend on n.
+	return [4]float32{1, 2, 3, 4} * num.Sum(x, []intidx{0})
+}
+`
+
+func TestBuckets(t *testing.T) {
+	buckets, err := bucket.New(8, 4, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		length, want int
+	}{
+		{length: 0, want: 4},
+		{length: 4, want: 4},
+		{length: 5, want: 8},
+		{length: 16, want: 16},
+	} {
+		got, err := buckets.Size(test.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("bucket size of %d: got %d but want %d", test.length, got, test.want)
+		}
+	}
+	if _, err := buckets.Size(17); err == nil {
+		t.Errorf("bucket size of 17: got no error")
+	}
+	for _, sizes := range [][]int{nil, {0, 4}, {4, 4}} {
+		if _, err := bucket.New(sizes...); err == nil {
+			t.Errorf("bucket.New(%v): got no error", sizes)
+		}
+	}
+}
+
+func newFunc(t *testing.T, name string) *bucket.Func {
+	bld := builder.New(importers.NewCacheLoader(
+		gxstdlib.Importer(stdlib.Stdlib),
+		stdlib.Importer(),
+	))
+	rtm, err := plugin.NewWithBuilder("cpu", bld, backend.WithShapeBuckets(4, 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := bld.NewIncrementalPackage("buckettest")
+	if err := pkg.Build(src); err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	dev, err := rtm.Device(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn, err := bucket.NewFunc(dev, pkg.IR().FindFunc(name).(*ir.FuncDecl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fn
+}
+
+func run(t *testing.T, fn *bucket.Func, vals []float32) []float32 {
+	typ := ir.NewArrayType(nil, ir.Float32Type(), nil)
+	arg, err := values.ArrayFloatValue(typ, vals, []int{len(vals)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outs, err := fn.Run([]values.Value{arg})
+	if err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	host, err := outs[0].(values.Array).ToHostArray(kernels.Allocator())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer host.Buffer().Release()
+	return slices.Clone(dtype.ToSlice[float32](host.Buffer().Acquire()))
+}
+
+func TestFunc(t *testing.T) {
+	double := newFunc(t, "Double")
+	mean := newFunc(t, "Mean")
+	spread := newFunc(t, "Spread")
+	for _, vals := range [][]float32{
+		{1, 2, 3},
+		{1, 2, 3, 4},
+		{1, 2, 3, 4, 5},
+		{5, 6},
+	} {
+		want := make([]float32, len(vals))
+		var sum float32
+		for i, v := range vals {
+			want[i] = 2 * v
+			sum += v
+		}
+		if got := run(t, double, vals); !slices.Equal(got, want) {
+			t.Errorf("Double(%v): got %v but want %v", vals, got, want)
+		}
+		if got, want := run(t, mean, vals), sum/float32(len(vals)); !slices.Equal(got, []float32{want}) {
+			t.Errorf("Mean(%v): got %v but want %v", vals, got, want)
+		}
+		if got, want := run(t, spread, vals), []float32{sum, 2 * sum, 3 * sum, 4 * sum}; !slices.Equal(got, want) {
+			t.Errorf("Spread(%v): got %v but want %v", vals, got, want)
+		}
+	}
+	// Lengths 3, 4, and 2 share the bucket of size 4. Length 5 uses the bucket of size 8.
+	want := bucket.Stats{Compilations: 2, Runs: 4}
+	for name, fn := range map[string]*bucket.Func{"Double": double, "Mean": mean, "Spread": spread} {
+		if got := fn.Stats(); got != want {
+			t.Errorf("%s statistics: got %+v but want %+v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors_test
+
+import (
+	"errors"
+	"go/ast"
+	"go/token"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gx-org/backend/dtype"
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/backend/platform"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/api/tracer"
+	"github.com/gx-org/gx/api/values"
+	"github.com/gx-org/gx/build/builder"
+	"github.com/gx-org/gx/build/importers"
+	"github.com/gx-org/gx/build/ir"
+	gxstdlib "github.com/gx-org/gx/stdlib"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+	pjrtplatform "github.com/gx-org/xlapjrt/backend/platform"
+	"github.com/gx-org/xlapjrt/plugin"
+	"github.com/gx-org/xlapjrt/stdlib"
+)
+
+func TestOpError(t *testing.T) {
+	rtm, err := plugin.New("cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := rtm.Backend().NewOps("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	xShape := &shape.Shape{DType: dtype.Float32, AxisLengths: []int{2, 3}}
+	x, err := g.Core().Argument("x", xShape, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = g.Core().Reshape(x, []int{4})
+	var opErr *pjrtgraph.OpError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("got error %v of type %T but want a %T", err, err, opErr)
+	}
+	if opErr.Op != "Reshape" {
+		t.Errorf("got operation %q but want %q", opErr.Op, "Reshape")
+	}
+	if len(opErr.Operands) != 1 || !opErr.Operands[0].Equal(xShape) {
+		t.Errorf("got operands %v but want [%s]", opErr.Operands, xShape)
+	}
+	// Set the GX source position of the error.
+	fset := token.NewFileSet()
+	file := fset.AddFile("reshape.gx", -1, 100)
+	file.SetLines([]int{0, 50})
+	src := &ast.Ident{NamePos: file.Pos(55), Name: "x"}
+	err = pjrtgraph.AtPosition(fset, src, err)
+	if want := "reshape.gx:2:6: cannot build Reshape("; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("got error %q but want prefix %q", err.Error(), want)
+	}
+}
+
+const reshapeSrc = `package errtest
+
+func Reshape(x [_]float32) [4]float32 {
+	return [4]float32(x)
+}
+`
+
+func TestOpErrorPosition(t *testing.T) {
+	bld := builder.New(importers.NewCacheLoader(
+		gxstdlib.Importer(stdlib.Stdlib),
+		stdlib.Importer(),
+	))
+	rtm, err := plugin.NewWithBuilder("cpu", bld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := fstest.MapFS{"reshape.gx": &fstest.MapFile{Data: []byte(reshapeSrc)}}
+	pkg, err := bld.BuildFiles("errtest", "errtest", fs, []string{"reshape.gx"})
+	if err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	dev, err := rtm.Device(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ := ir.NewArrayType(nil, ir.Float32Type(), nil)
+	arg, err := values.ArrayFloatValue(typ, make([]float32, 6), []int{2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The GX type checker cannot check the reshape of an array of unknown rank:
+	// the error is only detected by XLA when the graph is built.
+	fn := pkg.IR().FindFunc("Reshape").(*ir.FuncDecl)
+	_, err = tracer.Trace(dev, fn, nil, []values.Value{arg}, nil)
+	if err == nil {
+		t.Fatal("reshaping [2][3]float32 to [4]float32: got no error")
+	}
+	const wantPos = "reshape.gx:4:9"
+	if !strings.Contains(err.Error(), wantPos) {
+		t.Errorf("got error %q but want position %s", err.Error(), wantPos)
+	}
+	opErr, ok := pjrtgraph.OpErrorOf(err)
+	if !ok {
+		t.Fatalf("got error %v of type %T but want a %T", err, err, opErr)
+	}
+	if opErr.Op != "Reshape" {
+		t.Errorf("got operation %q but want %q", opErr.Op, "Reshape")
+	}
+	if got := opErr.Pos.String(); got != wantPos {
+		t.Errorf("got position %s but want %s", got, wantPos)
+	}
+}
+
+func TestBackendErrors(t *testing.T) {
+	rtm, err := plugin.New("cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev, err := rtm.Backend().Platform().Device(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sh := &shape.Shape{DType: dtype.Float32, AxisLengths: []int{2}}
+	if _, err := dev.Send(make([]byte, 4), sh); !errors.Is(err, pjrtplatform.ErrShapeMismatch) {
+		t.Errorf("sending 4 bytes as %s: got error %v but want %v", sh, err, pjrtplatform.ErrShapeMismatch)
+	}
+	handle, err := dev.Send(make([]byte, 8), sh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := handle.ToDevice(nil); !errors.Is(err, pjrtplatform.ErrUnsupportedTransfer) {
+		t.Errorf("transfer to a nil device: got error %v but want %v", err, pjrtplatform.ErrUnsupportedTransfer)
+	}
+}
+
+func TestOutOfMemory(t *testing.T) {
+	pjrtErr := errors.New("RESOURCE_EXHAUSTED: Out of memory allocating 1GiB")
+	err := pjrtplatform.NewError(pjrtplatform.ErrExecution, pjrtErr, "cannot run function %s", "F")
+	if !errors.Is(err, pjrtplatform.ErrOutOfMemory) {
+		t.Errorf("got error %v but want %v", err, pjrtplatform.ErrOutOfMemory)
+	}
+	if !errors.Is(err, pjrtErr) {
+		t.Errorf("error %v does not wrap the PJRT error", err)
+	}
+	var backendErr *pjrtplatform.Error
+	if !errors.As(err, &backendErr) || backendErr.Msg != "cannot run function F" {
+		t.Errorf("got error %#v but want a %T with its message", err, backendErr)
+	}
+}
+
+func TestEighMaxSize(t *testing.T) {
+	rtm, err := plugin.New("cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := rtm.Backend().NewOps("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := pjrtgraph.EighMaxSize + 1
+	x, err := g.Core().Argument("x", &shape.Shape{DType: dtype.Float32, AxisLengths: []int{n, n}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := g.(*pjrtgraph.Graph).Eigh(x); !errors.Is(err, pjrtplatform.ErrUnsupported) {
+		t.Errorf("Eigh of %d x %d matrices: got error %v but want %v", n, n, err, pjrtplatform.ErrUnsupported)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	rtm, err := plugin.New("cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph, err := rtm.Backend().NewOps("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := graph.(*pjrtgraph.Graph)
+	xShape := &shape.Shape{DType: dtype.Float32, AxisLengths: []int{2, 3}}
+	x, err := g.Argument("x", xShape, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, scalarErr := g.Scalar(1, dtype.Int)
+	_, castErr := g.Cast(x, dtype.Int)
+	_, bitcastErr := g.Bitcast(x, dtype.Int)
+	_, unaryErr := g.Unary(&ast.UnaryExpr{Op: token.XOR}, x)
+	_, binaryErr := g.Binary(&ast.BinaryExpr{Op: token.ARROW}, x, x)
+	for _, test := range []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "Scalar", err: scalarErr, want: pjrtplatform.ErrUnsupportedDType},
+		{name: "Cast", err: castErr, want: pjrtplatform.ErrUnsupportedDType},
+		{name: "Bitcast", err: bitcastErr, want: pjrtplatform.ErrUnsupportedDType},
+		{name: "Unary", err: unaryErr, want: pjrtplatform.ErrUnsupported},
+		{name: "Binary", err: binaryErr, want: pjrtplatform.ErrUnsupported},
+	} {
+		if !errors.Is(test.err, test.want) {
+			t.Errorf("%s: got error %v but want %v", test.name, test.err, test.want)
+		}
+	}
+	_, splitErr := g.Split(x, 1, 2)
+	var opErr *pjrtgraph.OpError
+	if !errors.As(splitErr, &opErr) || opErr.Op != "Split" {
+		t.Errorf("Split: got error %v of type %T but want a %T", splitErr, splitErr, opErr)
+	}
+}
+
+func TestInvalidHandle(t *testing.T) {
+	rtm, err := plugin.New("cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := rtm.Backend().NewOps("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sh := &shape.Shape{DType: dtype.Float32, AxisLengths: []int{2}}
+	x, err := g.Core().Argument("x", sh, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev, err := rtm.Backend().Platform().Device(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner, err := g.Compile(dev, []*ops.OutputNode{{Node: x, Shape: sh}}, nil, []*shape.Shape{sh})
+	if err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	handle, err := dev.Send(make([]byte, 8), sh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := handle.(*pjrtplatform.Handle).OnDeviceBuffer().Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := runner.Run([]platform.Handle{handle}); !errors.Is(err, pjrtplatform.ErrInvalidHandle) {
+		t.Errorf("running with a destroyed buffer: got error %v but want %v", err, pjrtplatform.ErrInvalidHandle)
+	}
+}
+
+func TestGraphOpErrors(t *testing.T) {
+	rtm, err := plugin.New("cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph, err := rtm.Backend().NewOps("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := graph.(*pjrtgraph.Graph)
+	xShape := &shape.Shape{DType: dtype.Float32, AxisLengths: []int{3}}
+	x, err := g.Argument("x", xShape, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, choleskyErr := g.Cholesky(x)
+	_, windowErr := g.ReduceWindow(x, pjrtgraph.WindowReducer(-1), &pjrtgraph.Window{Dimensions: []int{2}})
+	for _, test := range []struct {
+		op  string
+		err error
+	}{
+		{op: "Cholesky", err: choleskyErr},
+		{op: "ReduceWindow", err: windowErr},
+	} {
+		var opErr *pjrtgraph.OpError
+		if !errors.As(test.err, &opErr) {
+			t.Errorf("%s: got error %v of type %T but want a %T", test.op, test.err, test.err, opErr)
+			continue
+		}
+		if opErr.Op != test.op {
+			t.Errorf("got operation %q but want %q", opErr.Op, test.op)
+		}
+		if len(opErr.Operands) != 1 || !opErr.Operands[0].Equal(xShape) {
+			t.Errorf("%s: got operands %v but want [%s]", test.op, opErr.Operands, xShape)
+		}
+	}
+	// The GX interpreter sets the position of the errors of builtins.
+	fset := token.NewFileSet()
+	file := fset.AddFile("linalg.gx", -1, 100)
+	file.SetLines([]int{0, 50})
+	src := &ast.Ident{NamePos: file.Pos(60), Name: "x"}
+	err = pjrtgraph.AtPosition(fset, src, choleskyErr)
+	if want := "linalg.gx:2:11: cannot build Cholesky("; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("got error %q but want prefix %q", err.Error(), want)
+	}
+}
+
+const highestSrc = `
+package errtest
+
+import "xla"
+
+func MatMul(x, y [2][2]float32) [2][2]float32 {
+	return xla.MatMulWithConfig(x, y, "highest", "")
+}
+`
+
+func TestHighestPrecision(t *testing.T) {
+	bld := builder.New(importers.NewCacheLoader(
+		gxstdlib.Importer(stdlib.Stdlib),
+		stdlib.Importer(),
+	))
+	if _, err := plugin.NewWithBuilder("cpu", bld); err != nil {
+		t.Fatal(err)
+	}
+	err := bld.NewIncrementalPackage("errtest").Build(highestSrc)
+	if err == nil {
+		t.Fatal("building a dot product with the highest precision: got no error")
+	}
+	if want := pjrtplatform.ErrUnsupported.Error(); !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %q but want %q", err.Error(), want)
+	}
+}
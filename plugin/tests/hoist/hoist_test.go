@@ -0,0 +1,168 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hoist_test
+
+import (
+	"go/ast"
+	"go/token"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/gx-org/backend/dtype"
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/backend/platform"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/api/tracer"
+	"github.com/gx-org/gx/api/values"
+	"github.com/gx-org/gx/build/builder"
+	"github.com/gx-org/gx/build/importers"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/golang/backend/kernels"
+	gxstdlib "github.com/gx-org/gx/stdlib"
+	"github.com/gx-org/xlapjrt/backend"
+	"github.com/gx-org/xlapjrt/plugin"
+	"github.com/gx-org/xlapjrt/stdlib"
+)
+
+const src = `
+package hoisttest
+
+func Affine(x [4]float32) [4]float32 {
+	// The scale (16 bytes) is hoisted, the offset (4 bytes) is embedded.
+	return x*[4]float32{1, 2, 3, 4} + float32(10)
+}
+`
+
+func TestHoistConstants(t *testing.T) {
+	bld := builder.New(importers.NewCacheLoader(
+		gxstdlib.Importer(stdlib.Stdlib),
+		stdlib.Importer(),
+	))
+	rtm, err := plugin.NewWithBuilder("cpu", bld, backend.WithConstantHoisting(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := bld.NewIncrementalPackage("hoisttest")
+	if err := pkg.Build(src); err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	dev, err := rtm.Device(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ := ir.NewArrayType(nil, ir.Float32Type(), nil)
+	arg := func(vals []float32) values.Value {
+		val, err := values.ArrayFloatValue(typ, vals, []int{len(vals)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return val
+	}
+	fn := pkg.IR().FindFunc("Affine").(*ir.FuncDecl)
+	compiled, err := tracer.Trace(dev, fn, nil, []values.Value{arg([]float32{0, 0, 0, 0})}, nil)
+	if err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	// Run the same executable several times to check that hoisted buffers are reused.
+	for _, vals := range [][]float32{{1, 1, 1, 1}, {1, 2, 3, 4}, {-1, 0, 1, 2}} {
+		outs, err := compiled.Run(nil, []values.Value{arg(vals)}, nil)
+		if err != nil {
+			t.Fatalf("\n%+v", err)
+		}
+		host, err := outs[0].(values.Array).ToHostArray(kernels.Allocator())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := slices.Clone(dtype.ToSlice[float32](host.Buffer().Acquire()))
+		host.Buffer().Release()
+		want := make([]float32, len(vals))
+		for i, v := range vals {
+			want[i] = v*float32(i+1) + 10
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("Affine(%v): got %v but want %v", vals, got, want)
+		}
+	}
+}
+
+func TestCloseRunner(t *testing.T) {
+	rtm, err := plugin.New("cpu", backend.WithConstantHoisting(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev, err := rtm.Device(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ := ir.NewArrayType(nil, ir.Float32Type(), nil)
+	scale, err := values.ArrayFloatValue(typ, []float32{1, 2, 3, 4}, []int{4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scaleHost, err := scale.ToHostArray(kernels.Allocator())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := rtm.Backend().NewOps("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sh := &shape.Shape{DType: dtype.Float32, AxisLengths: []int{4}}
+	x, err := g.Core().Argument("x", sh, 0)
+	if err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	cst, err := g.Core().Constant(scaleHost.Buffer())
+	if err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	y, err := g.Core().Binary(&ast.BinaryExpr{Op: token.MUL}, x, cst)
+	if err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	runner, err := g.Compile(dev.PlatformDevice(), []*ops.OutputNode{{Node: y, Shape: sh}}, nil, []*shape.Shape{sh})
+	if err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	arg, err := scale.ToDevice(dev.PlatformDevice())
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := []platform.Handle{arg.DeviceHandle()}
+	if _, _, err := runner.Run(args); err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	closer, ok := runner.(io.Closer)
+	if !ok {
+		t.Fatalf("runner %T does not implement io.Closer", runner)
+	}
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// Closing twice is a no-op.
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := runner.Run(args); err == nil {
+		t.Errorf("running a closed runner: got no error")
+	}
+}
+
+func TestHoistConstantsInvalidThreshold(t *testing.T) {
+	if _, err := plugin.New("cpu", backend.WithConstantHoisting(0)); err == nil {
+		t.Errorf("WithConstantHoisting(0): got no error")
+	}
+}
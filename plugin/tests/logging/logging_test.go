@@ -0,0 +1,107 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/gx-org/gx/api/tracer"
+	"github.com/gx-org/gx/api/values"
+	"github.com/gx-org/gx/build/builder"
+	"github.com/gx-org/gx/build/importers"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/golang/backend/kernels"
+	gxstdlib "github.com/gx-org/gx/stdlib"
+	"github.com/gx-org/xlapjrt/backend"
+	"github.com/gx-org/xlapjrt/plugin"
+	"github.com/gx-org/xlapjrt/stdlib"
+)
+
+const src = `
+package loggingtest
+
+func Double(x [4]float32) [4]float32 {
+	return x * 2
+}
+`
+
+func TestLogger(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	bld := builder.New(importers.NewCacheLoader(
+		gxstdlib.Importer(stdlib.Stdlib),
+		stdlib.Importer(),
+	))
+	rtm, err := plugin.NewWithBuilder("cpu", bld, backend.WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := bld.NewIncrementalPackage("loggingtest")
+	if err := pkg.Build(src); err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	dev, err := rtm.Device(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ := ir.NewArrayType(nil, ir.Float32Type(), nil)
+	x, err := values.ArrayFloatValue(typ, []float32{1, 2, 3, 4}, []int{4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := pkg.IR().FindFunc("Double").(*ir.FuncDecl)
+	compiled, err := tracer.Trace(dev, fn, nil, []values.Value{x}, nil)
+	if err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	outs, err := compiled.Run(nil, []values.Value{x}, nil)
+	if err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	host, err := outs[0].(values.Array).ToHostArray(kernels.Allocator())
+	if err != nil {
+		t.Fatal(err)
+	}
+	host.Buffer().Release()
+
+	events := make(map[string]map[string]any)
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var event map[string]any
+		if err := dec.Decode(&event); err != nil {
+			t.Fatal(err)
+		}
+		events[event["msg"].(string)] = event
+	}
+	for msg, attrs := range map[string][]string{
+		"graph compilation": {"function", "hlo_text_bytes", "duration"},
+		"buffer transfer":   {"direction", "device", "shape", "bytes"},
+		"graph run":         {"function", "device", "latency"},
+	} {
+		event, ok := events[msg]
+		if !ok {
+			t.Errorf("no %q event logged", msg)
+			continue
+		}
+		for _, attr := range attrs {
+			if _, ok := event[attr]; !ok {
+				t.Errorf("event %q has no attribute %q: %v", msg, attr, event)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics_test
+
+import (
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/gx-org/gx/api/tracer"
+	"github.com/gx-org/gx/api/values"
+	"github.com/gx-org/gx/build/builder"
+	"github.com/gx-org/gx/build/importers"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/golang/backend/kernels"
+	gxstdlib "github.com/gx-org/gx/stdlib"
+	"github.com/gx-org/xlapjrt/backend"
+	"github.com/gx-org/xlapjrt/backend/metrics"
+	"github.com/gx-org/xlapjrt/plugin"
+	"github.com/gx-org/xlapjrt/stdlib"
+)
+
+const src = `
+package metricstest
+
+func Double(x [4]float32) [4]float32 {
+	return x * 2
+}
+`
+
+func TestExpvar(t *testing.T) {
+	// A single bucket such that all runs are counted in the same bucket.
+	m := metrics.NewExpvar("xlapjrt_metrics_test", []time.Duration{time.Hour})
+	bld := builder.New(importers.NewCacheLoader(
+		gxstdlib.Importer(stdlib.Stdlib),
+		stdlib.Importer(),
+	))
+	rtm, err := plugin.NewWithBuilder("cpu", bld, backend.WithMetrics(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := bld.NewIncrementalPackage("metricstest")
+	if err := pkg.Build(src); err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	dev, err := rtm.Device(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ := ir.NewArrayType(nil, ir.Float32Type(), nil)
+	x, err := values.ArrayFloatValue(typ, []float32{1, 2, 3, 4}, []int{4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := pkg.IR().FindFunc("Double").(*ir.FuncDecl)
+	compiled, err := tracer.Trace(dev, fn, nil, []values.Value{x}, nil)
+	if err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	const numRuns = 3
+	for range numRuns {
+		outs, err := compiled.Run(nil, []values.Value{x}, nil)
+		if err != nil {
+			t.Fatalf("\n%+v", err)
+		}
+		host, err := outs[0].(values.Array).ToHostArray(kernels.Allocator())
+		if err != nil {
+			t.Fatal(err)
+		}
+		host.Buffer().Release()
+	}
+	functions := m.Map().Get("functions").(*expvar.Map)
+	var numFuncs int
+	functions.Do(func(kv expvar.KeyValue) {
+		numFuncs++
+		fn := kv.Value.(*expvar.Map)
+		if got := fn.Get("compilations").(*expvar.Int).Value(); got != 1 {
+			t.Errorf("%s: got %d compilations but want 1", kv.Key, got)
+		}
+		if got := fn.Get("runs").(*expvar.Int).Value(); got != numRuns {
+			t.Errorf("%s: got %d runs but want %d", kv.Key, got, numRuns)
+		}
+		latency := fn.Get("run_latency").(*expvar.Map)
+		if got := latency.Get(time.Hour.String()).(*expvar.Int).Value(); got != numRuns {
+			t.Errorf("%s: got %d runs in the %s latency bucket but want %d", kv.Key, got, time.Hour, numRuns)
+		}
+		if got := latency.Get("inf").(*expvar.Int).Value(); got != 0 {
+			t.Errorf("%s: got %d runs in the inf latency bucket but want 0", kv.Key, got)
+		}
+		// Each run passes and returns a [4]float32 array.
+		for _, key := range []string{"argument_bytes", "output_bytes"} {
+			if got := fn.Get(key).(*expvar.Int).Value(); got != 16*numRuns {
+				t.Errorf("%s: got %d %s but want %d", kv.Key, got, key, 16*numRuns)
+			}
+		}
+	})
+	if numFuncs != 1 {
+		t.Errorf("got metrics for %d functions but want 1", numFuncs)
+	}
+	transfers := m.Map().Get("transfers").(*expvar.Map)
+	for _, key := range []string{"device0.host_to_device_bytes", "device0.device_to_host_bytes"} {
+		bytes, ok := transfers.Get(key).(*expvar.Int)
+		if !ok || bytes.Value() < 16 {
+			t.Errorf("%s: got %v but want at least 16 bytes", key, transfers.Get(key))
+		}
+	}
+}
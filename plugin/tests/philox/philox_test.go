@@ -0,0 +1,320 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package philox_test checks that the Philox generators produce the same bits
+// as the reference implementation of the XLA RngBitGenerator operation.
+//
+// A failure means that XLA, or the plugin, changed the Philox implementation:
+// programs seeded with the same values would not be reproducible anymore.
+// Use the -plugin flag to run the tests on other PJRT plugins than cpu.
+package philox_test
+
+import (
+	"flag"
+	"fmt"
+	"math/bits"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gx-org/xlapjrt/plugin"
+	"github.com/gx-org/gx/build/builder"
+	"github.com/gx-org/gx/build/importers"
+	gxstdlib "github.com/gx-org/gx/stdlib"
+	gxtesting "github.com/gx-org/gx/tests/testing"
+	"github.com/gx-org/xlapjrt/stdlib"
+)
+
+// philox4x32 computes 10 rounds of the Philox-4x32 block function.
+func philox4x32(ctr [4]uint32, key [2]uint32) [4]uint32 {
+	for range 10 {
+		hi0, lo0 := bits.Mul32(ctr[0], 0xD2511F53)
+		hi1, lo1 := bits.Mul32(ctr[2], 0xCD9E8D57)
+		ctr = [4]uint32{hi1 ^ ctr[1] ^ key[0], lo1, hi0 ^ ctr[3] ^ key[1], lo0}
+		key[0] += 0x9E3779B9
+		key[1] += 0xBB67AE85
+	}
+	return ctr
+}
+
+// generator is the reference implementation of the XLA RngBitGenerator operation
+// with the Philox algorithm.
+// The first element of the state is the key and the last two elements are
+// a 128-bit counter (low bits first).
+type generator [3]uint64
+
+// blocks returns n blocks of 128 random bits and advances the counter by n.
+func (g *generator) blocks(n int) [][4]uint32 {
+	key := [2]uint32{uint32(g[0]), uint32(g[0] >> 32)}
+	out := make([][4]uint32, n)
+	for i := range out {
+		lo, carry := bits.Add64(g[1], uint64(i), 0)
+		hi := g[2] + carry
+		out[i] = philox4x32([4]uint32{uint32(lo), uint32(lo >> 32), uint32(hi), uint32(hi >> 32)}, key)
+	}
+	var carry uint64
+	g[1], carry = bits.Add64(g[1], uint64(n), 0)
+	g[2] += carry
+	return out
+}
+
+func (g *generator) uint32s(n int) []uint64 {
+	var out []uint64
+	for _, block := range g.blocks((n + 3) / 4) {
+		for _, v := range block {
+			out = append(out, uint64(v))
+		}
+	}
+	return out[:n]
+}
+
+func (g *generator) uint64s(n int) []uint64 {
+	var out []uint64
+	for _, block := range g.blocks((n + 1) / 2) {
+		out = append(out,
+			uint64(block[0])|uint64(block[1])<<32,
+			uint64(block[2])|uint64(block[3])<<32,
+		)
+	}
+	return out[:n]
+}
+
+// TestReference checks the reference implementation against the values
+// of the GX rand package tests (seeded with rand.NewSource(0)).
+func TestReference(t *testing.T) {
+	g := generator{8717895732742165505, 2259404117704393152, 6050128673802995827}
+	f32 := g
+	wants := [][]uint64{
+		{5593899049600577110, 15353157502835371651, 596190196732659588, 5614058057677614496, 9713047263490594073, 17857316907901081200},
+		{10826104460941062082, 13199785683230879330, 11346820297660219021, 5989968235824991032, 13384425856091111145, 3558705469962351023},
+	}
+	for i, want := range wants {
+		if got := g.uint64s(len(want)); !slices.Equal(got, want) {
+			t.Errorf("call %d: got %v but want %v", i, got, want)
+		}
+	}
+	// float32 values computed from the 23 high bits of uint32 values.
+	wantFloats := []string{"0.253272", "0.303246", "0.504397", "0.832296", "0.245392", "0.032319"}
+	for i, v := range f32.uint32s(len(wantFloats)) {
+		got := fmt.Sprintf("%.6f", float32(v>>9)/(1<<23))
+		if got != wantFloats[i] {
+			t.Errorf("float32 value %d: got %s but want %s", i, got, wantFloats[i])
+		}
+	}
+}
+
+// gxArray returns the text of a GX array as printed by the GX tests.
+func gxArray(t *testing.T, dtype string, dims []int, vals []uint64) string {
+	t.Helper()
+	row := func(vals []uint64) string {
+		s := make([]string, len(vals))
+		for i, v := range vals {
+			s[i] = fmt.Sprint(v)
+		}
+		return "{" + strings.Join(s, ", ") + "}"
+	}
+	switch len(dims) {
+	case 1:
+		return fmt.Sprintf("[%d]%s%s", dims[0], dtype, row(vals))
+	case 2:
+		var b strings.Builder
+		fmt.Fprintf(&b, "[%d][%d]%s{\n", dims[0], dims[1], dtype)
+		for r := range dims[0] {
+			fmt.Fprintf(&b, "\t%s,\n", row(vals[r*dims[1]:(r+1)*dims[1]]))
+		}
+		b.WriteString("}")
+		return b.String()
+	}
+	t.Fatalf("cannot print an array with %d axes", len(dims))
+	return ""
+}
+
+func gxSeed(g generator) string {
+	return fmt.Sprintf("[3]uint64{%d, %d, %d}", g[0], g[1], g[2])
+}
+
+func gxDims(dims []int) string {
+	s := make([]string, len(dims))
+	for i, d := range dims {
+		s[i] = fmt.Sprint(d)
+	}
+	return "[]intlen{" + strings.Join(s, ", ") + "}"
+}
+
+func numElements(dims []int) int {
+	n := 1
+	for _, d := range dims {
+		n *= d
+	}
+	return n
+}
+
+// sample is a call to a method of a Philox generator.
+type sample struct {
+	dtype string
+	dims  []int
+}
+
+func (s sample) want(t *testing.T, g *generator) string {
+	n := numElements(s.dims)
+	var vals []uint64
+	if s.dtype == "uint32" {
+		vals = g.uint32s(n)
+	} else {
+		vals = g.uint64s(n)
+	}
+	return gxArray(t, s.dtype, s.dims, vals)
+}
+
+func (s sample) method() string {
+	return map[string]string{"uint32": "Uint32", "uint64": "Uint64"}[s.dtype]
+}
+
+func resultTypes(samples []sample) string {
+	s := make([]string, len(samples))
+	for i, smp := range samples {
+		for _, d := range smp.dims {
+			s[i] += fmt.Sprintf("[%d]", d)
+		}
+		s[i] += smp.dtype
+	}
+	return "(" + strings.Join(s, ", ") + ")"
+}
+
+func writeWant(b *strings.Builder, wants []string) {
+	b.WriteString("\t// Want:\n")
+	for i, want := range wants {
+		for j, line := range strings.Split(want, "\n") {
+			if j == 0 && len(wants) > 1 {
+				line = fmt.Sprintf("%d: %s", i, line)
+			}
+			fmt.Fprintf(b, "\t// %s\n", line)
+		}
+	}
+	b.WriteString("}\n\n")
+}
+
+// philoxPackage is a GX package providing a Philox generator.
+type philoxPackage struct {
+	name string
+	// newPhilox is the GX expression of the function returning a new generator.
+	newPhilox string
+}
+
+var philoxPackages = []philoxPackage{
+	{name: "Rand", newPhilox: "rand.NewPhilox"},
+	{name: "XLA", newPhilox: "random.NewPhilox"},
+}
+
+// streamTest returns a GX test checking the values returned by successive calls
+// to the methods of a generator.
+func streamTest(t *testing.T, pkg philoxPackage, name string, seed generator, samples []sample) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "func Test%s%s() %s {\n", pkg.name, name, resultTypes(samples))
+	fmt.Fprintf(&b, "\tp := %s(%s)\n", pkg.newPhilox, gxSeed(seed))
+	vars := make([]string, len(samples))
+	wants := make([]string, len(samples))
+	g := seed
+	for i, smp := range samples {
+		vars[i] = fmt.Sprintf("v%d", i)
+		fmt.Fprintf(&b, "\tp, %s := p.%s(%s)\n", vars[i], smp.method(), gxDims(smp.dims))
+		wants[i] = smp.want(t, &g)
+	}
+	fmt.Fprintf(&b, "\treturn %s\n", strings.Join(vars, ", "))
+	writeWant(&b, wants)
+	return b.String()
+}
+
+// advanceTest returns a GX test checking that the state of a generator after a sequence of calls
+// is the state computed by the reference implementation.
+func advanceTest(t *testing.T, pkg philoxPackage, name string, seed generator, samples []sample, next sample) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "func Test%s%s() %s {\n", pkg.name, name, resultTypes([]sample{next, next}))
+	fmt.Fprintf(&b, "\tp := %s(%s)\n", pkg.newPhilox, gxSeed(seed))
+	g := seed
+	for _, smp := range samples {
+		fmt.Fprintf(&b, "\tp, _ = p.%s(%s)\n", smp.method(), gxDims(smp.dims))
+		smp.want(t, &g)
+	}
+	fmt.Fprintf(&b, "\t_, got := p.%s(%s)\n", next.method(), gxDims(next.dims))
+	fmt.Fprintf(&b, "\t_, want := %s(%s).%s(%s)\n", pkg.newPhilox, gxSeed(g), next.method(), gxDims(next.dims))
+	b.WriteString("\treturn got, want\n")
+	want := next.want(t, &g)
+	writeWant(&b, []string{want, want})
+	return b.String()
+}
+
+var seeds = []struct {
+	name string
+	seed generator
+}{
+	{name: "Zero", seed: generator{0, 0, 0}},
+	{name: "Source0", seed: generator{8717895732742165505, 2259404117704393152, 6050128673802995827}},
+	// The low 64 bits of the counter overflow after 2 blocks.
+	{name: "Carry", seed: generator{0x0123456789abcdef, 0xfffffffffffffffe, 42}},
+	// The 128-bit counter wraps around after 1 block.
+	{name: "Wrap", seed: generator{^uint64(0), ^uint64(0), ^uint64(0)}},
+}
+
+func philoxSource(t *testing.T) string {
+	var b strings.Builder
+	b.WriteString("package philoxtest\n\nimport (\n\t\"rand\"\n\t\"xla/random\"\n)\n\n")
+	for _, pkg := range philoxPackages {
+		for _, s := range seeds {
+			b.WriteString(streamTest(t, pkg, "Uint32"+s.name, s.seed, []sample{
+				{dtype: "uint32", dims: []int{5}},
+				{dtype: "uint32", dims: []int{2, 3}},
+				{dtype: "uint32", dims: []int{4}},
+			}))
+			b.WriteString(streamTest(t, pkg, "Uint64"+s.name, s.seed, []sample{
+				{dtype: "uint64", dims: []int{3}},
+				{dtype: "uint64", dims: []int{2, 3}},
+				{dtype: "uint64", dims: []int{1}},
+			}))
+			// uint32 values are generated 4 at a time and uint64 values 2 at a time:
+			// unused bits of a block are discarded.
+			b.WriteString(streamTest(t, pkg, "Mixed"+s.name, s.seed, []sample{
+				{dtype: "uint32", dims: []int{5}},
+				{dtype: "uint64", dims: []int{3, 1}},
+				{dtype: "uint32", dims: []int{2}},
+			}))
+			b.WriteString(advanceTest(t, pkg, "Advance"+s.name, s.seed, []sample{
+				{dtype: "uint32", dims: []int{7}},
+				{dtype: "uint64", dims: []int{2, 2}},
+			}, sample{dtype: "uint64", dims: []int{4}}))
+		}
+	}
+	return b.String()
+}
+
+var pluginName = flag.String("plugin", "cpu", "name of the PJRT plugin running TestPhilox")
+
+// TestPhilox compares the bits generated by the GX Philox generators
+// to the reference implementation.
+func TestPhilox(t *testing.T) {
+	bld := builder.New(importers.NewCacheLoader(
+		gxstdlib.Importer(stdlib.Stdlib),
+		stdlib.Importer(),
+	))
+	rtm, err := plugin.NewWithBuilder(*pluginName, bld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := fstest.MapFS{
+		"philoxtest/philox_test.gx": &fstest.MapFile{Data: []byte(philoxSource(t))},
+	}
+	session := gxtesting.NewSession(rtm, fs)
+	session.TestFolder(t, "philoxtest")
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package stdlib_test
+
+import (
+	"fmt"
+	"math/cmplx"
+	"math/rand/v2"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	gxtesting "github.com/gx-org/gx/tests/testing"
+)
+
+func randomComplex(rng *rand.Rand, n int) []complex128 {
+	c := make([]complex128, n)
+	for i := range c {
+		c[i] = complex(rng.Float64()*4-2, rng.Float64()*4-2)
+	}
+	return c
+}
+
+// gxComplex returns the GX literals of the real and imaginary parts of a complex vector.
+func gxComplex(dtype string, c []complex128) (re, im string) {
+	reVals := make([]float64, len(c))
+	imVals := make([]float64, len(c))
+	for i, v := range c {
+		reVals[i], imVals[i] = real(v), imag(v)
+	}
+	prefix := fmt.Sprintf("[%d]%s", len(c), dtype)
+	return prefix + gxValues(reVals), prefix + gxValues(imVals)
+}
+
+func mapComplex(x []complex128, f func(complex128) complex128) []complex128 {
+	out := make([]complex128, len(x))
+	for i, v := range x {
+		out[i] = f(v)
+	}
+	return out
+}
+
+func zipComplex(x, y []complex128, f func(complex128, complex128) complex128) []complex128 {
+	out := make([]complex128, len(x))
+	for i := range x {
+		out[i] = f(x[i], y[i])
+	}
+	return out
+}
+
+// complexTests returns tests checking both parts of the result of a complex builtin
+// returning a complex array.
+func complexTests(name, dtype, call string, want []complex128, tol float64) []linalgTest {
+	wantRe, wantIm := gxComplex(dtype, want)
+	vars := []string{"re, im := " + call}
+	return []linalgTest{
+		{name: name + "Real", vars: vars, got: "re", want: wantRe, rank: 1, tol: tol},
+		{name: name + "Imag", vars: vars, got: "im", want: wantIm, rank: 1, tol: tol},
+	}
+}
+
+func cmplxTests() []linalgTest {
+	rng := rand.New(rand.NewPCG(3, 4))
+	const n = 8
+	x, y := randomComplex(rng, n), randomComplex(rng, n)
+	xRe, xIm := gxComplex("float64", x)
+	yRe, yIm := gxComplex("float64", y)
+	x32Re, x32Im := gxComplex("float32", x)
+	y32Re, y32Im := gxComplex("float32", y)
+	args := func(parts ...string) string { return strings.Join(parts, ", ") }
+	var tests []linalgTest
+	tests = append(tests, complexTests("Mul", "float64",
+		fmt.Sprintf("cmplx.Mul(%s)", args(xRe, xIm, yRe, yIm)),
+		zipComplex(x, y, func(a, b complex128) complex128 { return a * b }), 1e-12)...)
+	tests = append(tests, complexTests("MulFloat32", "float32",
+		fmt.Sprintf("cmplx.Mul(%s)", args(x32Re, x32Im, y32Re, y32Im)),
+		zipComplex(x, y, func(a, b complex128) complex128 { return a * b }), 1e-5)...)
+	tests = append(tests, complexTests("Div", "float64",
+		fmt.Sprintf("cmplx.Div(%s)", args(xRe, xIm, yRe, yIm)),
+		zipComplex(x, y, func(a, b complex128) complex128 { return a / b }), 1e-12)...)
+	tests = append(tests, complexTests("Conj", "float64",
+		fmt.Sprintf("cmplx.Conj(%s)", args(xRe, xIm)),
+		mapComplex(x, cmplx.Conj), 1e-12)...)
+	abs := make([]float64, n)
+	phase := make([]float64, n)
+	for i, v := range x {
+		abs[i], phase[i] = cmplx.Abs(v), cmplx.Phase(v)
+	}
+	return append(tests,
+		linalgTest{
+			name: "Abs",
+			got:  fmt.Sprintf("cmplx.Abs(%s)", args(xRe, xIm)),
+			want: fmt.Sprintf("[%d]float64%s", n, gxValues(abs)),
+			rank: 1, tol: 1e-12,
+		},
+		linalgTest{
+			name: "Angle",
+			got:  fmt.Sprintf("cmplx.Angle(%s)", args(xRe, xIm)),
+			want: fmt.Sprintf("[%d]float64%s", n, gxValues(phase)),
+			rank: 1, tol: 1e-12,
+		},
+	)
+}
+
+// TestCmplx compares the complex builtins to Go complex arithmetic.
+func TestCmplx(t *testing.T) {
+	var src strings.Builder
+	src.WriteString("package cmplxtest\n\nimport (\n\t\"math\"\n\t\"num\"\n\t\"xla/cmplx\"\n)\n\n")
+	for _, test := range cmplxTests() {
+		src.WriteString(test.source())
+	}
+	fs := fstest.MapFS{
+		"cmplxtest/cmplx_test.gx": &fstest.MapFile{Data: []byte(src.String())},
+	}
+	session := gxtesting.NewSession(newXLARuntime(t), fs)
+	session.TestFolder(t, "cmplxtest")
+}
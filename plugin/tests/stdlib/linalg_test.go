@@ -0,0 +1,485 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package stdlib_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	gxtesting "github.com/gx-org/gx/tests/testing"
+)
+
+// matrix is a dense row-major matrix used by the reference implementations.
+type matrix [][]float64
+
+func newMatrix(rows, cols int) matrix {
+	m := make(matrix, rows)
+	for i := range m {
+		m[i] = make([]float64, cols)
+	}
+	return m
+}
+
+func randomMatrix(rng *rand.Rand, rows, cols int) matrix {
+	m := newMatrix(rows, cols)
+	for i := range m {
+		for j := range m[i] {
+			m[i][j] = rng.Float64()*2 - 1
+		}
+	}
+	return m
+}
+
+func (m matrix) transpose() matrix {
+	t := newMatrix(len(m[0]), len(m))
+	for i := range m {
+		for j := range m[i] {
+			t[j][i] = m[i][j]
+		}
+	}
+	return t
+}
+
+func (m matrix) mul(o matrix) matrix {
+	p := newMatrix(len(m), len(o[0]))
+	for i := range m {
+		for j := range o[0] {
+			for k := range o {
+				p[i][j] += m[i][k] * o[k][j]
+			}
+		}
+	}
+	return p
+}
+
+func (m matrix) clone() matrix {
+	c := make(matrix, len(m))
+	for i := range m {
+		c[i] = slices.Clone(m[i])
+	}
+	return c
+}
+
+// randomSPD returns a random symmetric positive definite matrix.
+func randomSPD(rng *rand.Rand, n int) matrix {
+	m := randomMatrix(rng, n, n)
+	spd := m.mul(m.transpose())
+	for i := range n {
+		spd[i][i] += float64(n)
+	}
+	return spd
+}
+
+// refCholesky computes the Cholesky decomposition with the Cholesky-Banachiewicz algorithm.
+func refCholesky(a matrix) matrix {
+	n := len(a)
+	l := newMatrix(n, n)
+	for i := range n {
+		for j := 0; j <= i; j++ {
+			sum := a[i][j]
+			for k := range j {
+				sum -= l[i][k] * l[j][k]
+			}
+			if i == j {
+				l[i][j] = math.Sqrt(sum)
+			} else {
+				l[i][j] = sum / l[j][j]
+			}
+		}
+	}
+	return l
+}
+
+// refTriangularSolve solves a x = b by forward or backward substitution.
+func refTriangularSolve(a, b matrix, lower bool) matrix {
+	n, k := len(a), len(b[0])
+	x := newMatrix(n, k)
+	for step := range n {
+		i := step
+		if !lower {
+			i = n - 1 - step
+		}
+		for c := range k {
+			sum := b[i][c]
+			for j := range n {
+				if (lower && j < i) || (!lower && j > i) {
+					sum -= a[i][j] * x[j][c]
+				}
+			}
+			x[i][c] = sum / a[i][i]
+		}
+	}
+	return x
+}
+
+// refDet computes the determinant by Gaussian elimination with partial pivoting.
+func refDet(a matrix) float64 {
+	a = a.clone()
+	n := len(a)
+	det := 1.0
+	for col := range n {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if pivot != col {
+			a[pivot], a[col] = a[col], a[pivot]
+			det = -det
+		}
+		det *= a[col][col]
+		for row := col + 1; row < n; row++ {
+			f := a[row][col] / a[col][col]
+			for j := col; j < n; j++ {
+				a[row][j] -= f * a[col][j]
+			}
+		}
+	}
+	return det
+}
+
+// refInv computes the inverse with the Gauss-Jordan elimination.
+func refInv(a matrix) matrix {
+	n := len(a)
+	aug := newMatrix(n, 2*n)
+	for i := range n {
+		copy(aug[i], a[i])
+		aug[i][n+i] = 1
+	}
+	for col := range n {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(aug[row][col]) > math.Abs(aug[pivot][col]) {
+				pivot = row
+			}
+		}
+		aug[pivot], aug[col] = aug[col], aug[pivot]
+		p := aug[col][col]
+		for j := range aug[col] {
+			aug[col][j] /= p
+		}
+		for row := range n {
+			if row == col {
+				continue
+			}
+			f := aug[row][col]
+			for j := range aug[row] {
+				aug[row][j] -= f * aug[col][j]
+			}
+		}
+	}
+	inv := newMatrix(n, n)
+	for i := range n {
+		copy(inv[i], aug[i][n:])
+	}
+	return inv
+}
+
+// refQR computes the QR decomposition with the modified Gram-Schmidt algorithm.
+// The diagonal of r is positive.
+func refQR(a matrix) (q, r matrix) {
+	m, n := len(a), len(a[0])
+	q = a.clone()
+	r = newMatrix(n, n)
+	for j := range n {
+		for k := range j {
+			for i := range m {
+				r[k][j] += q[i][k] * q[i][j]
+			}
+			for i := range m {
+				q[i][j] -= r[k][j] * q[i][k]
+			}
+		}
+		for i := range m {
+			r[j][j] += q[i][j] * q[i][j]
+		}
+		r[j][j] = math.Sqrt(r[j][j])
+		for i := range m {
+			q[i][j] /= r[j][j]
+		}
+	}
+	return q, r
+}
+
+// refEigvals computes the eigenvalues of a symmetric matrix, in ascending order,
+// by applying Jacobi rotations in place until the matrix is diagonal.
+func refEigvals(a matrix) []float64 {
+	a = a.clone()
+	n := len(a)
+	for range 100 {
+		off := 0.0
+		for p := range n {
+			for q := p + 1; q < n; q++ {
+				off += a[p][q] * a[p][q]
+			}
+		}
+		if off < 1e-30 {
+			break
+		}
+		for p := range n {
+			for q := p + 1; q < n; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := range n {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p], a[k][q] = c*akp-s*akq, s*akp+c*akq
+				}
+				for k := range n {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k], a[q][k] = c*apk-s*aqk, s*apk+c*aqk
+				}
+			}
+		}
+	}
+	w := make([]float64, n)
+	for i := range n {
+		w[i] = a[i][i]
+	}
+	slices.Sort(w)
+	return w
+}
+
+func absMatrix(m matrix) matrix {
+	a := m.clone()
+	for i := range a {
+		for j := range a[i] {
+			a[i][j] = math.Abs(a[i][j])
+		}
+	}
+	return a
+}
+
+func eye(n int) matrix {
+	m := newMatrix(n, n)
+	for i := range n {
+		m[i][i] = 1
+	}
+	return m
+}
+
+// gxBatch returns a GX literal for a batch of matrices.
+func gxBatch(dtype string, batch []matrix) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%d][%d][%d]%s{", len(batch), len(batch[0]), len(batch[0][0]), dtype)
+	for _, m := range batch {
+		b.WriteString("{")
+		for _, row := range m {
+			b.WriteString(gxValues(row))
+			b.WriteString(",")
+		}
+		b.WriteString("},")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// gxVectors returns a GX literal for a batch of vectors.
+func gxVectors(dtype string, batch [][]float64) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%d][%d]%s{", len(batch), len(batch[0]), dtype)
+	for _, v := range batch {
+		b.WriteString(gxValues(v))
+		b.WriteString(",")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func gxValues(vals []float64) string {
+	s := make([]string, len(vals))
+	for i, v := range vals {
+		s[i] = fmt.Sprintf("%.17g", v)
+	}
+	return "{" + strings.Join(s, ", ") + "}"
+}
+
+// linalgTest is a GX test function comparing the result of an expression to a reference value.
+type linalgTest struct {
+	name string
+	// vars are GX statements declaring the variables used by the expressions.
+	vars []string
+	// got and want are GX expressions of the same type.
+	got, want string
+	// rank is the number of axes of got and want.
+	rank int
+	tol  float64
+}
+
+func (lt linalgTest) source() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "func Test%s() bool {\n", lt.name)
+	for _, v := range lt.vars {
+		fmt.Fprintf(&b, "\t%s\n", v)
+	}
+	diff := fmt.Sprintf("math.Abs((%s) - (%s))", lt.got, lt.want)
+	if lt.rank > 0 {
+		axes := make([]string, lt.rank)
+		for i := range axes {
+			axes[i] = fmt.Sprint(i)
+		}
+		diff = fmt.Sprintf("num.ReduceMax(%s, []intidx{%s})", diff, strings.Join(axes, ", "))
+	}
+	fmt.Fprintf(&b, "\treturn %s < %g\n", diff, lt.tol)
+	b.WriteString("\t// Want:\n\t// bool(true)\n}\n\n")
+	return b.String()
+}
+
+func linalgTests() []linalgTest {
+	rng := rand.New(rand.NewPCG(1, 2))
+	const n, batch = 4, 2
+	var spd, lower, upper, rhs, square, tall []matrix
+	var chol, solveLower, solveUpper, inv, absR []matrix
+	var det, logDet []float64
+	var eigvals [][]float64
+	for range batch {
+		a := randomSPD(rng, n)
+		spd = append(spd, a)
+		chol = append(chol, refCholesky(a))
+		eigvals = append(eigvals, refEigvals(a))
+
+		l := refCholesky(randomSPD(rng, n))
+		b := randomMatrix(rng, n, 3)
+		lower = append(lower, l)
+		upper = append(upper, l.transpose())
+		rhs = append(rhs, b)
+		solveLower = append(solveLower, refTriangularSolve(l, b, true))
+		solveUpper = append(solveUpper, refTriangularSolve(l.transpose(), b, false))
+
+		s := randomMatrix(rng, n, n)
+		square = append(square, s)
+		d := refDet(s)
+		det = append(det, d)
+		logDet = append(logDet, math.Log(math.Abs(d)))
+		inv = append(inv, refInv(s))
+
+		t := randomMatrix(rng, n+2, n)
+		tall = append(tall, t)
+		_, r := refQR(t)
+		absR = append(absR, r)
+	}
+	float32SPD := []matrix{spd[0]}
+	return []linalgTest{
+		{
+			name: "Cholesky",
+			got:  fmt.Sprintf("linalg.Cholesky(%s)", gxBatch("float64", spd)),
+			want: gxBatch("float64", chol),
+			rank: 3, tol: 1e-10,
+		},
+		{
+			name: "CholeskyFloat32",
+			got:  fmt.Sprintf("linalg.Cholesky(%s)", gxBatch("float32", float32SPD)),
+			want: gxBatch("float32", []matrix{chol[0]}),
+			rank: 3, tol: 1e-4,
+		},
+		{
+			name: "TriangularSolveLower",
+			got:  fmt.Sprintf("linalg.TriangularSolve(%s, %s, true)", gxBatch("float64", lower), gxBatch("float64", rhs)),
+			want: gxBatch("float64", solveLower),
+			rank: 3, tol: 1e-10,
+		},
+		{
+			name: "TriangularSolveUpper",
+			got:  fmt.Sprintf("linalg.TriangularSolve(%s, %s, false)", gxBatch("float64", upper), gxBatch("float64", rhs)),
+			want: gxBatch("float64", solveUpper),
+			rank: 3, tol: 1e-10,
+		},
+		{
+			name: "QRReconstruction",
+			vars: []string{
+				"a := " + gxBatch("float64", tall),
+				"q, r := linalg.QR(a)",
+			},
+			got:  "xla.MatMul(q, r)",
+			want: "a",
+			rank: 3, tol: 1e-10,
+		},
+		{
+			name: "QROrthonormal",
+			vars: []string{"q, _ := linalg.QR(" + gxBatch("float64", tall) + ")"},
+			got:  "xla.MatMul(xla.SwapAxes(q, 1, 2), q)",
+			want: gxBatch("float64", []matrix{eye(n), eye(n)}),
+			rank: 3, tol: 1e-10,
+		},
+		{
+			name: "QRTriangular",
+			vars: []string{"_, r := linalg.QR(" + gxBatch("float64", tall) + ")"},
+			got:  "math.Abs(r)",
+			want: gxBatch("float64", []matrix{absMatrix(absR[0]), absMatrix(absR[1])}),
+			rank: 3, tol: 1e-10,
+		},
+		{
+			name: "EighEigenvalues",
+			vars: []string{"w, _ := linalg.Eigh(" + gxBatch("float64", spd) + ")"},
+			got:  "w",
+			want: gxVectors("float64", eigvals),
+			rank: 2, tol: 1e-10,
+		},
+		{
+			name: "EighEigenvectors",
+			vars: []string{
+				"a := " + gxBatch("float64", spd),
+				"w, v := linalg.Eigh(a)",
+			},
+			got:  "xla.MatMul(a, v)",
+			want: `xla.Einsum("bij,bj->bij", v, w)`,
+			rank: 3, tol: 1e-10,
+		},
+		{
+			name: "Det",
+			got:  fmt.Sprintf("linalg.Det(%s)", gxBatch("float64", square)),
+			want: fmt.Sprintf("[%d]float64%s", batch, gxValues(det)),
+			rank: 1, tol: 1e-10,
+		},
+		{
+			name: "LogDet",
+			got:  fmt.Sprintf("linalg.LogDet(%s)", gxBatch("float64", square)),
+			want: fmt.Sprintf("[%d]float64%s", batch, gxValues(logDet)),
+			rank: 1, tol: 1e-10,
+		},
+		{
+			name: "Inv",
+			got:  fmt.Sprintf("linalg.Inv(%s)", gxBatch("float64", square)),
+			want: gxBatch("float64", inv),
+			rank: 3, tol: 1e-10,
+		},
+	}
+}
+
+// TestLinalg compares the linear algebra builtins to reference Go implementations.
+func TestLinalg(t *testing.T) {
+	var src strings.Builder
+	src.WriteString("package linalgtest\n\nimport (\n\t\"math\"\n\t\"num\"\n\t\"xla\"\n\t\"xla/linalg\"\n)\n\n")
+	for _, test := range linalgTests() {
+		src.WriteString(test.source())
+	}
+	fs := fstest.MapFS{
+		"linalgtest/linalg_test.gx": &fstest.MapFile{Data: []byte(src.String())},
+	}
+	session := gxtesting.NewSession(newXLARuntime(t), fs)
+	session.TestFolder(t, "linalgtest")
+}
@@ -0,0 +1,135 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package stdlib_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	gxtesting "github.com/gx-org/gx/tests/testing"
+)
+
+func splitMix64(z uint64) uint64 {
+	z += 0x9e3779b97f4a7c15
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+// deriveState is the reference implementation of the state derived by Split and FoldIn.
+func deriveState(state [3]uint64, data uint64) [3]uint64 {
+	var derived [3]uint64
+	prev := splitMix64(data)
+	for i := range derived {
+		prev = splitMix64(state[i] ^ prev)
+		derived[i] = prev
+	}
+	return derived
+}
+
+func gxState(state [3]uint64) string {
+	return fmt.Sprintf("[3]uint64{%d, %d, %d}", state[0], state[1], state[2])
+}
+
+func gxStates(states [][3]uint64) string {
+	s := make([]string, len(states))
+	for i, state := range states {
+		s[i] = fmt.Sprintf("{%d, %d, %d}", state[0], state[1], state[2])
+	}
+	return fmt.Sprintf("[%d][3]uint64{%s}", len(states), strings.Join(s, ", "))
+}
+
+// randomTest is a GX test function checking that two integer arrays are equal.
+type randomTest struct {
+	name      string
+	vars      []string
+	got, want string
+	// axes are the axes of got and want.
+	axes string
+}
+
+func (rt randomTest) source() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "func Test%s() bool {\n", rt.name)
+	for _, v := range rt.vars {
+		fmt.Fprintf(&b, "\t%s\n", v)
+	}
+	fmt.Fprintf(&b, "\treturn num.Sum(xla.Select(%s != %s, int64(1), int64(0)), []intidx{%s}) == 0\n", rt.got, rt.want, rt.axes)
+	b.WriteString("\t// Want:\n\t// bool(true)\n}\n\n")
+	return b.String()
+}
+
+func randomTests() []randomTest {
+	seed := [3]uint64{1, 2, 3}
+	split := func(n int) [][3]uint64 {
+		states := make([][3]uint64, n)
+		for i := range states {
+			states[i] = deriveState(seed, uint64(i))
+		}
+		return states
+	}
+	philox := "random.NewPhilox(" + gxState(seed) + ")"
+	return []randomTest{
+		{
+			name: "Split",
+			got:  philox + ".Split(4)",
+			want: gxStates(split(4)),
+			axes: "0, 1",
+		},
+		{
+			// Splitting in fewer seeds returns the first seeds.
+			name: "SplitPrefix",
+			got:  philox + ".Split(2)",
+			want: gxStates(split(2)),
+			axes: "0, 1",
+		},
+		{
+			name: "FoldIn",
+			vars: []string{
+				"_, got := " + philox + ".FoldIn(42).Uint64([]intlen{16})",
+				"_, want := random.NewPhilox(" + gxState(deriveState(seed, 42)) + ").Uint64([]intlen{16})",
+			},
+			got:  "got",
+			want: "want",
+			axes: "0",
+		},
+		{
+			// Folding in i derives the same generator as the ith seed of a split.
+			name: "FoldInSplit",
+			vars: []string{
+				"_, got := " + philox + ".FoldIn(3).Uint64([]intlen{16})",
+				"_, want := random.NewPhilox(" + philox + ".Split(5)[3]).Uint64([]intlen{16})",
+			},
+			got:  "got",
+			want: "want",
+			axes: "0",
+		},
+	}
+}
+
+// TestRandomStreams compares the seeds derived by Split and FoldIn to a Go reference.
+func TestRandomStreams(t *testing.T) {
+	var src strings.Builder
+	src.WriteString("package randomtest\n\nimport (\n\t\"num\"\n\t\"xla\"\n\t\"xla/random\"\n)\n\n")
+	for _, test := range randomTests() {
+		src.WriteString(test.source())
+	}
+	fs := fstest.MapFS{
+		"randomtest/random_test.gx": &fstest.MapFile{Data: []byte(src.String())},
+	}
+	session := gxtesting.NewSession(newXLARuntime(t), fs)
+	session.TestFolder(t, "randomtest")
+}
@@ -14,17 +14,41 @@
 package stdlib_test
 
 import (
+	"embed"
 	"testing"
 
+	"github.com/gx-org/xlapjrt/backend"
 	"github.com/gx-org/xlapjrt/plugin"
+	"github.com/gx-org/gx/api"
+	"github.com/gx-org/gx/build/builder"
+	"github.com/gx-org/gx/build/importers"
+	gxstdlib "github.com/gx-org/gx/stdlib"
 	gxtesting "github.com/gx-org/gx/tests/testing"
 	"github.com/gx-org/gx/tests"
 	"github.com/gx-org/xlapjrt/stdlib"
 )
 
+//go:embed testfiles
+var testFS embed.FS
+
+// xlaTests are the folders testing the GX packages specific to the XLA backend.
+var xlaTests = []string{
+	"testfiles/einsum",
+	"testfiles/fft",
+	"testfiles/gather",
+	"testfiles/matmul",
+	"testfiles/precision",
+	"testfiles/primitives",
+	"testfiles/quant",
+	"testfiles/random",
+	"testfiles/segment",
+	"testfiles/transpose",
+	"testfiles/window",
+}
+
 func TestPJRTStdlib(t *testing.T) {
 	bld := tests.StdlibBuilder(stdlib.Stdlib)
-	bck, err := plugin.NewWithBuilder("cpu", bld)
+	bck, err := plugin.NewWithBuilder("cpu", bld, backend.WithShapeChecks())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -33,3 +57,23 @@ func TestPJRTStdlib(t *testing.T) {
 		session.TestFolder(t, path)
 	}
 }
+
+// newXLARuntime returns a runtime importing the GX packages specific to the XLA backend.
+func newXLARuntime(t *testing.T) *api.Runtime {
+	bld := builder.New(importers.NewCacheLoader(
+		gxstdlib.Importer(stdlib.Stdlib),
+		stdlib.Importer(),
+	))
+	rtm, err := plugin.NewWithBuilder("cpu", bld, backend.WithShapeChecks())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rtm
+}
+
+func TestPJRTXLAStdlib(t *testing.T) {
+	session := gxtesting.NewSession(newXLARuntime(t), testFS)
+	for _, path := range xlaTests {
+		session.TestFolder(t, path)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subgraph_test
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gx-org/backend/dtype"
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/api"
+	"github.com/gx-org/gx/api/tracer"
+	"github.com/gx-org/gx/api/values"
+	"github.com/gx-org/gx/build/builder"
+	"github.com/gx-org/gx/build/importers"
+	"github.com/gx-org/gx/build/ir"
+	gxstdlib "github.com/gx-org/gx/stdlib"
+	"github.com/gx-org/xlapjrt/backend"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+	"github.com/gx-org/xlapjrt/plugin"
+	"github.com/gx-org/xlapjrt/stdlib"
+)
+
+var scalar = &shape.Shape{DType: dtype.Float32}
+
+// buildDoubleTwice builds a graph calling a subgraph doubling its argument twice.
+func buildDoubleTwice(t *testing.T, opts ...backend.Option) (*api.Runtime, *pjrtgraph.Graph, ops.Node) {
+	rtm, err := plugin.New("cpu", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := rtm.Backend().NewOps("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := g.Core().Argument("x", scalar, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	double, err := g.Core().Subgraph("double", []*shape.Shape{scalar})
+	if err != nil {
+		t.Fatal(err)
+	}
+	arg, err := double.Core().Argument("a", scalar, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	add := &ast.BinaryExpr{Op: token.ADD}
+	sum, err := double.Core().Binary(add, arg, arg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sg := &ops.Subgraph{Graph: double, Result: ops.OutputNode{Node: sum, Shape: scalar}}
+	// Call the subgraph at two call sites.
+	y, err := g.Core().Call(sg, x)
+	if err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	z, err := g.Core().Call(sg, y)
+	if err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	return rtm, g.(*pjrtgraph.Graph), z
+}
+
+func compile(t *testing.T, rtm *api.Runtime, g *pjrtgraph.Graph, z ops.Node) {
+	dev, err := rtm.Backend().Platform().Device(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := []*ops.OutputNode{{Node: z, Shape: scalar}}
+	if _, err := g.Compile(dev, out, nil, []*shape.Shape{scalar}); err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	rtm, g, z := buildDoubleTwice(t, backend.WithGraphExport(dir))
+	exp := g.Export([]ops.Node{z})
+	// The subgraph is shared by the two calls and only exported once.
+	numSubgraphs := 0
+	for _, node := range exp.Nodes {
+		if node.Op == "Subgraph" {
+			numSubgraphs++
+		}
+	}
+	if numSubgraphs != 1 {
+		t.Errorf("got %d exported subgraphs but want 1:\n%+v", numSubgraphs, exp.Nodes)
+	}
+	compile(t, rtm, g, z)
+	dot, err := os.ReadFile(filepath.Join(dir, "main.0.dot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(dot), `label="main.double"`) {
+		t.Errorf("DOT export does not contain the subgraph cluster:\n%s", dot)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "main.0.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got pjrtgraph.Exported
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Nodes) != len(exp.Nodes) {
+		t.Errorf("JSON export has %d nodes but want %d", len(got.Nodes), len(exp.Nodes))
+	}
+	// Compiling the function again does not overwrite the first export.
+	rtm, g, z = buildDoubleTwice(t, backend.WithGraphExport(dir))
+	compile(t, rtm, g, z)
+	for _, name := range []string{"main.0.dot", "main.0.json", "main.1.dot", "main.1.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("export %s: %v", name, err)
+		}
+	}
+}
+
+const modelSrc = `
+package model
+
+func Double(x float32) float32 {
+	return x * 2
+}
+`
+
+func TestExportPackagePath(t *testing.T) {
+	dir := t.TempDir()
+	bld := builder.New(importers.NewCacheLoader(
+		gxstdlib.Importer(stdlib.Stdlib),
+		stdlib.Importer(),
+	))
+	rtm, err := plugin.NewWithBuilder("cpu", bld, backend.WithGraphExport(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The name of the graph is the fully qualified name of the function,
+	// which includes the path of its package.
+	pkg := bld.NewIncrementalPackage("github.com/gx-org/exporttest/model")
+	if err := pkg.Build(modelSrc); err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	dev, err := rtm.Device(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := values.AtomFloatValue[float32](ir.Float32Type(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := pkg.IR().FindFunc("Double").(*ir.FuncDecl)
+	if _, err := tracer.Trace(dev, fn, nil, []values.Value{x}, nil); err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	prefix := strings.ReplaceAll(fn.FullyQualifiedName(), "/", "_")
+	for _, ext := range []string{".0.dot", ".0.json"} {
+		if _, err := os.Stat(filepath.Join(dir, prefix+ext)); err != nil {
+			t.Errorf("export of %s: %v", fn.FullyQualifiedName(), err)
+		}
+	}
+}
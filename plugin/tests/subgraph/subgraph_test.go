@@ -0,0 +1,191 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subgraph_test
+
+import (
+	"go/ast"
+	"go/token"
+	"testing"
+
+	"github.com/gx-org/backend/dtype"
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/api/tracer"
+	"github.com/gx-org/gx/api/values"
+	"github.com/gx-org/gx/build/builder"
+	"github.com/gx-org/gx/build/importers"
+	"github.com/gx-org/gx/build/ir"
+	gxstdlib "github.com/gx-org/gx/stdlib"
+	"github.com/gx-org/xlapjrt/backend"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+	"github.com/gx-org/xlapjrt/plugin"
+	"github.com/gx-org/xlapjrt/stdlib"
+)
+
+func TestSubcomputationReuse(t *testing.T) {
+	rtm, err := plugin.New("cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := rtm.Backend().NewOps("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scalar := &shape.Shape{DType: dtype.Float32}
+	x, err := g.Core().Argument("x", scalar, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Build a subgraph doubling its argument.
+	double, err := g.Core().Subgraph("double", []*shape.Shape{scalar})
+	if err != nil {
+		t.Fatal(err)
+	}
+	arg, err := double.Core().Argument("a", scalar, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	add := &ast.BinaryExpr{Op: token.ADD}
+	sum, err := double.Core().Binary(add, arg, arg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sg := &ops.Subgraph{Graph: double, Result: ops.OutputNode{Node: sum, Shape: scalar}}
+	// Call the subgraph at two call sites.
+	y, err := g.Core().Call(sg, x)
+	if err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	z, err := g.Core().Call(sg, y)
+	if err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	want := pjrtgraph.SubcomputationStats{Built: 1, Reused: 1}
+	if got := g.(*pjrtgraph.Graph).SubcomputationStats(); got != want {
+		t.Errorf("subcomputation statistics: got %+v but want %+v", got, want)
+	}
+	dev, err := rtm.Backend().Platform().Device(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := []*ops.OutputNode{{Node: z, Shape: scalar}}
+	if _, err := g.Compile(dev, out, nil, []*shape.Shape{scalar}); err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+}
+
+func TestSubcomputationIgnoringArguments(t *testing.T) {
+	rtm, err := plugin.New("cpu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := rtm.Backend().NewOps("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scalar := &shape.Shape{DType: dtype.Float32}
+	params := []*shape.Shape{
+		{DType: dtype.Float32, AxisLengths: []int{3}},
+		{DType: dtype.Float32, AxisLengths: []int{5}},
+	}
+	// Call, for each parameter, a subgraph returning 1 regardless of its argument.
+	// The subgraphs only differ by the shape of their argument.
+	var results []ops.Node
+	for i, param := range params {
+		x, err := g.Core().Argument("x", param, i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		one, err := g.Core().Subgraph("one", []*shape.Shape{param})
+		if err != nil {
+			t.Fatal(err)
+		}
+		cst, err := one.(*pjrtgraph.Graph).Scalar(1, dtype.Float32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sg := &ops.Subgraph{Graph: one, Result: ops.OutputNode{Node: cst, Shape: scalar}}
+		result, err := g.Core().Call(sg, x)
+		if err != nil {
+			t.Fatalf("\n%+v", err)
+		}
+		results = append(results, result)
+	}
+	want := pjrtgraph.SubcomputationStats{Built: 2, Reused: 0}
+	if got := g.(*pjrtgraph.Graph).SubcomputationStats(); got != want {
+		t.Errorf("subcomputation statistics: got %+v but want %+v", got, want)
+	}
+	dev, err := rtm.Backend().Platform().Device(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := []*ops.OutputNode{{Node: results[0], Shape: scalar}, {Node: results[1], Shape: scalar}}
+	if _, err := g.Compile(dev, out, nil, params); err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+}
+
+const src = `
+package subgraphtest
+
+import "control"
+
+func countTo10(x int32) int32 {
+	return control.While(x,
+		func(s int32) bool { return s < 10 },
+		func(s int32) int32 { return s + 1 })
+}
+
+func CountTwice(x int32) int32 {
+	return countTo10(x) + countTo10(2*x)
+}
+`
+
+func TestSubcomputationReuseAcrossCallSites(t *testing.T) {
+	bld := builder.New(importers.NewCacheLoader(
+		gxstdlib.Importer(stdlib.Stdlib),
+		stdlib.Importer(),
+	))
+	rtm, err := plugin.NewWithBuilder("cpu", bld)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := bld.NewIncrementalPackage("subgraphtest")
+	if err := pkg.Build(src); err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	dev, err := rtm.Device(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := values.AtomIntegerValue[int32](ir.Int32Type(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := pkg.IR().FindFunc("CountTwice").(*ir.FuncDecl)
+	if _, err := tracer.Trace(dev, fn, nil, []values.Value{x}, nil); err != nil {
+		t.Fatalf("\n%+v", err)
+	}
+	analyses := rtm.Backend().(backend.Analyzer).Analyses()
+	if len(analyses) != 1 {
+		t.Fatalf("got %d analyses but want 1", len(analyses))
+	}
+	// countTo10 is inlined twice: the condition and body of the second loop
+	// reuse the subcomputations built for the first loop.
+	got := analyses[0]
+	if got.NumSubcomputations != 2 || got.NumReusedSubcomputations != 2 {
+		t.Errorf("got %d subcomputations built and %d reused but want 2 and 2", got.NumSubcomputations, got.NumReusedSubcomputations)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdlib
+
+import (
+	"go/ast"
+	"go/token"
+	"math/big"
+	"strconv"
+
+	"github.com/gx-org/gx/build/builtins"
+	"github.com/gx-org/gx/build/fmterr"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/interp/elements"
+	"github.com/gx-org/gx/interp/evaluator"
+	"github.com/gx-org/gx/interp/fun"
+	"github.com/gx-org/gx/interp"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+)
+
+// withPosition returns a builtin setting the position of its call
+// in the errors returned by the graph when an operation cannot be built.
+func withPosition(f interp.FuncBuiltin) interp.FuncBuiltin {
+	return func(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+		out, err := f(env, call, fn, irFunc, args)
+		if err != nil {
+			return nil, pjrtgraph.AtPosition(call.FSet(), call.Source(), err)
+		}
+		return out, nil
+	}
+}
+
+// funcType returns the type of a builtin function given the type of its parameters and results.
+func funcType(call *ir.CallExpr, params []ir.Type, results ...ir.Type) *ir.FuncType {
+	return &ir.FuncType{
+		BaseType: ir.BaseType[*ast.FuncType]{Src: &ast.FuncType{Func: call.Source().Pos()}},
+		Params:   builtins.Fields(call, params...),
+		Results:  builtins.Fields(call, results...),
+	}
+}
+
+// staticInts evaluates at compile time a slice of integers passed as an argument to a builtin.
+func staticInts(fetcher ir.Fetcher, call *ir.CallExpr, name string, argIndex int) ([]int, error) {
+	arg := call.Args[argIndex]
+	el, err := fetcher.EvalExpr(arg)
+	if err != nil {
+		return nil, err
+	}
+	vals, err := elements.AxesFromElement(el)
+	if err != nil {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), arg.Source(), "argument %d in call to %s must be known at compile time: %v", argIndex+1, name, err)
+	}
+	return vals, nil
+}
+
+// axisValues returns the expressions of the axis lengths of an array.
+// It returns an error if the number of axes of the array is unknown.
+func axisValues(fetcher ir.Fetcher, call *ir.CallExpr, name string, typ ir.ArrayType) ([]ir.AssignableExpr, error) {
+	axes := typ.Rank().Axes()
+	vals := make([]ir.AssignableExpr, len(axes))
+	for i, ax := range axes {
+		val := ax.AxisValue()
+		if val == nil || val.Type().Kind() == ir.SliceKind {
+			return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "cannot use %s in call to %s: the number of axes must be known at compile time", typ.String(), name)
+		}
+		vals[i] = val
+	}
+	return vals, nil
+}
+
+// intLen returns an axis length expression for a constant integer.
+func intLen(call *ir.CallExpr, val int) ir.AssignableExpr {
+	return &ir.NumberCastExpr{
+		X: &ir.NumberInt{
+			Src: &ast.BasicLit{ValuePos: call.Source().Pos(), Kind: token.INT, Value: strconv.Itoa(val)},
+			Val: big.NewInt(int64(val)),
+		},
+		Typ: ir.IntLenType(),
+	}
+}
+
+// axisLength returns an axis length computed from an expression.
+func axisLength(call *ir.CallExpr, x ir.AssignableExpr) ir.AxisLengths {
+	return &ir.AxisExpr{Src: call.Expr(), X: x}
+}
+
+// arrayType returns an array type given a data type and the expressions of its axis lengths.
+func arrayType(call *ir.CallExpr, dtype ir.Type, axes []ir.AssignableExpr) ir.ArrayType {
+	ax := make([]ir.AxisLengths, len(axes))
+	for i, val := range axes {
+		ax[i] = axisLength(call, val)
+	}
+	return ir.NewArrayType(&ast.ArrayType{}, dtype, &ir.Rank{Ax: ax})
+}
+
+// broadcastParams infers the types of n arguments, starting at first, of an element-wise builtin.
+// Numbers are converted to the data type of the other arguments.
+// Like GX binary operators, arrays need to have the same axis lengths and scalars are broadcast.
+// It returns the parameter types, their data type, and the type of a non-scalar argument
+// (the type of the first argument if all arguments are scalars).
+func broadcastParams(fetcher ir.Fetcher, call *ir.CallExpr, name string, first, n int) ([]ir.Type, ir.Type, ir.Type, error) {
+	var dtype ir.Type
+	for i := first; i < first+n; i++ {
+		if ir.IsNumber(call.Args[i].Type().Kind()) {
+			continue
+		}
+		var err error
+		if _, dtype, err = builtins.InferFromNumericalType(fetcher, call, i, nil); err != nil {
+			return nil, nil, nil, err
+		}
+		break
+	}
+	params := make([]ir.Type, n)
+	var shaped ir.Type
+	for i := range n {
+		typ, argDType, err := builtins.InferFromNumericalType(fetcher, call, first+i, dtype)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if dtype == nil {
+			dtype = argDType
+		}
+		if eq, err := dtype.Equal(fetcher, argDType); err != nil || !eq {
+			return nil, nil, nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "mismatch types %s and %s in call to %s", dtype.String(), argDType.String(), name)
+		}
+		params[i] = typ
+		if arr, ok := typ.(ir.ArrayType); !ok || arr.Rank().IsAtomic() {
+			continue
+		}
+		if shaped == nil {
+			shaped = typ
+			continue
+		}
+		if eq, err := shaped.Equal(fetcher, typ); err != nil || !eq {
+			return nil, nil, nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "mismatch types %s and %s in call to %s", shaped.String(), typ.String(), name)
+		}
+	}
+	if shaped == nil {
+		shaped = params[0]
+	}
+	return params, dtype, shaped, nil
+}
+
+// staticString evaluates at compile time a string passed as an argument to a builtin.
+func staticString(fetcher ir.Fetcher, call *ir.CallExpr, name string, argIndex int) (string, error) {
+	arg := call.Args[argIndex]
+	el, err := fetcher.EvalExpr(arg)
+	if err != nil {
+		return "", err
+	}
+	str, err := elements.StringFromElement(el)
+	if err != nil {
+		return "", fmterr.Errorf(fetcher.File().FileSet(), arg.Source(), "argument %d in call to %s must be a string known at compile time: %v", argIndex+1, name, err)
+	}
+	return str, nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdlib
+
+import (
+	"go/token"
+
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/gx/build/builtins"
+	"github.com/gx-org/gx/build/fmterr"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/interp/elements"
+	"github.com/gx-org/gx/interp/evaluator"
+	"github.com/gx-org/gx/interp/fun"
+	"github.com/gx-org/gx/interp"
+	"github.com/gx-org/gx/interp/materialise"
+	"github.com/gx-org/gx/stdlib/builtin"
+	"github.com/gx-org/gx/stdlib/impl"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+)
+
+// complexPartType returns the type of the real or imaginary part of a complex array.
+func complexPartType(fetcher ir.Fetcher, call *ir.CallExpr, name string, param ir.Type) (ir.ArrayType, error) {
+	typ, err := builtins.NarrowType[ir.ArrayType](fetcher, call, param)
+	if err != nil {
+		return nil, err
+	}
+	if kind := typ.DataType().Kind(); kind != ir.Float32Kind && kind != ir.Float64Kind {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "cannot use %s in call to %s: only float32 and float64 are supported", typ.String(), name)
+	}
+	if _, err := axisValues(fetcher, call, name, typ); err != nil {
+		return nil, err
+	}
+	return typ, nil
+}
+
+// complexParts returns the type shared by the real and imaginary parts of complex arrays.
+func complexParts(fetcher ir.Fetcher, call *ir.CallExpr, name string, params []ir.Type) (ir.ArrayType, error) {
+	typ, err := complexPartType(fetcher, call, name, params[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, param := range params[1:] {
+		other, err := complexPartType(fetcher, call, name, param)
+		if err != nil {
+			return nil, err
+		}
+		if eq, err := typ.Equal(fetcher, other); err != nil || !eq {
+			return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "mismatched types %s and %s in call to %s: the real and imaginary parts of complex arrays must have the same type", typ.String(), other.String(), name)
+		}
+	}
+	return typ, nil
+}
+
+// buildComplexType returns the type of a complex builtin taking numArgs arrays
+// and returning numResults arrays, all of the same type.
+func buildComplexType(fetcher ir.Fetcher, call *ir.CallExpr, name string, numArgs, numResults int) (*ir.FuncType, error) {
+	paramTypes := make([]ir.Type, numArgs)
+	for i := range paramTypes {
+		paramTypes[i] = builtins.GenericArrayType
+	}
+	params, err := builtins.BuildFuncParams(fetcher, call, name, paramTypes)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := complexParts(fetcher, call, name, params)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]ir.Type, numResults)
+	for i := range results {
+		results[i] = typ
+	}
+	return funcType(call, params, results...), nil
+}
+
+func evalComplexBinary(op token.Token) interp.FuncBuiltin {
+	return func(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+		mat := builtin.Materialiser(env)
+		parts, err := materialise.AllWithShapes(mat, args)
+		if err != nil {
+			return nil, err
+		}
+		re, im, err := pjrtGraph(env).ComplexBinary(op, parts[0].Node, parts[1].Node, parts[2].Node, parts[3].Node)
+		if err != nil {
+			return nil, err
+		}
+		return resultElements(env, call,
+			&ops.OutputNode{Node: re, Shape: parts[0].Shape},
+			&ops.OutputNode{Node: im, Shape: parts[0].Shape},
+		)
+	}
+}
+
+type complexMul struct {
+	builtin.Func
+}
+
+func (f complexMul) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[complexMul]("Mul", withPosition(evalComplexBinary(token.MUL)), pkg), nil
+}
+
+func (f complexMul) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildComplexType(fetcher, call, f.Name(), 4, 2)
+}
+
+type complexDiv struct {
+	builtin.Func
+}
+
+func (f complexDiv) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[complexDiv]("Div", withPosition(evalComplexBinary(token.QUO)), pkg), nil
+}
+
+func (f complexDiv) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildComplexType(fetcher, call, f.Name(), 4, 2)
+}
+
+type conj struct {
+	builtin.Func
+}
+
+func (f conj) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[conj]("Conj", withPosition(evalConj), pkg), nil
+}
+
+func (f conj) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildComplexType(fetcher, call, f.Name(), 2, 2)
+}
+
+func evalConj(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	parts, err := materialise.AllWithShapes(mat, args)
+	if err != nil {
+		return nil, err
+	}
+	re, im, err := pjrtGraph(env).Conj(parts[0].Node, parts[1].Node)
+	if err != nil {
+		return nil, err
+	}
+	return resultElements(env, call,
+		&ops.OutputNode{Node: re, Shape: parts[0].Shape},
+		&ops.OutputNode{Node: im, Shape: parts[0].Shape},
+	)
+}
+
+// evalComplexToReal returns the implementation of a builtin computing a real array from a complex array.
+func evalComplexToReal(f func(*pjrtgraph.Graph, ops.Node, ops.Node) (ops.Node, error)) interp.FuncBuiltin {
+	return func(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+		mat := builtin.Materialiser(env)
+		parts, err := materialise.AllWithShapes(mat, args)
+		if err != nil {
+			return nil, err
+		}
+		node, err := f(pjrtGraph(env), parts[0].Node, parts[1].Node)
+		if err != nil {
+			return nil, err
+		}
+		out, err := outputNode(pjrtGraph(env), node, parts[0].Shape)
+		if err != nil {
+			return nil, err
+		}
+		return mat.ElementsFromNodes(call.File(), call.Node(), out)
+	}
+}
+
+type complexAbs struct {
+	builtin.Func
+}
+
+func (f complexAbs) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[complexAbs]("Abs", withPosition(evalComplexToReal((*pjrtgraph.Graph).ComplexAbs)), pkg), nil
+}
+
+func (f complexAbs) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildComplexType(fetcher, call, f.Name(), 2, 1)
+}
+
+type complexAngle struct {
+	builtin.Func
+}
+
+func (f complexAngle) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[complexAngle]("Angle", withPosition(evalComplexToReal((*pjrtgraph.Graph).ComplexAngle)), pkg), nil
+}
+
+func (f complexAngle) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildComplexType(fetcher, call, f.Name(), 2, 1)
+}
@@ -0,0 +1,437 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdlib
+
+import (
+	"fmt"
+	"go/ast"
+	"slices"
+
+	"github.com/gx-org/backend/dtype"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/build/builtins"
+	"github.com/gx-org/gx/build/fmterr"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/interp/elements"
+	"github.com/gx-org/gx/interp/evaluator"
+	"github.com/gx-org/gx/interp/fun"
+	"github.com/gx-org/gx/interp/materialise"
+	"github.com/gx-org/gx/stdlib/builtin"
+	"github.com/gx-org/gx/stdlib/impl"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+	pjrtplatform "github.com/gx-org/xlapjrt/backend/platform"
+)
+
+var precisions = map[string]pjrtgraph.Precision{
+	"default": pjrtgraph.DefaultPrecision,
+	"high":    pjrtgraph.HighPrecision,
+}
+
+// highestPrecision is the name of the XLA HIGHEST precision.
+// It is accepted as a precision but not supported: gopjrt does not expose
+// the XLA precision configuration of dot products.
+const highestPrecision = "highest"
+
+// parseDotConfig returns the configuration of a dot product given a precision
+// ("default", "high", or "highest") and the name of the output data type
+// (an empty name keeps the data type of the operands).
+// The operands are converted to the output data type before the product is computed.
+func parseDotConfig(precision, outputType string) (*pjrtgraph.DotConfig, error) {
+	if precision == highestPrecision {
+		return nil, pjrtplatform.NewError(pjrtplatform.ErrUnsupported, nil, "precision %q: gopjrt does not expose the XLA precision configuration of dot products", precision)
+	}
+	cfg := &pjrtgraph.DotConfig{}
+	var ok bool
+	if cfg.Precision, ok = precisions[precision]; !ok {
+		return nil, fmt.Errorf("invalid precision %q: must be one of default, high, or highest", precision)
+	}
+	if outputType == "" {
+		return cfg, nil
+	}
+	cfg.OutputType = ir.KindFromString(outputType).DType()
+	if !dtype.IsAlgebra(cfg.OutputType) {
+		return nil, fmt.Errorf("invalid output data type %q", outputType)
+	}
+	return cfg, nil
+}
+
+// dotConfigFromArgs builds a dot product configuration at compile time
+// and returns the data type of the result.
+func dotConfigFromArgs(fetcher ir.Fetcher, call *ir.CallExpr, name string, argIndex int, operandType ir.Type) (ir.Type, error) {
+	precision, err := staticString(fetcher, call, name, argIndex)
+	if err != nil {
+		return nil, err
+	}
+	outputType, err := staticString(fetcher, call, name, argIndex+1)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := parseDotConfig(precision, outputType)
+	if err != nil {
+		return nil, fmterr.Position(fetcher.File().FileSet(), call.Source(), fmt.Errorf("invalid configuration in call to %s: %w", name, err))
+	}
+	if cfg.OutputType == dtype.Invalid {
+		return operandType, nil
+	}
+	return ir.TypeFromKind(ir.Kind(cfg.OutputType)), nil
+}
+
+// evalDotConfig returns the configuration of a dot product from the arguments passed to a builtin
+// and the data type of the result.
+func evalDotConfig(args []ir.Element, argIndex int, operandType dtype.DataType) (*pjrtgraph.DotConfig, dtype.DataType, error) {
+	if len(args) <= argIndex {
+		return nil, operandType, nil
+	}
+	precision, err := elements.StringFromElement(args[argIndex])
+	if err != nil {
+		return nil, dtype.Invalid, err
+	}
+	outputType, err := elements.StringFromElement(args[argIndex+1])
+	if err != nil {
+		return nil, dtype.Invalid, err
+	}
+	cfg, err := parseDotConfig(precision, outputType)
+	if err != nil {
+		return nil, dtype.Invalid, err
+	}
+	if cfg.OutputType == dtype.Invalid {
+		return cfg, operandType, nil
+	}
+	return cfg, cfg.OutputType, nil
+}
+
+// checkSameDataType returns an error if two arrays have different data types.
+func checkSameDataType(fetcher ir.Fetcher, call *ir.CallExpr, name string, x, y ir.ArrayType) error {
+	if x.DataType().Kind() != y.DataType().Kind() {
+		return fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "mismatched argument types %s and %s in call to %s", x.DataType().String(), y.DataType().String(), name)
+	}
+	return nil
+}
+
+// broadcastBatchAxes returns the batch axes broadcasting the batch axes of two operands.
+// Batch axes are aligned on the last axis. Missing axes or axes of length 1 are broadcast.
+func broadcastBatchAxes(fetcher ir.Fetcher, call *ir.CallExpr, name string, x, y []ir.AxisLengths) ([]ir.AxisLengths, error) {
+	batch := make([]ir.AxisLengths, max(len(x), len(y)))
+	for i := range batch {
+		var xi, yi ir.AxisLengths
+		if j := len(x) - len(batch) + i; j >= 0 {
+			xi = x[j]
+		}
+		if j := len(y) - len(batch) + i; j >= 0 {
+			yi = y[j]
+		}
+		switch {
+		case xi == nil:
+			batch[i] = yi
+		case yi == nil:
+			batch[i] = xi
+		case isStaticOne(fetcher, yi):
+			batch[i] = xi
+		case isStaticOne(fetcher, xi):
+			batch[i] = yi
+		default:
+			ok, err := xi.AssignableTo(fetcher, yi)
+			if err != nil {
+				return nil, fmterr.Position(fetcher.File().FileSet(), call.Source(), err)
+			}
+			if !ok {
+				return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "batch axes %s and %s cannot be broadcast in call to %s", xi.String(), yi.String(), name)
+			}
+			batch[i] = xi
+		}
+	}
+	return batch, nil
+}
+
+func isStaticOne(fetcher ir.Fetcher, ax ir.AxisLengths) bool {
+	val := ax.AxisValue()
+	if val == nil {
+		return false
+	}
+	length, err := elements.EvalInt(fetcher, val)
+	return err == nil && length == 1
+}
+
+// buildMatMulType returns the type of a batched matrix multiplication.
+// If withConfig is true, the precision and the output data type are passed as arguments.
+func buildMatMulType(fetcher ir.Fetcher, call *ir.CallExpr, name string, withConfig bool) (*ir.FuncType, error) {
+	sig := []ir.Type{builtins.GenericArrayType, builtins.GenericArrayType}
+	if withConfig {
+		sig = append(sig, ir.StringType(), ir.StringType())
+	}
+	params, err := builtins.BuildFuncParams(fetcher, call, name, sig)
+	if err != nil {
+		return nil, err
+	}
+	arrays, err := builtins.NarrowTypes[ir.ArrayType](fetcher, call, params[:2])
+	if err != nil {
+		return nil, err
+	}
+	x, y := arrays[0], arrays[1]
+	if err := checkSameDataType(fetcher, call, name, x, y); err != nil {
+		return nil, err
+	}
+	outType := x.DataType()
+	if withConfig {
+		if outType, err = dotConfigFromArgs(fetcher, call, name, 2, outType); err != nil {
+			return nil, err
+		}
+	}
+	for _, array := range arrays {
+		if _, err := axisValues(fetcher, call, name, array); err != nil {
+			return nil, err
+		}
+	}
+	xAxes, yAxes := x.Rank().Axes(), y.Rank().Axes()
+	if len(xAxes) == 0 || len(yAxes) == 0 {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "cannot use scalars in call to %s", name)
+	}
+	yContracting := yAxes[0]
+	if len(yAxes) > 1 {
+		yContracting = yAxes[len(yAxes)-2]
+	}
+	ok, err := xAxes[len(xAxes)-1].AssignableTo(fetcher, yContracting)
+	if err != nil {
+		return nil, fmterr.Position(fetcher.File().FileSet(), call.Source(), err)
+	}
+	if !ok {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "left argument (shape: %v) not compatible with right argument (shape: %v) in call to %s", x.Rank(), y.Rank(), name)
+	}
+	var xBatch, yBatch []ir.AxisLengths
+	if len(xAxes) > 2 {
+		xBatch = xAxes[:len(xAxes)-2]
+	}
+	if len(yAxes) > 2 {
+		yBatch = yAxes[:len(yAxes)-2]
+	}
+	outAxes, err := broadcastBatchAxes(fetcher, call, name, xBatch, yBatch)
+	if err != nil {
+		return nil, err
+	}
+	if len(xAxes) > 1 {
+		outAxes = append(outAxes, xAxes[len(xAxes)-2])
+	}
+	if len(yAxes) > 1 {
+		outAxes = append(outAxes, yAxes[len(yAxes)-1])
+	}
+	return funcType(call, params, ir.NewArrayType(&ast.ArrayType{}, outType, &ir.Rank{Ax: outAxes})), nil
+}
+
+func evalMatMul(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	x, xShape, err := materialise.Element(mat, args[0])
+	if err != nil {
+		return nil, err
+	}
+	y, yShape, err := materialise.Element(mat, args[1])
+	if err != nil {
+		return nil, err
+	}
+	cfg, outType, err := evalDotConfig(args, 2, xShape.DType)
+	if err != nil {
+		return nil, err
+	}
+	lengths, err := pjrtgraph.MatMulAxisLengths(xShape.AxisLengths, yShape.AxisLengths)
+	if err != nil {
+		return nil, err
+	}
+	node, err := pjrtGraph(env).MatMul(x, y, cfg)
+	if err != nil {
+		return nil, err
+	}
+	out, err := outputNode(pjrtGraph(env), node, &shape.Shape{
+		DType:       outType,
+		AxisLengths: lengths,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
+}
+
+// dotGeneralAxes returns the axes of the result of a dot product:
+// batch axes, followed by the free axes of x, followed by the free axes of y.
+func dotGeneralAxes[T any](x, y []T, batchAxes, reduceAxes [2][]int) []T {
+	var out []T
+	for _, axis := range batchAxes[0] {
+		out = append(out, x[axis])
+	}
+	for i, axes := range [2][]T{x, y} {
+		for axis, length := range axes {
+			if slices.Contains(batchAxes[i], axis) || slices.Contains(reduceAxes[i], axis) {
+				continue
+			}
+			out = append(out, length)
+		}
+	}
+	return out
+}
+
+// checkDotGeneralAxes checks that the axes of the operands of a dot product are valid.
+func checkDotGeneralAxes(ranks [2]int, batchAxes, reduceAxes [2][]int) error {
+	if len(batchAxes[0]) != len(batchAxes[1]) {
+		return fmt.Errorf("got %d and %d batch axes", len(batchAxes[0]), len(batchAxes[1]))
+	}
+	if len(reduceAxes[0]) != len(reduceAxes[1]) {
+		return fmt.Errorf("got %d and %d contracting axes", len(reduceAxes[0]), len(reduceAxes[1]))
+	}
+	for i, rank := range ranks {
+		seen := make(map[int]bool)
+		for _, axis := range append(slices.Clone(batchAxes[i]), reduceAxes[i]...) {
+			if axis < 0 || axis >= rank {
+				return fmt.Errorf("axis %d is out of bounds for operand %d of rank %d", axis, i, rank)
+			}
+			if seen[axis] {
+				return fmt.Errorf("axis %d of operand %d is specified more than once: axes may only be contracted or batched once", axis, i)
+			}
+			seen[axis] = true
+		}
+	}
+	return nil
+}
+
+// dotGeneralArgs returns the batch and reduce axes passed to DotGeneral.
+func dotGeneralArgs(ints func(int) ([]int, error)) (batchAxes, reduceAxes [2][]int, err error) {
+	for i, argIndex := range [2]int{1, 4} {
+		if reduceAxes[i], err = ints(argIndex); err != nil {
+			return
+		}
+		if batchAxes[i], err = ints(argIndex + 1); err != nil {
+			return
+		}
+	}
+	return
+}
+
+type batchedMatMul struct {
+	builtin.Func
+}
+
+func (f batchedMatMul) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[batchedMatMul]("MatMul", withPosition(evalMatMul), pkg), nil
+}
+
+func (f batchedMatMul) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildMatMulType(fetcher, call, f.Name(), false)
+}
+
+type matMulWithConfig struct {
+	builtin.Func
+}
+
+func (f matMulWithConfig) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[matMulWithConfig]("MatMulWithConfig", withPosition(evalMatMul), pkg), nil
+}
+
+func (f matMulWithConfig) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildMatMulType(fetcher, call, f.Name(), true)
+}
+
+type dotGeneral struct {
+	builtin.Func
+}
+
+func (f dotGeneral) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[dotGeneral]("DotGeneral", withPosition(evalDotGeneral), pkg), nil
+}
+
+func (f dotGeneral) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	params, err := builtins.BuildFuncParams(fetcher, call, f.Name(), []ir.Type{
+		builtins.GenericArrayType,
+		ir.IntIndexSliceType(),
+		ir.IntIndexSliceType(),
+		builtins.GenericArrayType,
+		ir.IntIndexSliceType(),
+		ir.IntIndexSliceType(),
+		ir.StringType(),
+		ir.StringType(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	x, err := builtins.NarrowType[ir.ArrayType](fetcher, call, params[0])
+	if err != nil {
+		return nil, err
+	}
+	y, err := builtins.NarrowType[ir.ArrayType](fetcher, call, params[3])
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSameDataType(fetcher, call, f.Name(), x, y); err != nil {
+		return nil, err
+	}
+	outType, err := dotConfigFromArgs(fetcher, call, f.Name(), 6, x.DataType())
+	if err != nil {
+		return nil, err
+	}
+	batchAxes, reduceAxes, err := dotGeneralArgs(func(i int) ([]int, error) {
+		return staticInts(fetcher, call, f.Name(), i)
+	})
+	if err != nil {
+		return nil, err
+	}
+	xAxes, yAxes := x.Rank().Axes(), y.Rank().Axes()
+	if err := checkDotGeneralAxes([2]int{len(xAxes), len(yAxes)}, batchAxes, reduceAxes); err != nil {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "invalid axes in call to %s: %v", f.Name(), err)
+	}
+	for _, axes := range [][2][]int{batchAxes, reduceAxes} {
+		for i := range axes[0] {
+			xi, yi := xAxes[axes[0][i]], yAxes[axes[1][i]]
+			ok, err := xi.AssignableTo(fetcher, yi)
+			if err != nil {
+				return nil, fmterr.Position(fetcher.File().FileSet(), call.Source(), err)
+			}
+			if !ok {
+				return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "axis %d of the left argument (shape: %v) not compatible with axis %d of the right argument (shape: %v) in call to %s", axes[0][i], x.Rank(), axes[1][i], y.Rank(), f.Name())
+			}
+		}
+	}
+	outAxes := dotGeneralAxes(xAxes, yAxes, batchAxes, reduceAxes)
+	return funcType(call, params, ir.NewArrayType(&ast.ArrayType{}, outType, &ir.Rank{Ax: outAxes})), nil
+}
+
+func evalDotGeneral(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	x, xShape, err := materialise.Element(mat, args[0])
+	if err != nil {
+		return nil, err
+	}
+	y, yShape, err := materialise.Element(mat, args[3])
+	if err != nil {
+		return nil, err
+	}
+	batchAxes, reduceAxes, err := dotGeneralArgs(func(i int) ([]int, error) {
+		return elements.AxesFromElement(args[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+	cfg, outType, err := evalDotConfig(args, 6, xShape.DType)
+	if err != nil {
+		return nil, err
+	}
+	node, err := pjrtGraph(env).DotGeneralWithConfig(x, y, batchAxes, reduceAxes, cfg)
+	if err != nil {
+		return nil, err
+	}
+	out, err := outputNode(pjrtGraph(env), node, &shape.Shape{
+		DType:       outType,
+		AxisLengths: dotGeneralAxes(xShape.AxisLengths, yShape.AxisLengths, batchAxes, reduceAxes),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
+}
@@ -16,20 +16,26 @@ package stdlib
 
 import (
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 
-	"github.com/gx-org/backend/ops"
 	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/build/builtins"
+	"github.com/gx-org/gx/build/fmterr"
 	"github.com/gx-org/gx/build/ir"
 	"github.com/gx-org/gx/interp/elements"
 	"github.com/gx-org/gx/interp/evaluator"
 	"github.com/gx-org/gx/interp/fun"
 	"github.com/gx-org/gx/interp/materialise"
 	"github.com/gx-org/gx/stdlib/builtin"
+	"github.com/gx-org/gx/stdlib/impl"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
 )
 
 func evalReinterpret(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
 	mat := builtin.Materialiser(env)
-	argNode, _, err := materialise.Element(mat, args[0])
+	argNode, argShape, err := materialise.Element(mat, args[0])
 	if err != nil {
 		return nil, err
 	}
@@ -39,15 +45,74 @@ func evalReinterpret(env evaluator.Env, call elements.CallAt, fn fun.Func, irFun
 		return nil, fmt.Errorf("%T is not an array type", retType)
 	}
 	dtype := arrayType.DataType().Kind().DType()
+	lengths, err := bitcastAxisLengths(argShape.AxisLengths, argShape.DType, dtype)
+	if err != nil {
+		return nil, err
+	}
 	op, err := pjrtGraph(env).Bitcast(argNode, dtype)
 	if err != nil {
 		return nil, err
 	}
-	return mat.ElementsFromNodes(call.File(), call.Node(), &ops.OutputNode{
-		Node: op,
-		Shape: &shape.Shape{
-			DType:       dtype,
-			AxisLengths: op.(interface{ PJRTDims() []int }).PJRTDims(),
-		},
+	out, err := outputNode(pjrtGraph(env), op, &shape.Shape{
+		DType:       dtype,
+		AxisLengths: lengths,
 	})
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
+}
+
+type roundToPrecision struct {
+	builtin.Func
+}
+
+func (f roundToPrecision) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[roundToPrecision]("RoundToPrecision", withPosition(evalRoundToPrecision), pkg), nil
+}
+
+func (f roundToPrecision) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	if len(call.Args) != 2 {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "wrong number of arguments in call to %s: got %d but want 2", f.Name(), len(call.Args))
+	}
+	typ, dtype, err := builtins.InferFromNumericalType(fetcher, call, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !ir.IsFloat(dtype) {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "cannot use %s in call to %s: only floating-point values can be rounded", typ.String(), f.Name())
+	}
+	format, err := staticString(fetcher, call, f.Name(), 1)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := pjrtgraph.LowPrecisionFloats[format]; !ok {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[1].Source(), "invalid floating-point format %q in call to %s: must be one of %s", format, f.Name(), strings.Join(slices.Sorted(maps.Keys(pjrtgraph.LowPrecisionFloats)), ", "))
+	}
+	return funcType(call, []ir.Type{typ, ir.StringType()}, typ), nil
+}
+
+func evalRoundToPrecision(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	x, xShape, err := materialise.Element(mat, args[0])
+	if err != nil {
+		return nil, err
+	}
+	format, err := elements.StringFromElement(args[1])
+	if err != nil {
+		return nil, err
+	}
+	target, ok := pjrtgraph.LowPrecisionFloats[format]
+	if !ok {
+		return nil, fmt.Errorf("invalid floating-point format %q", format)
+	}
+	node, err := pjrtGraph(env).RoundToPrecision(x, target)
+	if err != nil {
+		return nil, err
+	}
+	out, err := outputNode(pjrtGraph(env), node, xShape)
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
 }
@@ -0,0 +1,180 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdlib
+
+import (
+	"fmt"
+	"go/ast"
+
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/build/builtins"
+	"github.com/gx-org/gx/build/fmterr"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/interp/elements"
+	"github.com/gx-org/gx/interp/evaluator"
+	"github.com/gx-org/gx/interp/fun"
+	"github.com/gx-org/gx/interp"
+	"github.com/gx-org/gx/interp/materialise"
+	"github.com/gx-org/gx/stdlib/builtin"
+	"github.com/gx-org/gx/stdlib/impl"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+)
+
+// einsumFirstOperand returns the index of the first array passed to an einsum builtin.
+func einsumFirstOperand(withConfig bool) int {
+	if withConfig {
+		return 3
+	}
+	return 1
+}
+
+// buildEinsumType returns the type of an einsum given its specification, known at compile time.
+func buildEinsumType(fetcher ir.Fetcher, call *ir.CallExpr, name string, withConfig bool) (*ir.FuncType, error) {
+	first := einsumFirstOperand(withConfig)
+	if len(call.Args) <= first {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "not enough arguments in call to %s: at least one array is required", name)
+	}
+	sig := make([]ir.Type, len(call.Args))
+	for i := range sig {
+		sig[i] = builtins.GenericArrayType
+		if i < first {
+			sig[i] = ir.StringType()
+		}
+	}
+	params, err := builtins.BuildFuncParams(fetcher, call, name, sig)
+	if err != nil {
+		return nil, err
+	}
+	arrays, err := builtins.NarrowTypes[ir.ArrayType](fetcher, call, params[first:])
+	if err != nil {
+		return nil, err
+	}
+	axes := make([][]ir.AxisLengths, len(arrays))
+	ranks := make([]int, len(arrays))
+	for i, array := range arrays {
+		if err := checkSameDataType(fetcher, call, name, arrays[0], array); err != nil {
+			return nil, err
+		}
+		if _, err := axisValues(fetcher, call, name, array); err != nil {
+			return nil, err
+		}
+		axes[i] = array.Rank().Axes()
+		ranks[i] = len(axes[i])
+	}
+	outType := arrays[0].DataType()
+	if withConfig {
+		if outType, err = dotConfigFromArgs(fetcher, call, name, 1, outType); err != nil {
+			return nil, err
+		}
+	}
+	specString, err := staticString(fetcher, call, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := pjrtgraph.ParseEinsum(specString, ranks)
+	if err != nil {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "invalid specification in call to %s: %v", name, err)
+	}
+	for _, label := range spec.Labels() {
+		occurrences := spec.Occurrences(label)
+		want := axes[occurrences[0].Operand][occurrences[0].Axis]
+		for _, occ := range occurrences[1:] {
+			got := axes[occ.Operand][occ.Axis]
+			ok, err := got.AssignableTo(fetcher, want)
+			if err != nil {
+				return nil, fmterr.Position(fetcher.File().FileSet(), call.Source(), err)
+			}
+			if !ok {
+				return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "label %q has incompatible axis lengths %s and %s in call to %s", label, want.String(), got.String(), name)
+			}
+		}
+	}
+	outAxes := pjrtgraph.EinsumOutput(spec, axes)
+	return funcType(call, params, ir.NewArrayType(&ast.ArrayType{}, outType, &ir.Rank{Ax: outAxes})), nil
+}
+
+func evalEinsumSpec(withConfig bool) interp.FuncBuiltin {
+	first := einsumFirstOperand(withConfig)
+	return func(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+		if len(args) <= first {
+			return nil, fmt.Errorf("einsum expects at least %d arguments, got %d", first+1, len(args))
+		}
+		mat := builtin.Materialiser(env)
+		operands, err := materialise.AllWithShapes(mat, args[first:])
+		if err != nil {
+			return nil, err
+		}
+		nodes := make([]ops.Node, len(operands))
+		axes := make([][]int, len(operands))
+		ranks := make([]int, len(operands))
+		for i, operand := range operands {
+			nodes[i] = operand.Node
+			axes[i] = operand.Shape.AxisLengths
+			ranks[i] = len(axes[i])
+		}
+		specString, err := elements.StringFromElement(args[0])
+		if err != nil {
+			return nil, err
+		}
+		spec, err := pjrtgraph.ParseEinsum(specString, ranks)
+		if err != nil {
+			return nil, err
+		}
+		var cfg *pjrtgraph.DotConfig
+		outType := operands[0].Shape.DType
+		if withConfig {
+			if cfg, outType, err = evalDotConfig(args, 1, outType); err != nil {
+				return nil, err
+			}
+		}
+		node, err := pjrtGraph(env).Einsum(spec, nodes, cfg)
+		if err != nil {
+			return nil, err
+		}
+		out, err := outputNode(pjrtGraph(env), node, &shape.Shape{
+			DType:       outType,
+			AxisLengths: pjrtgraph.EinsumOutput(spec, axes),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return mat.ElementsFromNodes(call.File(), call.Node(), out)
+	}
+}
+
+type einsum struct {
+	builtin.Func
+}
+
+func (f einsum) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[einsum]("Einsum", withPosition(evalEinsumSpec(false)), pkg), nil
+}
+
+func (f einsum) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildEinsumType(fetcher, call, f.Name(), false)
+}
+
+type einsumWithConfig struct {
+	builtin.Func
+}
+
+func (f einsumWithConfig) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[einsumWithConfig]("EinsumWithConfig", withPosition(evalEinsumSpec(true)), pkg), nil
+}
+
+func (f einsumWithConfig) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildEinsumType(fetcher, call, f.Name(), true)
+}
@@ -0,0 +1,258 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdlib
+
+import (
+	"slices"
+
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/build/builtins"
+	"github.com/gx-org/gx/build/fmterr"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/interp/elements"
+	"github.com/gx-org/gx/interp/evaluator"
+	"github.com/gx-org/gx/interp/fun"
+	"github.com/gx-org/gx/interp"
+	"github.com/gx-org/gx/interp/materialise"
+	"github.com/gx-org/gx/stdlib/builtin"
+	"github.com/gx-org/gx/stdlib/impl"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+)
+
+// fftArray returns the axes of an array transformed by a FFT builtin.
+func fftArray(fetcher ir.Fetcher, call *ir.CallExpr, name string, typ ir.ArrayType, numAxes int) ([]ir.AxisLengths, error) {
+	axes := typ.Rank().Axes()
+	if numAxes > len(axes) {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "cannot transform %d axes of %s in call to %s", numAxes, typ.String(), name)
+	}
+	return axes, nil
+}
+
+// fftNumAxes returns the number of axes to transform, known at compile time.
+func fftNumAxes(fetcher ir.Fetcher, call *ir.CallExpr, name string, argIndex int) (int, error) {
+	arg := call.Args[argIndex]
+	numAxes, err := elements.EvalInt(fetcher, arg)
+	if err != nil {
+		return 0, fmterr.Errorf(fetcher.File().FileSet(), arg.Source(), "argument %d in call to %s must be known at compile time: %v", argIndex+1, name, err)
+	}
+	if numAxes < 1 || numAxes > pjrtgraph.MaxFFTAxes {
+		return 0, fmterr.Errorf(fetcher.File().FileSet(), arg.Source(), "invalid number of axes %d in call to %s: must be between 1 and %d", numAxes, name, pjrtgraph.MaxFFTAxes)
+	}
+	return numAxes, nil
+}
+
+func evalFFT(inverse bool) interp.FuncBuiltin {
+	return func(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+		mat := builtin.Materialiser(env)
+		parts, err := materialise.AllWithShapes(mat, args[:2])
+		if err != nil {
+			return nil, err
+		}
+		numAxes, err := elements.ConstantIntFromElement(args[2])
+		if err != nil {
+			return nil, err
+		}
+		re, im, err := pjrtGraph(env).FFT(parts[0].Node, parts[1].Node, numAxes, inverse)
+		if err != nil {
+			return nil, err
+		}
+		return resultElements(env, call,
+			&ops.OutputNode{Node: re, Shape: parts[0].Shape},
+			&ops.OutputNode{Node: im, Shape: parts[0].Shape},
+		)
+	}
+}
+
+// buildFFTType returns the type of FFT and IFFT.
+func buildFFTType(fetcher ir.Fetcher, call *ir.CallExpr, name string) (*ir.FuncType, error) {
+	params, err := builtins.BuildFuncParams(fetcher, call, name, []ir.Type{
+		builtins.GenericArrayType,
+		builtins.GenericArrayType,
+		ir.IntIndexType(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	numAxes, err := fftNumAxes(fetcher, call, name, 2)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := complexParts(fetcher, call, name, params[:2])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fftArray(fetcher, call, name, typ, numAxes); err != nil {
+		return nil, err
+	}
+	return funcType(call, params, typ, typ), nil
+}
+
+type fft struct {
+	builtin.Func
+}
+
+func (f fft) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[fft]("FFT", withPosition(evalFFT(false)), pkg), nil
+}
+
+func (f fft) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildFFTType(fetcher, call, f.Name())
+}
+
+type ifft struct {
+	builtin.Func
+}
+
+func (f ifft) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[ifft]("IFFT", withPosition(evalFFT(true)), pkg), nil
+}
+
+func (f ifft) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildFFTType(fetcher, call, f.Name())
+}
+
+type rfft struct {
+	builtin.Func
+}
+
+func (f rfft) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[rfft]("RFFT", withPosition(evalRFFT), pkg), nil
+}
+
+func (f rfft) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	params, err := builtins.BuildFuncParams(fetcher, call, f.Name(), []ir.Type{
+		builtins.GenericArrayType,
+		ir.IntIndexType(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	numAxes, err := fftNumAxes(fetcher, call, f.Name(), 1)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := complexPartType(fetcher, call, f.Name(), params[0])
+	if err != nil {
+		return nil, err
+	}
+	axes, err := fftArray(fetcher, call, f.Name(), typ, numAxes)
+	if err != nil {
+		return nil, err
+	}
+	last := axes[len(axes)-1]
+	length, err := elements.EvalInt(fetcher, last.AxisValue())
+	if err != nil {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "the length of the last axis of %s must be known at compile time in call to %s", typ.String(), f.Name())
+	}
+	outAxes := append(slices.Clone(axes[:len(axes)-1]), axisLength(call, intLen(call, length/2+1)))
+	out := withAxes(typ, outAxes)
+	return funcType(call, params, out, out), nil
+}
+
+func evalRFFT(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	x, xShape, err := materialise.Element(mat, args[0])
+	if err != nil {
+		return nil, err
+	}
+	numAxes, err := elements.ConstantIntFromElement(args[1])
+	if err != nil {
+		return nil, err
+	}
+	re, im, err := pjrtGraph(env).RFFT(x, numAxes)
+	if err != nil {
+		return nil, err
+	}
+	outShape := &shape.Shape{
+		DType:       xShape.DType,
+		AxisLengths: pjrtgraph.RFFTAxisLengths(xShape.AxisLengths),
+	}
+	return resultElements(env, call,
+		&ops.OutputNode{Node: re, Shape: outShape},
+		&ops.OutputNode{Node: im, Shape: outShape},
+	)
+}
+
+type irfft struct {
+	builtin.Func
+}
+
+func (f irfft) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[irfft]("IRFFT", withPosition(evalIRFFT), pkg), nil
+}
+
+func (f irfft) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	params, err := builtins.BuildFuncParams(fetcher, call, f.Name(), []ir.Type{
+		builtins.GenericArrayType,
+		builtins.GenericArrayType,
+		ir.IntIndexType(),
+		ir.IntLenType(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	numAxes, err := fftNumAxes(fetcher, call, f.Name(), 2)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := complexParts(fetcher, call, f.Name(), params[:2])
+	if err != nil {
+		return nil, err
+	}
+	axes, err := fftArray(fetcher, call, f.Name(), typ, numAxes)
+	if err != nil {
+		return nil, err
+	}
+	length, err := elements.EvalInt(fetcher, call.Args[3])
+	if err != nil {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[3].Source(), "argument 4 in call to %s must be known at compile time: %v", f.Name(), err)
+	}
+	if got, gotErr := elements.EvalInt(fetcher, axes[len(axes)-1].AxisValue()); gotErr == nil && got != length/2+1 {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "cannot compute an inverse real FFT of length %d from %s in call to %s: the last axis must have length %d", length, typ.String(), f.Name(), length/2+1)
+	}
+	outAxes := append(slices.Clone(axes[:len(axes)-1]), axisLength(call, intLen(call, length)))
+	return funcType(call, params, withAxes(typ, outAxes)), nil
+}
+
+func evalIRFFT(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	parts, err := materialise.AllWithShapes(mat, args[:2])
+	if err != nil {
+		return nil, err
+	}
+	numAxes, err := elements.ConstantIntFromElement(args[2])
+	if err != nil {
+		return nil, err
+	}
+	length, err := elements.ConstantIntFromElement(args[3])
+	if err != nil {
+		return nil, err
+	}
+	node, err := pjrtGraph(env).IRFFT(parts[0].Node, parts[1].Node, numAxes, length)
+	if err != nil {
+		return nil, err
+	}
+	lengths := slices.Clone(parts[0].Shape.AxisLengths)
+	lengths[len(lengths)-1] = length
+	out, err := outputNode(pjrtGraph(env), node, &shape.Shape{
+		DType:       parts[0].Shape.DType,
+		AxisLengths: lengths,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
+}
@@ -0,0 +1,408 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdlib
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/build/builtins"
+	"github.com/gx-org/gx/build/fmterr"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/interp/elements"
+	"github.com/gx-org/gx/interp/evaluator"
+	"github.com/gx-org/gx/interp/fun"
+	"github.com/gx-org/gx/interp/materialise"
+	"github.com/gx-org/gx/stdlib/builtin"
+	"github.com/gx-org/gx/stdlib/impl"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+)
+
+// gatherConfigFromArgs returns the configuration of a gather given a function
+// returning the integers of an argument.
+// The arguments are: indexVectorAxis, offsetAxes, collapsedSliceAxes, startIndexMap,
+// sliceSizes, operandBatchingAxes, and indicesBatchingAxes.
+func gatherConfigFromArgs(indexVectorAxis int, ints func(int) ([]int, error)) (*pjrtgraph.GatherConfig, error) {
+	cfg := &pjrtgraph.GatherConfig{IndexVectorAxis: indexVectorAxis}
+	for i, dst := range []*[]int{
+		&cfg.OffsetAxes,
+		&cfg.CollapsedSliceAxes,
+		&cfg.StartIndexMap,
+		&cfg.SliceSizes,
+		&cfg.OperandBatchingAxes,
+		&cfg.IndicesBatchingAxes,
+	} {
+		var err error
+		if *dst, err = ints(3 + i); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+type gather struct {
+	builtin.Func
+}
+
+func (f gather) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[gather]("Gather", withPosition(evalGatherWithConfig), pkg), nil
+}
+
+// BuildFuncType returns the type of Gather:
+//
+//	func(x [operand___]T, indices [indices___]I, indexVectorAxis intidx,
+//		offsetAxes, collapsedSliceAxes, startIndexMap, sliceSizes,
+//		operandBatchingAxes, indicesBatchingAxes []intidx, indicesAreSorted bool) [out___]T
+func (f gather) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	params, err := builtins.BuildFuncParams(fetcher, call, f.Name(), []ir.Type{
+		builtins.GenericArrayType,
+		builtins.GenericArrayType,
+		ir.IntIndexType(),
+		ir.IntIndexSliceType(),
+		ir.IntIndexSliceType(),
+		ir.IntIndexSliceType(),
+		ir.IntIndexSliceType(),
+		ir.IntIndexSliceType(),
+		ir.IntIndexSliceType(),
+		ir.BoolType(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	x, err := builtins.NarrowType[ir.ArrayType](fetcher, call, params[0])
+	if err != nil {
+		return nil, err
+	}
+	xVals, err := axisValues(fetcher, call, f.Name(), x)
+	if err != nil {
+		return nil, err
+	}
+	xLengths, ok := staticAxisLengths(fetcher, xVals)
+	if !ok {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[0].Source(), "cannot use %s in call to %s: axis lengths must be known at compile time", x.String(), f.Name())
+	}
+	indices, err := integerIndices(fetcher, call, f.Name(), params[1], 1)
+	if err != nil {
+		return nil, err
+	}
+	indexVectorAxis, err := elements.EvalInt(fetcher, call.Args[2])
+	if err != nil {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[2].Source(), "argument 3 in call to %s must be known at compile time: %v", f.Name(), err)
+	}
+	cfg, err := gatherConfigFromArgs(indexVectorAxis, func(i int) ([]int, error) {
+		return staticInts(fetcher, call, f.Name(), i)
+	})
+	if err != nil {
+		return nil, err
+	}
+	indicesAxes := indices.Rank().Axes()
+	if err := cfg.Check(len(xLengths), len(indicesAxes)); err != nil {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "invalid configuration in call to %s: %v", f.Name(), err)
+	}
+	if indexVectorAxis < len(indicesAxes) {
+		size, err := elements.EvalInt(fetcher, indicesAxes[indexVectorAxis].AxisValue())
+		if err != nil {
+			return nil, fmterr.Position(fetcher.File().FileSet(), call.Args[1].Source(), err)
+		}
+		if size != len(cfg.StartIndexMap) {
+			return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "invalid configuration in call to %s: got %d axes in the start index map but the index vector has %d elements", f.Name(), len(cfg.StartIndexMap), size)
+		}
+	}
+	for axis, size := range cfg.SliceSizes {
+		if size < 0 || size > xLengths[axis] {
+			return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "invalid slice size %d for axis %d of length %d in call to %s", size, axis, xLengths[axis], f.Name())
+		}
+	}
+	xAxes := x.Rank().Axes()
+	for i, axis := range cfg.OperandBatchingAxes {
+		if err := checkAssignableAxes(fetcher, call, f.Name(), indicesAxes[cfg.IndicesBatchingAxes[i]], xAxes[axis]); err != nil {
+			return nil, err
+		}
+	}
+	outAxes := pjrtgraph.GatherOutputAxes(indicesAxes, cfg, func(axis int) ir.AxisLengths {
+		if cfg.SliceSizes[axis] == xLengths[axis] {
+			return xAxes[axis]
+		}
+		return axisLength(call, intLen(call, cfg.SliceSizes[axis]))
+	})
+	return funcType(call, params, withAxes(x, outAxes)), nil
+}
+
+func evalGatherWithConfig(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	operands, err := materialise.AllWithShapes(mat, args[:2])
+	if err != nil {
+		return nil, err
+	}
+	indexVectorAxis, err := elements.ConstantIntFromElement(args[2])
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := gatherConfigFromArgs(indexVectorAxis, func(i int) ([]int, error) {
+		return elements.AxesFromElement(args[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.IndicesAreSorted, err = elements.ConstantScalarFromElement[bool](args[9]); err != nil {
+		return nil, err
+	}
+	x, indices := operands[0], operands[1]
+	lengths, err := pjrtgraph.GatherAxisLengths(x.Shape.AxisLengths, indices.Shape.AxisLengths, cfg)
+	if err != nil {
+		return nil, err
+	}
+	node, err := pjrtGraph(env).GatherWithConfig(x.Node, indices.Node, cfg)
+	if err != nil {
+		return nil, err
+	}
+	out, err := outputNode(pjrtGraph(env), node, &shape.Shape{
+		DType:       x.Shape.DType,
+		AxisLengths: lengths,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
+}
+
+// takeMode is the behavior of Take and TakeAlongAxis for indices out of bounds.
+type takeMode struct {
+	gather pjrtgraph.GatherMode
+	// checked is true if the indices are checked at compile time.
+	checked bool
+}
+
+var takeModes = map[string]takeMode{
+	"clip":  {gather: pjrtgraph.GatherClip},
+	"fill":  {gather: pjrtgraph.GatherFill},
+	"error": {gather: pjrtgraph.GatherClip, checked: true},
+}
+
+func parseTakeMode(mode string) (takeMode, error) {
+	m, ok := takeModes[mode]
+	if !ok {
+		return takeMode{}, fmt.Errorf("invalid mode %q: must be one of clip, fill, or error", mode)
+	}
+	return m, nil
+}
+
+// literalIndices returns the values of an array literal of indices.
+// It returns false if the values cannot be computed at compile time.
+func literalIndices(fetcher ir.Fetcher, expr ir.Expr) ([]int, bool) {
+	lit, ok := expr.(*ir.ArrayLitExpr)
+	if !ok {
+		val, err := elements.EvalInt(fetcher, expr)
+		if err != nil {
+			return nil, false
+		}
+		return []int{val}, true
+	}
+	var vals []int
+	for _, elt := range lit.Values() {
+		eltVals, ok := literalIndices(fetcher, elt)
+		if !ok {
+			return nil, false
+		}
+		vals = append(vals, eltVals...)
+	}
+	return vals, true
+}
+
+// checkTakeIndices checks at compile time that the indices passed to a take builtin
+// are in the bounds of an axis.
+func checkTakeIndices(fetcher ir.Fetcher, call *ir.CallExpr, name string, axisLength ir.AxisLengths) error {
+	indices := call.Args[1]
+	vals, ok := literalIndices(fetcher, indices)
+	if !ok {
+		return fmterr.Errorf(fetcher.File().FileSet(), indices.Source(), "cannot check the indices in call to %s: indices must be an array literal known at compile time in error mode (use clip or fill instead)", name)
+	}
+	length, err := elements.EvalInt(fetcher, axisLength.AxisValue())
+	if err != nil {
+		return fmterr.Position(fetcher.File().FileSet(), call.Source(), err)
+	}
+	for _, val := range vals {
+		if val < 0 || val >= length {
+			return fmterr.Errorf(fetcher.File().FileSet(), indices.Source(), "index %d out of bounds [0:%d] in call to %s", val, length, name)
+		}
+	}
+	return nil
+}
+
+// buildTakeParams returns the parameters of a take builtin, the array, the indices, and the axis.
+func buildTakeParams(fetcher ir.Fetcher, call *ir.CallExpr, name string) ([]ir.Type, ir.ArrayType, ir.ArrayType, int, error) {
+	params, err := builtins.BuildFuncParams(fetcher, call, name, []ir.Type{
+		builtins.GenericArrayType,
+		builtins.GenericArrayType,
+		ir.IntIndexType(),
+		ir.StringType(),
+	})
+	if err != nil {
+		return nil, nil, nil, 0, err
+	}
+	x, err := builtins.NarrowType[ir.ArrayType](fetcher, call, params[0])
+	if err != nil {
+		return nil, nil, nil, 0, err
+	}
+	xAxes, err := axisValues(fetcher, call, name, x)
+	if err != nil {
+		return nil, nil, nil, 0, err
+	}
+	indices, err := integerIndices(fetcher, call, name, params[1], 1)
+	if err != nil {
+		return nil, nil, nil, 0, err
+	}
+	axis, err := elements.EvalInt(fetcher, call.Args[2])
+	if err != nil {
+		return nil, nil, nil, 0, fmterr.Errorf(fetcher.File().FileSet(), call.Args[2].Source(), "argument 3 in call to %s must be known at compile time: %v", name, err)
+	}
+	if axis < 0 || axis >= len(xAxes) {
+		return nil, nil, nil, 0, fmterr.Errorf(fetcher.File().FileSet(), call.Args[2].Source(), "invalid axis %d in call to %s: must be in [0, %d)", axis, name, len(xAxes))
+	}
+	modeName, err := staticString(fetcher, call, name, 3)
+	if err != nil {
+		return nil, nil, nil, 0, err
+	}
+	mode, err := parseTakeMode(modeName)
+	if err != nil {
+		return nil, nil, nil, 0, fmterr.Errorf(fetcher.File().FileSet(), call.Args[3].Source(), "%v in call to %s", err, name)
+	}
+	if mode.checked {
+		if err := checkTakeIndices(fetcher, call, name, x.Rank().Axes()[axis]); err != nil {
+			return nil, nil, nil, 0, err
+		}
+	}
+	return params, x, indices, axis, nil
+}
+
+// takeArgs returns the operands, the axis, and the mode passed to a take builtin.
+func takeArgs(env evaluator.Env, args []ir.Element) ([]*ops.OutputNode, int, pjrtgraph.GatherMode, error) {
+	operands, err := materialise.AllWithShapes(builtin.Materialiser(env), args[:2])
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	axis, err := elements.ConstantIntFromElement(args[2])
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	modeName, err := elements.StringFromElement(args[3])
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	mode, err := parseTakeMode(modeName)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return operands, axis, mode.gather, nil
+}
+
+type take struct {
+	builtin.Func
+}
+
+func (f take) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[take]("Take", withPosition(evalTake), pkg), nil
+}
+
+// BuildFuncType returns the type of Take:
+//
+//	func(x [before___][n][after___]T, indices [indices___]I, axis intidx, mode string) [before___][indices___][after___]T
+func (f take) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	params, x, indices, axis, err := buildTakeParams(fetcher, call, f.Name())
+	if err != nil {
+		return nil, err
+	}
+	xAxes := x.Rank().Axes()
+	outAxes := slices.Concat(xAxes[:axis], indices.Rank().Axes(), xAxes[axis+1:])
+	return funcType(call, params, withAxes(x, outAxes)), nil
+}
+
+func evalTake(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	operands, axis, mode, err := takeArgs(env, args)
+	if err != nil {
+		return nil, err
+	}
+	x, indices := operands[0], operands[1]
+	cfg := pjrtgraph.TakeConfig(x.Shape.AxisLengths, len(indices.Shape.AxisLengths), axis)
+	lengths, err := pjrtgraph.GatherAxisLengths(x.Shape.AxisLengths, indices.Shape.AxisLengths, cfg)
+	if err != nil {
+		return nil, err
+	}
+	node, err := pjrtGraph(env).Take(x.Node, indices.Node, axis, mode)
+	if err != nil {
+		return nil, err
+	}
+	out, err := outputNode(pjrtGraph(env), node, &shape.Shape{
+		DType:       x.Shape.DType,
+		AxisLengths: lengths,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return builtin.Materialiser(env).ElementsFromNodes(call.File(), call.Node(), out)
+}
+
+type takeAlongAxis struct {
+	builtin.Func
+}
+
+func (f takeAlongAxis) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[takeAlongAxis]("TakeAlongAxis", withPosition(evalTakeAlongAxis), pkg), nil
+}
+
+// BuildFuncType returns the type of TakeAlongAxis:
+//
+//	func(x [before___][n][after___]T, indices [before___][m][after___]I, axis intidx, mode string) [before___][m][after___]T
+func (f takeAlongAxis) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	params, x, indices, axis, err := buildTakeParams(fetcher, call, f.Name())
+	if err != nil {
+		return nil, err
+	}
+	xAxes, indicesAxes := x.Rank().Axes(), indices.Rank().Axes()
+	if len(xAxes) != len(indicesAxes) {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "cannot use indices %s with %s in call to %s: want the same number of axes", indices.String(), x.String(), f.Name())
+	}
+	for i := range xAxes {
+		if i == axis {
+			continue
+		}
+		if err := checkAssignableAxes(fetcher, call, f.Name(), indicesAxes[i], xAxes[i]); err != nil {
+			return nil, err
+		}
+	}
+	return funcType(call, params, withAxes(x, indicesAxes)), nil
+}
+
+func evalTakeAlongAxis(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	operands, axis, mode, err := takeArgs(env, args)
+	if err != nil {
+		return nil, err
+	}
+	x, indices := operands[0], operands[1]
+	node, err := pjrtGraph(env).TakeAlongAxis(x.Node, indices.Node, axis, mode)
+	if err != nil {
+		return nil, err
+	}
+	out, err := outputNode(pjrtGraph(env), node, &shape.Shape{
+		DType:       x.Shape.DType,
+		AxisLengths: slices.Clone(indices.Shape.AxisLengths),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return builtin.Materialiser(env).ElementsFromNodes(call.File(), call.Node(), out)
+}
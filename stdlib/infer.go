@@ -0,0 +1,114 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdlib
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/gx-org/backend/dtype"
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/backend/shape"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+)
+
+// outputNode returns the output of a builtin given its node and the shape inferred from GX shapes.
+// The inferred shape is cross-checked with the shape inferred by XLA if the graph checks shapes
+// (see backend.WithShapeChecks).
+func outputNode(g *pjrtgraph.Graph, node ops.Node, inferred *shape.Shape) (*ops.OutputNode, error) {
+	if g.ShapeChecks() {
+		if err := checkShape(node, inferred); err != nil {
+			return nil, err
+		}
+	}
+	return &ops.OutputNode{Node: node, Shape: inferred}, nil
+}
+
+func checkShape(node ops.Node, inferred *shape.Shape) error {
+	backend, ok := node.(interface{ BackendShape() *shape.Shape })
+	if !ok {
+		return fmt.Errorf("cannot check the inferred shape %s: node %T does not provide a backend shape", inferred, node)
+	}
+	if got := backend.BackendShape(); !got.Equal(inferred) {
+		return fmt.Errorf("shape inference mismatch: GX inferred %s but XLA inferred %s", inferred, got)
+	}
+	return nil
+}
+
+// concatAxisLengths returns the axis lengths of arrays concatenated along an axis.
+func concatAxisLengths(axis int, shapes []*shape.Shape) ([]int, error) {
+	if len(shapes) == 0 {
+		return nil, fmt.Errorf("cannot concatenate an empty list of arrays")
+	}
+	lengths := slices.Clone(shapes[0].AxisLengths)
+	if axis < 0 || axis >= len(lengths) {
+		return nil, fmt.Errorf("invalid concatenation axis %d for arrays with %d axes", axis, len(lengths))
+	}
+	for _, shape := range shapes[1:] {
+		if len(shape.AxisLengths) != len(lengths) {
+			return nil, fmt.Errorf("cannot concatenate arrays with %d and %d axes", len(lengths), len(shape.AxisLengths))
+		}
+		lengths[axis] += shape.AxisLengths[axis]
+	}
+	return lengths, nil
+}
+
+// splitAxisLengths returns the axis lengths of an array split along an axis:
+// a new leading axis of length numSplits is added and the length of the axis is divided by numSplits.
+func splitAxisLengths(lengths []int, axis, numSplits int) ([]int, error) {
+	if axis < 0 || axis >= len(lengths) {
+		return nil, fmt.Errorf("axis %d is out of bounds for rank %d", axis, len(lengths))
+	}
+	if numSplits <= 0 || lengths[axis]%numSplits != 0 {
+		return nil, fmt.Errorf("axis %d has size %d which is not divisible by %d numSplits", axis, lengths[axis], numSplits)
+	}
+	out := append([]int{numSplits}, lengths...)
+	out[axis+1] /= numSplits
+	return out, nil
+}
+
+// reduceAxisLengths returns the axis lengths of an array reduced along some axes.
+func reduceAxisLengths(lengths []int, axes []int) ([]int, error) {
+	var out []int
+	for _, axis := range axes {
+		if axis < 0 || axis >= len(lengths) {
+			return nil, fmt.Errorf("reduction axis %d is out of bounds for rank %d", axis, len(lengths))
+		}
+	}
+	for axis, length := range lengths {
+		if !slices.Contains(axes, axis) {
+			out = append(out, length)
+		}
+	}
+	return out, nil
+}
+
+// bitcastAxisLengths returns the axis lengths of an array reinterpreted as another data type.
+// Like XLA, if the source data type is larger than the target data type, a trailing axis is added.
+// If it is smaller, the last axis, of length the ratio of the two sizes, is removed.
+func bitcastAxisLengths(lengths []int, from, to dtype.DataType) ([]int, error) {
+	fromSize, toSize := dtype.Sizeof(from), dtype.Sizeof(to)
+	switch {
+	case fromSize == toSize:
+		return slices.Clone(lengths), nil
+	case fromSize > toSize:
+		return append(slices.Clone(lengths), fromSize/toSize), nil
+	}
+	ratio := toSize / fromSize
+	if len(lengths) == 0 || lengths[len(lengths)-1] != ratio {
+		return nil, fmt.Errorf("cannot reinterpret %s%v as %s: the last axis must have a length of %d", from, lengths, to, ratio)
+	}
+	return slices.Clone(lengths[:len(lengths)-1]), nil
+}
@@ -0,0 +1,371 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdlib
+
+import (
+	"fmt"
+	"go/ast"
+	"slices"
+
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/build/builtins"
+	"github.com/gx-org/gx/build/fmterr"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/interp/elements"
+	"github.com/gx-org/gx/interp/evaluator"
+	"github.com/gx-org/gx/interp/fun"
+	"github.com/gx-org/gx/interp"
+	"github.com/gx-org/gx/interp/materialise"
+	"github.com/gx-org/gx/stdlib/builtin"
+	"github.com/gx-org/gx/stdlib/impl"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+)
+
+// matrixAxes returns the axes of a batch of matrices given as argument to a linear algebra builtin.
+func matrixAxes(fetcher ir.Fetcher, call *ir.CallExpr, name string, param ir.Type) (ir.ArrayType, []ir.AxisLengths, error) {
+	typ, err := builtins.NarrowType[ir.ArrayType](fetcher, call, param)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ir.IsFloat(typ.DataType()) || typ.DataType().Kind() == ir.Bfloat16Kind {
+		return nil, nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "cannot use %s in call to %s: only float32 and float64 are supported", typ.String(), name)
+	}
+	if _, err := axisValues(fetcher, call, name, typ); err != nil {
+		return nil, nil, err
+	}
+	axes := typ.Rank().Axes()
+	if len(axes) < 2 {
+		return nil, nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "cannot use %s in call to %s: at least 2 axes are required", typ.String(), name)
+	}
+	return typ, axes, nil
+}
+
+// checkAssignableAxes returns an error if two axes may have different lengths.
+func checkAssignableAxes(fetcher ir.Fetcher, call *ir.CallExpr, name string, x, y ir.AxisLengths) error {
+	ok, err := x.AssignableTo(fetcher, y)
+	if err != nil {
+		return fmterr.Position(fetcher.File().FileSet(), call.Source(), err)
+	}
+	if !ok {
+		return fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "axis lengths %s and %s are not compatible in call to %s", x.String(), y.String(), name)
+	}
+	return nil
+}
+
+// squareMatrixAxes returns the axes of a batch of square matrices given as argument to a linear algebra builtin.
+func squareMatrixAxes(fetcher ir.Fetcher, call *ir.CallExpr, name string, param ir.Type) (ir.ArrayType, []ir.AxisLengths, error) {
+	typ, axes, err := matrixAxes(fetcher, call, name, param)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkAssignableAxes(fetcher, call, name, axes[len(axes)-2], axes[len(axes)-1]); err != nil {
+		return nil, nil, err
+	}
+	return typ, axes, nil
+}
+
+// withAxes returns an array type with the data type of typ and the given axes.
+func withAxes(typ ir.ArrayType, axes []ir.AxisLengths) ir.ArrayType {
+	return ir.NewArrayType(&ast.ArrayType{}, typ.DataType(), &ir.Rank{Ax: axes})
+}
+
+// batchWith returns the batch axes of a batch of matrices, followed by extra axes.
+func batchWith(axes []ir.AxisLengths, extra ...ir.AxisLengths) []ir.AxisLengths {
+	return append(slices.Clone(axes[:len(axes)-2]), extra...)
+}
+
+// resultElements returns the elements of the results of a builtin call from the backend nodes.
+func resultElements(env evaluator.Env, call elements.CallAt, nodes ...*ops.OutputNode) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	els := make([]ir.Element, len(nodes))
+	for i, node := range nodes {
+		out, err := outputNode(pjrtGraph(env), node.Node, node.Shape)
+		if err != nil {
+			return nil, err
+		}
+		el, err := mat.ElementsFromNodes(call.File(), call.Node().ExprFromResult(i), out)
+		if err != nil {
+			return nil, err
+		}
+		els[i] = el[0]
+	}
+	return els, nil
+}
+
+// batchShape returns the shape of the batch axes of x followed by extra axes.
+func batchShape(x *shape.Shape, extra ...int) *shape.Shape {
+	return &shape.Shape{
+		DType:       x.DType,
+		AxisLengths: append(slices.Clone(x.AxisLengths[:len(x.AxisLengths)-2]), extra...),
+	}
+}
+
+// evalMatrixFunc evaluates a linear algebra builtin with a single result given a function
+// computing the result and its shape.
+func evalMatrixFunc(f func(g *pjrtgraph.Graph, x ops.Node) (ops.Node, error), resultShape func(*shape.Shape) *shape.Shape) interp.FuncBuiltin {
+	return func(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+		mat := builtin.Materialiser(env)
+		x, xShape, err := materialise.Element(mat, args[0])
+		if err != nil {
+			return nil, err
+		}
+		node, err := f(pjrtGraph(env), x)
+		if err != nil {
+			return nil, err
+		}
+		out, err := outputNode(pjrtGraph(env), node, resultShape(xShape))
+		if err != nil {
+			return nil, err
+		}
+		return mat.ElementsFromNodes(call.File(), call.Node(), out)
+	}
+}
+
+func sameShape(x *shape.Shape) *shape.Shape { return x }
+
+func batchOnlyShape(x *shape.Shape) *shape.Shape { return batchShape(x) }
+
+type cholesky struct {
+	builtin.Func
+}
+
+func (f cholesky) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[cholesky]("Cholesky", withPosition(evalMatrixFunc((*pjrtgraph.Graph).Cholesky, sameShape)), pkg), nil
+}
+
+func (f cholesky) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildSquareMatrixType(fetcher, call, f.Name(), false)
+}
+
+type inv struct {
+	builtin.Func
+}
+
+func (f inv) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[inv]("Inv", withPosition(evalMatrixFunc((*pjrtgraph.Graph).Inv, sameShape)), pkg), nil
+}
+
+func (f inv) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildSquareMatrixType(fetcher, call, f.Name(), false)
+}
+
+type det struct {
+	builtin.Func
+}
+
+func (f det) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[det]("Det", withPosition(evalMatrixFunc((*pjrtgraph.Graph).Det, batchOnlyShape)), pkg), nil
+}
+
+func (f det) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildSquareMatrixType(fetcher, call, f.Name(), true)
+}
+
+type logDet struct {
+	builtin.Func
+}
+
+func (f logDet) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[logDet]("LogDet", withPosition(evalMatrixFunc((*pjrtgraph.Graph).LogDet, batchOnlyShape)), pkg), nil
+}
+
+func (f logDet) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildSquareMatrixType(fetcher, call, f.Name(), true)
+}
+
+// buildSquareMatrixType returns the type of a builtin taking a batch of square matrices.
+// The result has the same type as the argument or, if batchOnly is true, the batch axes only.
+func buildSquareMatrixType(fetcher ir.Fetcher, call *ir.CallExpr, name string, batchOnly bool) (*ir.FuncType, error) {
+	params, err := builtins.BuildFuncParams(fetcher, call, name, []ir.Type{builtins.GenericArrayType})
+	if err != nil {
+		return nil, err
+	}
+	typ, axes, err := squareMatrixAxes(fetcher, call, name, params[0])
+	if err != nil {
+		return nil, err
+	}
+	if batchOnly {
+		return funcType(call, params, withAxes(typ, batchWith(axes))), nil
+	}
+	return funcType(call, params, typ), nil
+}
+
+type triangularSolve struct {
+	builtin.Func
+}
+
+func (f triangularSolve) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[triangularSolve]("TriangularSolve", withPosition(evalTriangularSolve), pkg), nil
+}
+
+func (f triangularSolve) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	params, err := builtins.BuildFuncParams(fetcher, call, f.Name(), []ir.Type{
+		builtins.GenericArrayType,
+		builtins.GenericArrayType,
+		ir.BoolType(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	aType, aAxes, err := squareMatrixAxes(fetcher, call, f.Name(), params[0])
+	if err != nil {
+		return nil, err
+	}
+	bType, bAxes, err := matrixAxes(fetcher, call, f.Name(), params[1])
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSameDataType(fetcher, call, f.Name(), aType, bType); err != nil {
+		return nil, err
+	}
+	if len(aAxes) != len(bAxes) {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "cannot solve %s with right-hand side %s in call to %s: the number of axes must be the same", aType.String(), bType.String(), f.Name())
+	}
+	for i := range len(aAxes) - 1 {
+		if err := checkAssignableAxes(fetcher, call, f.Name(), bAxes[i], aAxes[i]); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := fetcher.EvalExpr(call.Args[2]); err != nil {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[2].Source(), "argument 3 in call to %s must be known at compile time: %v", f.Name(), err)
+	}
+	return funcType(call, params, bType), nil
+}
+
+func evalTriangularSolve(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	a, _, err := materialise.Element(mat, args[0])
+	if err != nil {
+		return nil, err
+	}
+	b, bShape, err := materialise.Element(mat, args[1])
+	if err != nil {
+		return nil, err
+	}
+	lower, err := elements.ConstantScalarFromElement[bool](args[2])
+	if err != nil {
+		return nil, fmt.Errorf("lower must be known at compile time: %w", err)
+	}
+	node, err := pjrtGraph(env).TriangularSolve(a, b, lower)
+	if err != nil {
+		return nil, err
+	}
+	out, err := outputNode(pjrtGraph(env), node, bShape)
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
+}
+
+type qr struct {
+	builtin.Func
+}
+
+func (f qr) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[qr]("QR", withPosition(evalQR), pkg), nil
+}
+
+func (f qr) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	params, err := builtins.BuildFuncParams(fetcher, call, f.Name(), []ir.Type{builtins.GenericArrayType})
+	if err != nil {
+		return nil, err
+	}
+	typ, axes, err := matrixAxes(fetcher, call, f.Name(), params[0])
+	if err != nil {
+		return nil, err
+	}
+	m, n := axes[len(axes)-2], axes[len(axes)-1]
+	k, err := minAxis(fetcher, call, f.Name(), m, n)
+	if err != nil {
+		return nil, err
+	}
+	return funcType(call, params,
+		withAxes(typ, batchWith(axes, m, k)),
+		withAxes(typ, batchWith(axes, k, n)),
+	), nil
+}
+
+// minAxis returns the minimum of two axis lengths.
+func minAxis(fetcher ir.Fetcher, call *ir.CallExpr, name string, m, n ir.AxisLengths) (ir.AxisLengths, error) {
+	mVal, mErr := elements.EvalInt(fetcher, m.AxisValue())
+	nVal, nErr := elements.EvalInt(fetcher, n.AxisValue())
+	if mErr == nil && nErr == nil {
+		return axisLength(call, intLen(call, min(mVal, nVal))), nil
+	}
+	if ok, err := m.AssignableTo(fetcher, n); err == nil && ok {
+		return m, nil
+	}
+	return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "cannot infer the minimum of axis lengths %s and %s in call to %s: lengths must be known at compile time or equal", m.String(), n.String(), name)
+}
+
+func evalQR(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	x, xShape, err := materialise.Element(mat, args[0])
+	if err != nil {
+		return nil, err
+	}
+	q, r, err := pjrtGraph(env).QR(x)
+	if err != nil {
+		return nil, err
+	}
+	lengths := xShape.AxisLengths
+	m, n := lengths[len(lengths)-2], lengths[len(lengths)-1]
+	k := min(m, n)
+	return resultElements(env, call,
+		&ops.OutputNode{Node: q, Shape: batchShape(xShape, m, k)},
+		&ops.OutputNode{Node: r, Shape: batchShape(xShape, k, n)},
+	)
+}
+
+type eigh struct {
+	builtin.Func
+}
+
+func (f eigh) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[eigh]("Eigh", withPosition(evalEigh), pkg), nil
+}
+
+func (f eigh) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	params, err := builtins.BuildFuncParams(fetcher, call, f.Name(), []ir.Type{builtins.GenericArrayType})
+	if err != nil {
+		return nil, err
+	}
+	typ, axes, err := squareMatrixAxes(fetcher, call, f.Name(), params[0])
+	if err != nil {
+		return nil, err
+	}
+	return funcType(call, params,
+		withAxes(typ, batchWith(axes, axes[len(axes)-1])),
+		typ,
+	), nil
+}
+
+func evalEigh(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	x, xShape, err := materialise.Element(mat, args[0])
+	if err != nil {
+		return nil, err
+	}
+	w, v, err := pjrtGraph(env).Eigh(x)
+	if err != nil {
+		return nil, err
+	}
+	n := xShape.AxisLengths[len(xShape.AxisLengths)-1]
+	return resultElements(env, call,
+		&ops.OutputNode{Node: w, Shape: batchShape(xShape, n)},
+		&ops.OutputNode{Node: v, Shape: xShape},
+	)
+}
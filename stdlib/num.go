@@ -19,7 +19,6 @@ import (
 	"slices"
 
 	"github.com/gomlx/gopjrt/xlabuilder"
-	"github.com/gx-org/backend/ops"
 	"github.com/gx-org/backend/shape"
 	"github.com/gx-org/gx/build/ir"
 	"github.com/gx-org/gx/interp/elements"
@@ -46,17 +45,22 @@ func xlaReductionFunc(f func(*xlabuilder.Op, ...int) (*xlabuilder.Op, error)) in
 			// specified, treat this as a no-op.
 			return []ir.Element{args[0]}, nil
 		}
+		lengths, err := reduceAxisLengths(xShape.AxisLengths, axes)
+		if err != nil {
+			return nil, err
+		}
 		resultNode, err := pjrtGraph(env).ReduceFunc(x, axes, f)
 		if err != nil {
 			return nil, err
 		}
-		return mat.ElementsFromNodes(call.File(), call.Node(), &ops.OutputNode{
-			Node: resultNode,
-			Shape: &shape.Shape{
-				DType:       xShape.DType,
-				AxisLengths: resultNode.(interface{ PJRTDims() []int }).PJRTDims(),
-			},
+		out, err := outputNode(pjrtGraph(env), resultNode, &shape.Shape{
+			DType:       xShape.DType,
+			AxisLengths: lengths,
 		})
+		if err != nil {
+			return nil, err
+		}
+		return mat.ElementsFromNodes(call.File(), call.Node(), out)
 	}
 }
 
@@ -83,10 +87,11 @@ func evalTranspose(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc
 		DType:       argShape.DType,
 		AxisLengths: targetLengths,
 	}
-	return mat.ElementsFromNodes(call.File(), call.Node(), &ops.OutputNode{
-		Node:  op,
-		Shape: targetShape,
-	})
+	out, err := outputNode(pjrtGraph(env), op, targetShape)
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
 }
 
 func evalEinsum(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
@@ -122,13 +127,16 @@ func evalEinsum(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir
 	if err != nil {
 		return nil, fmt.Errorf("\nlhsContractingAxes: %v\nlhsBatchAxes: %v\nrhsContractingAxes: %v\nrhsBatchAxes: %v\nleft: %v\nright: %v", lhsContractingAxes, lhsBatchAxes, rhsContractingAxes, rhsBatchAxes, leftShape, rightShape)
 	}
-	return mat.ElementsFromNodes(call.File(), call.Node(), &ops.OutputNode{
-		Node: op,
-		Shape: &shape.Shape{
-			DType:       leftShape.DType,
-			AxisLengths: op.(interface{ PJRTDims() []int }).PJRTDims(),
-		},
+	out, err := outputNode(pjrtGraph(env), op, &shape.Shape{
+		DType: leftShape.DType,
+		AxisLengths: dotGeneralAxes(leftShape.AxisLengths, rightShape.AxisLengths,
+			[2][]int{lhsBatchAxes, rhsBatchAxes},
+			[2][]int{lhsContractingAxes, rhsContractingAxes}),
 	})
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
 }
 
 func evalIota(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
@@ -148,15 +156,16 @@ func evalIota(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.F
 	if err != nil {
 		return nil, err
 	}
-	return builtin.Materialiser(env).ElementsFromNodes(call.File(), call.Node(), &ops.OutputNode{
-		Node:  op,
-		Shape: targetShape,
-	})
+	out, err := outputNode(pjrtGraph(env), op, targetShape)
+	if err != nil {
+		return nil, err
+	}
+	return builtin.Materialiser(env).ElementsFromNodes(call.File(), call.Node(), out)
 }
 
 func evalArgmax(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
 	mat := builtin.Materialiser(env)
-	argNode, _, err := materialise.Element(mat, args[0])
+	argNode, argShape, err := materialise.Element(mat, args[0])
 	if err != nil {
 		return nil, err
 	}
@@ -164,15 +173,20 @@ func evalArgmax(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir
 	if err != nil {
 		return nil, err
 	}
+	lengths, err := reduceAxisLengths(argShape.AxisLengths, []int{int(axisIndex)})
+	if err != nil {
+		return nil, err
+	}
 	op, err := pjrtGraph(env).ArgMinMax(argNode, int(axisIndex), ir.DefaultIntKind, false)
 	if err != nil {
 		return nil, err
 	}
-	return mat.ElementsFromNodes(call.File(), call.Node(), &ops.OutputNode{
-		Node: op,
-		Shape: &shape.Shape{
-			DType:       ir.DefaultIntKind.DType(),
-			AxisLengths: op.(interface{ PJRTDims() []int }).PJRTDims(),
-		},
+	out, err := outputNode(pjrtGraph(env), op, &shape.Shape{
+		DType:       ir.DefaultIntKind.DType(),
+		AxisLengths: lengths,
 	})
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
 }
@@ -0,0 +1,310 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdlib
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"github.com/gx-org/backend/dtype"
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/build/builtins"
+	"github.com/gx-org/gx/build/fmterr"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/interp/elements"
+	"github.com/gx-org/gx/interp/evaluator"
+	"github.com/gx-org/gx/interp/fun"
+	"github.com/gx-org/gx/interp/materialise"
+	"github.com/gx-org/gx/stdlib/builtin"
+	"github.com/gx-org/gx/stdlib/impl"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+)
+
+// broadcastShape returns the shape of an element-wise operation given its operands.
+func broadcastShape(dt dtype.DataType, operands []*ops.OutputNode) *shape.Shape {
+	for _, operand := range operands {
+		if len(operand.Shape.AxisLengths) > 0 {
+			return &shape.Shape{DType: dt, AxisLengths: operand.Shape.AxisLengths}
+		}
+	}
+	return &shape.Shape{DType: dt}
+}
+
+// padAxes returns the padding configuration of all the axes of an array.
+func padAxes(rank int, low, high, interior []int) ([]pjrtgraph.PadAxis, error) {
+	if len(low) != rank || len(high) != rank || len(interior) != rank {
+		return nil, fmt.Errorf("got %d low, %d high, and %d interior paddings but the array has %d axes", len(low), len(high), len(interior), rank)
+	}
+	axes := make([]pjrtgraph.PadAxis, rank)
+	for i := range rank {
+		if interior[i] < 0 {
+			return nil, fmt.Errorf("invalid interior padding %d for axis %d: cannot be negative", interior[i], i)
+		}
+		axes[i] = pjrtgraph.PadAxis{Low: low[i], High: high[i], Interior: interior[i]}
+	}
+	return axes, nil
+}
+
+// paddedLength returns the length of an axis after padding.
+func paddedLength(length int, axis pjrtgraph.PadAxis) int {
+	if length > 0 {
+		length += (length - 1) * axis.Interior
+	}
+	return length + axis.Low + axis.High
+}
+
+type pad struct {
+	builtin.Func
+}
+
+func (f pad) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[pad]("Pad", withPosition(evalPad), pkg), nil
+}
+
+func (f pad) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	if len(call.Args) != 5 {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "wrong number of arguments in call to %s: got %d but want 5", f.Name(), len(call.Args))
+	}
+	xType, err := builtins.NarrowType[ir.ArrayType](fetcher, call, call.Args[0].Type())
+	if err != nil {
+		return nil, err
+	}
+	params, err := builtins.BuildFuncParams(fetcher, call, f.Name(), []ir.Type{
+		builtins.GenericArrayType,
+		xType.DataType(),
+		ir.IntLenSliceType(),
+		ir.IntLenSliceType(),
+		ir.IntLenSliceType(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	xAxes, err := axisValues(fetcher, call, f.Name(), xType)
+	if err != nil {
+		return nil, err
+	}
+	var paddings [3][]int
+	for i := range paddings {
+		if paddings[i], err = staticInts(fetcher, call, f.Name(), i+2); err != nil {
+			return nil, err
+		}
+	}
+	axes, err := padAxes(len(xAxes), paddings[0], paddings[1], paddings[2])
+	if err != nil {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "invalid padding in call to %s: %v", f.Name(), err)
+	}
+	outAxes := make([]ir.AssignableExpr, len(xAxes))
+	if lengths, ok := staticAxisLengths(fetcher, xAxes); ok {
+		for i, length := range lengths {
+			padded := paddedLength(length, axes[i])
+			if padded < 0 {
+				return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "invalid padding in call to %s: axis %d has a negative length %d", f.Name(), i, padded)
+			}
+			outAxes[i] = intLen(call, padded)
+		}
+		return funcType(call, params, arrayType(call, xType.DataType(), outAxes)), nil
+	}
+	// The axis lengths are only known when the function is called:
+	// the output axis length is length*(interior+1)+low+high-interior.
+	for i, ax := range xAxes {
+		val := ax
+		if axes[i].Interior != 0 {
+			val = builtins.ToBinaryExpr(token.MUL, val, intLen(call, axes[i].Interior+1))
+		}
+		offset := axes[i].Low + axes[i].High - axes[i].Interior
+		if offset > 0 {
+			val = builtins.ToBinaryExpr(token.ADD, val, intLen(call, offset))
+		} else if offset < 0 {
+			val = builtins.ToBinaryExpr(token.SUB, val, intLen(call, -offset))
+		}
+		outAxes[i] = val
+	}
+	return funcType(call, params, arrayType(call, xType.DataType(), outAxes)), nil
+}
+
+func evalPad(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	x, xShape, err := materialise.Element(mat, args[0])
+	if err != nil {
+		return nil, err
+	}
+	fill, _, err := materialise.Element(mat, args[1])
+	if err != nil {
+		return nil, err
+	}
+	var paddings [3][]int
+	for i := range paddings {
+		if paddings[i], err = elements.AxesFromElement(args[i+2]); err != nil {
+			return nil, err
+		}
+	}
+	axes, err := padAxes(len(xShape.AxisLengths), paddings[0], paddings[1], paddings[2])
+	if err != nil {
+		return nil, err
+	}
+	node, err := pjrtGraph(env).Pad(x, fill, axes)
+	if err != nil {
+		return nil, err
+	}
+	outShape := &shape.Shape{DType: xShape.DType, AxisLengths: make([]int, len(axes))}
+	for i, length := range xShape.AxisLengths {
+		outShape.AxisLengths[i] = paddedLength(length, axes[i])
+	}
+	out, err := outputNode(pjrtGraph(env), node, outShape)
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
+}
+
+type reverse struct {
+	builtin.Func
+}
+
+func (f reverse) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[reverse]("Reverse", withPosition(evalReverse), pkg), nil
+}
+
+func (f reverse) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	params, err := builtins.BuildFuncParams(fetcher, call, f.Name(), []ir.Type{
+		builtins.GenericArrayType,
+		ir.IntIndexSliceType(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	xType, err := builtins.NarrowType[ir.ArrayType](fetcher, call, params[0])
+	if err != nil {
+		return nil, err
+	}
+	axes, err := builtins.UniqueAxesFromExpr(fetcher, call.Args[1])
+	if err != nil {
+		return nil, err
+	}
+	rank := len(xType.Rank().Axes())
+	for axis := range axes {
+		if axis < 0 || axis >= rank {
+			return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "axis %d is out of bounds for array of rank %d in call to %s", axis, rank, f.Name())
+		}
+	}
+	return funcType(call, params, xType), nil
+}
+
+func evalReverse(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	x, xShape, err := materialise.Element(mat, args[0])
+	if err != nil {
+		return nil, err
+	}
+	axes, err := elements.AxesFromElement(args[1])
+	if err != nil {
+		return nil, err
+	}
+	node, err := pjrtGraph(env).Reverse(x, axes)
+	if err != nil {
+		return nil, err
+	}
+	out, err := outputNode(pjrtGraph(env), node, xShape)
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
+}
+
+type clamp struct {
+	builtin.Func
+}
+
+func (f clamp) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[clamp]("Clamp", withPosition(evalClamp), pkg), nil
+}
+
+func (f clamp) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	if len(call.Args) != 3 {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "wrong number of arguments in call to %s: got %d but want 3", f.Name(), len(call.Args))
+	}
+	params, _, result, err := broadcastParams(fetcher, call, f.Name(), 0, 3)
+	if err != nil {
+		return nil, err
+	}
+	return funcType(call, params, result), nil
+}
+
+func evalClamp(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	operands, err := materialise.AllWithShapes(mat, args)
+	if err != nil {
+		return nil, err
+	}
+	node, err := pjrtGraph(env).Clamp(operands[0].Node, operands[1].Node, operands[2].Node)
+	if err != nil {
+		return nil, err
+	}
+	out, err := outputNode(pjrtGraph(env), node, broadcastShape(operands[0].Shape.DType, operands))
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
+}
+
+type selectFunc struct {
+	builtin.Func
+}
+
+func (f selectFunc) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[selectFunc]("Select", withPosition(evalSelect), pkg), nil
+}
+
+func (f selectFunc) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	if len(call.Args) != 3 {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "wrong number of arguments in call to %s: got %d but want 3", f.Name(), len(call.Args))
+	}
+	condType, ok := call.Args[0].Type().(ir.ArrayType)
+	if !ok || condType.DataType().Kind() != ir.BoolKind {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "cannot use %s as a condition in call to %s: requires booleans", call.Args[0].Type().String(), f.Name())
+	}
+	params, dtype, result, err := broadcastParams(fetcher, call, f.Name(), 1, 2)
+	if err != nil {
+		return nil, err
+	}
+	if !condType.Rank().IsAtomic() {
+		resultType, ok := result.(ir.ArrayType)
+		if !ok || resultType.Rank().IsAtomic() {
+			result = ir.NewArrayType(&ast.ArrayType{}, dtype, condType.Rank())
+		} else if eq, err := condType.Rank().Equal(fetcher, resultType.Rank()); err != nil || !eq {
+			return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "mismatch types %s and %s in call to %s", condType.String(), resultType.String(), f.Name())
+		}
+	}
+	return funcType(call, append([]ir.Type{condType}, params...), result), nil
+}
+
+func evalSelect(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	operands, err := materialise.AllWithShapes(mat, args)
+	if err != nil {
+		return nil, err
+	}
+	node, err := pjrtGraph(env).Select(operands[0].Node, operands[1].Node, operands[2].Node)
+	if err != nil {
+		return nil, err
+	}
+	out, err := outputNode(pjrtGraph(env), node, broadcastShape(operands[1].Shape.DType, operands))
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
+}
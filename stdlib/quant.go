@@ -0,0 +1,243 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdlib
+
+import (
+	"go/ast"
+
+	"github.com/gx-org/backend/dtype"
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/build/builtins"
+	"github.com/gx-org/gx/build/fmterr"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/interp/elements"
+	"github.com/gx-org/gx/interp/evaluator"
+	"github.com/gx-org/gx/interp/fun"
+	"github.com/gx-org/gx/interp"
+	"github.com/gx-org/gx/interp/materialise"
+	"github.com/gx-org/gx/stdlib/builtin"
+	"github.com/gx-org/gx/stdlib/impl"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+)
+
+// quantizedArray returns the type of the array argument of a quantization builtin.
+func quantizedArray(fetcher ir.Fetcher, call *ir.CallExpr, name string, check func(ir.Type) bool, want string) (ir.ArrayType, error) {
+	typ, dtype, err := builtins.InferFromNumericalType(fetcher, call, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := typ.(ir.ArrayType)
+	if !ok || !check(dtype) {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "cannot use %s in call to %s: want an array of %s", typ.String(), name, want)
+	}
+	return arr, nil
+}
+
+// quantParamTypes returns the types of the scale and zero-point arguments of a quantization builtin.
+// Both are scalars for per-tensor quantization or have a single axis of the length of the
+// quantized axis of x for per-axis quantization.
+// scaleDType is the data type of the scale, or nil if the data type is inferred from the argument.
+func quantParamTypes(fetcher ir.Fetcher, call *ir.CallExpr, name string, x ir.ArrayType, scaleDType ir.Type, perAxis bool) (scale, zeroPoint ir.Type, err error) {
+	scale, scaleType, err := builtins.InferFromNumericalType(fetcher, call, 1, scaleDType)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ir.IsFloat(scaleType) || (scaleDType != nil && !isSameType(fetcher, scaleType, scaleDType)) {
+		return nil, nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[1].Source(), "cannot use %s as a scale in call to %s: want %s", scale.String(), name, scaleKind(scaleDType))
+	}
+	zeroPoint, zeroPointType, err := builtins.InferFromNumericalType(fetcher, call, 2, ir.Int32Type())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isSameType(fetcher, zeroPointType, ir.Int32Type()) {
+		return nil, nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[2].Source(), "cannot use %s as a zero-point in call to %s: want int32", zeroPoint.String(), name)
+	}
+	if !perAxis {
+		for i, param := range []ir.Type{scale, zeroPoint} {
+			if arr, ok := param.(ir.ArrayType); ok && !arr.Rank().IsAtomic() {
+				return nil, nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[i+1].Source(), "cannot use %s in call to %s: want a scalar", param.String(), name)
+			}
+		}
+		return scale, zeroPoint, nil
+	}
+	axis, err := elements.EvalInt(fetcher, call.Args[3])
+	if err != nil {
+		return nil, nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[3].Source(), "argument 4 in call to %s must be known at compile time: %v", name, err)
+	}
+	axes := x.Rank().Axes()
+	if axis < 0 || axis >= len(axes) {
+		return nil, nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[3].Source(), "invalid axis %d in call to %s: %s has %d axes", axis, name, x.String(), len(axes))
+	}
+	for i, param := range []ir.Type{scale, zeroPoint} {
+		arr, ok := param.(ir.ArrayType)
+		if !ok || len(arr.Rank().Axes()) != 1 {
+			return nil, nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[i+1].Source(), "cannot use %s in call to %s: want an array with a single axis", param.String(), name)
+		}
+		if err := checkAssignableAxes(fetcher, call, name, arr.Rank().Axes()[0], axes[axis]); err != nil {
+			return nil, nil, err
+		}
+	}
+	return scale, zeroPoint, nil
+}
+
+func isSameType(fetcher ir.Fetcher, x, y ir.Type) bool {
+	eq, err := x.Equal(fetcher, y)
+	return err == nil && eq
+}
+
+func scaleKind(scaleDType ir.Type) string {
+	if scaleDType == nil {
+		return "a floating-point scale"
+	}
+	return scaleDType.String()
+}
+
+// buildQuantizeType returns the type of Quantize and QuantizeAxis.
+func buildQuantizeType(fetcher ir.Fetcher, call *ir.CallExpr, name string, perAxis bool) (*ir.FuncType, error) {
+	if err := checkQuantNumArgs(fetcher, call, name, perAxis); err != nil {
+		return nil, err
+	}
+	x, err := quantizedArray(fetcher, call, name, ir.IsFloat, "floating-point values")
+	if err != nil {
+		return nil, err
+	}
+	scale, zeroPoint, err := quantParamTypes(fetcher, call, name, x, x.DataType(), perAxis)
+	if err != nil {
+		return nil, err
+	}
+	params := []ir.Type{x, scale, zeroPoint}
+	if perAxis {
+		params = append(params, ir.IntIndexType())
+	}
+	return funcType(call, params, ir.NewArrayType(&ast.ArrayType{}, ir.Int32Type(), x.Rank())), nil
+}
+
+// buildDequantizeType returns the type of Dequantize and DequantizeAxis.
+func buildDequantizeType(fetcher ir.Fetcher, call *ir.CallExpr, name string, perAxis bool) (*ir.FuncType, error) {
+	if err := checkQuantNumArgs(fetcher, call, name, perAxis); err != nil {
+		return nil, err
+	}
+	isInt32 := func(typ ir.Type) bool { return typ.Kind() == ir.Int32Kind }
+	q, err := quantizedArray(fetcher, call, name, isInt32, "int32 quantized values")
+	if err != nil {
+		return nil, err
+	}
+	scale, zeroPoint, err := quantParamTypes(fetcher, call, name, q, nil, perAxis)
+	if err != nil {
+		return nil, err
+	}
+	params := []ir.Type{q, scale, zeroPoint}
+	if perAxis {
+		params = append(params, ir.IntIndexType())
+	}
+	_, scaleDType, err := builtins.InferFromNumericalType(fetcher, call, 1, nil)
+	if err != nil {
+		return nil, err
+	}
+	return funcType(call, params, ir.NewArrayType(&ast.ArrayType{}, scaleDType, q.Rank())), nil
+}
+
+func checkQuantNumArgs(fetcher ir.Fetcher, call *ir.CallExpr, name string, perAxis bool) error {
+	want := 3
+	if perAxis {
+		want = 4
+	}
+	if len(call.Args) != want {
+		return fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "wrong number of arguments in call to %s: got %d but want %d", name, len(call.Args), want)
+	}
+	return nil
+}
+
+// evalQuant returns the implementation of a quantization builtin.
+func evalQuant(f func(g *pjrtgraph.Graph, x, scale, zeroPoint ops.Node, axis int) (ops.Node, error), quantize, perAxis bool) interp.FuncBuiltin {
+	return func(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+		mat := builtin.Materialiser(env)
+		operands, err := materialise.AllWithShapes(mat, args[:3])
+		if err != nil {
+			return nil, err
+		}
+		axis := -1
+		if perAxis {
+			if axis, err = elements.ConstantIntFromElement(args[3]); err != nil {
+				return nil, err
+			}
+		}
+		node, err := f(pjrtGraph(env), operands[0].Node, operands[1].Node, operands[2].Node, axis)
+		if err != nil {
+			return nil, err
+		}
+		dt := operands[1].Shape.DType
+		if quantize {
+			dt = dtype.Int32
+		}
+		out, err := outputNode(pjrtGraph(env), node, &shape.Shape{
+			DType:       dt,
+			AxisLengths: operands[0].Shape.AxisLengths,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return mat.ElementsFromNodes(call.File(), call.Node(), out)
+	}
+}
+
+type quantize struct {
+	builtin.Func
+}
+
+func (f quantize) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[quantize]("Quantize", withPosition(evalQuant((*pjrtgraph.Graph).Quantize, true, false)), pkg), nil
+}
+
+func (f quantize) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildQuantizeType(fetcher, call, f.Name(), false)
+}
+
+type quantizeAxis struct {
+	builtin.Func
+}
+
+func (f quantizeAxis) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[quantizeAxis]("QuantizeAxis", withPosition(evalQuant((*pjrtgraph.Graph).Quantize, true, true)), pkg), nil
+}
+
+func (f quantizeAxis) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildQuantizeType(fetcher, call, f.Name(), true)
+}
+
+type dequantize struct {
+	builtin.Func
+}
+
+func (f dequantize) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[dequantize]("Dequantize", withPosition(evalQuant((*pjrtgraph.Graph).Dequantize, false, false)), pkg), nil
+}
+
+func (f dequantize) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildDequantizeType(fetcher, call, f.Name(), false)
+}
+
+type dequantizeAxis struct {
+	builtin.Func
+}
+
+func (f dequantizeAxis) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[dequantizeAxis]("DequantizeAxis", withPosition(evalQuant((*pjrtgraph.Graph).Dequantize, false, true)), pkg), nil
+}
+
+func (f dequantizeAxis) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildDequantizeType(fetcher, call, f.Name(), true)
+}
@@ -33,8 +33,8 @@ var philoxStateShape = &shape.Shape{
 	AxisLengths: []int{3},
 }
 
-func evalPhilox(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element, dtyp dtype.DataType) ([]ir.Element, error) {
-	mat := builtin.Materialiser(env)
+// receiverState returns the state of the Philox generator on which a method is called.
+func receiverState(mat materialise.Materialiser, call elements.CallAt, fn fun.Func) (ops.Node, error) {
 	philox := fn.Recv().Element
 	philoxStruct := ir.Underlying(philox.NamedType()).(*ir.StructType)
 	stateArray := philoxStruct.Fields.FindField("state")
@@ -45,52 +45,80 @@ func evalPhilox(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir
 	if err != nil {
 		return nil, err
 	}
-	evaluator := env.Evaluator()
 	stateNode, _, err := materialise.Element(mat, field)
+	return stateNode, err
+}
+
+// newPhiloxElement returns a generator of the type of the receiver of a method given its state.
+func newPhiloxElement(env evaluator.Env, call elements.CallAt, fn fun.Func, state ops.Node) (ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	philox := fn.Recv().Element
+	philoxStruct := ir.Underlying(philox.NamedType()).(*ir.StructType)
+	stateArray := philoxStruct.Fields.FindField("state")
+	out, err := outputNode(pjrtGraph(env), state, philoxStateShape)
 	if err != nil {
 		return nil, err
 	}
-	dimensions, err := elements.AxesFromElement(args[0])
-	if err != nil {
-		return nil, err
-	}
-	bckGraph := evaluator.ArrayOps().Graph().(*xlagraph.Graph)
-	targetShape := &shape.Shape{DType: dtyp, AxisLengths: dimensions}
-	newState, values, err := bckGraph.RngBitGenerator(stateNode, targetShape)
-	if err != nil {
-		return nil, err
-	}
-
 	philoxStateElement, err := mat.ElementsFromNodes(
 		call.File(),
 		&ir.ValueRef{
 			Src:  stateArray.Name,
 			Stor: stateArray.Storage(),
 		},
-		&ops.OutputNode{
-			Node:  newState,
-			Shape: philoxStateShape,
-		})
+		out)
+	if err != nil {
+		return nil, err
+	}
+	return fun.NewNamedType(interp.NewRunFunc, philox.NamedType(), elements.NewStruct(
+		philoxStruct,
+		map[string]ir.Element{"state": philoxStateElement[0]},
+	)), nil
+}
+
+// sampleFunc samples random values given the state of a Philox generator.
+// It returns the new state and the values.
+type sampleFunc func(g *xlagraph.Graph, state ops.Node) (newState, values ops.Node, err error)
+
+// evalSample evaluates a method of a Philox generator sampling random values.
+// The method consumes the state of the receiver and returns a generator with the new state
+// followed by the values.
+func evalSample(env evaluator.Env, call elements.CallAt, fn fun.Func, valuesShape *shape.Shape, sample sampleFunc) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	stateNode, err := receiverState(mat, call, fn)
+	if err != nil {
+		return nil, err
+	}
+	newState, values, err := sample(pjrtGraph(env), stateNode)
+	if err != nil {
+		return nil, err
+	}
+	philoxElement, err := newPhiloxElement(env, call, fn, newState)
+	if err != nil {
+		return nil, err
+	}
+	out, err := outputNode(pjrtGraph(env), values, valuesShape)
 	if err != nil {
 		return nil, err
 	}
 	valuesElement, err := mat.ElementsFromNodes(
 		call.File(),
 		call.Node().ExprFromResult(1),
-		&ops.OutputNode{
-			Node:  values,
-			Shape: targetShape,
-		})
+		out)
+	if err != nil {
+		return nil, err
+	}
+	return []ir.Element{philoxElement, valuesElement[0]}, nil
+}
+
+func evalPhilox(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element, dtyp dtype.DataType) ([]ir.Element, error) {
+	dimensions, err := elements.AxesFromElement(args[0])
 	if err != nil {
 		return nil, err
 	}
-	return []ir.Element{
-		fun.NewNamedType(interp.NewRunFunc, philox.NamedType(), elements.NewStruct(
-			philoxStruct,
-			map[string]ir.Element{"state": philoxStateElement[0]},
-		)),
-		valuesElement[0],
-	}, nil
+	targetShape := &shape.Shape{DType: dtyp, AxisLengths: dimensions}
+	return evalSample(env, call, fn, targetShape, func(g *xlagraph.Graph, state ops.Node) (ops.Node, ops.Node, error) {
+		return g.RngBitGenerator(state, targetShape)
+	})
 }
 
 func evalPhiloxUint32(ctx evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
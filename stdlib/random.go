@@ -0,0 +1,144 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdlib
+
+import (
+	"github.com/gx-org/backend/dtype"
+	"github.com/gx-org/backend/ops"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/interp/elements"
+	"github.com/gx-org/gx/interp/evaluator"
+	"github.com/gx-org/gx/interp/fun"
+	"github.com/gx-org/gx/interp"
+	"github.com/gx-org/gx/interp/materialise"
+	"github.com/gx-org/gx/stdlib/builtin"
+	xlagraph "github.com/gx-org/xlapjrt/backend/graph"
+)
+
+// evalDistribution returns the implementation of a method sampling values of a given data type
+// from a distribution without parameters. The last argument of the method is the axis lengths
+// of the values.
+func evalDistribution(dtyp dtype.DataType, f func(g *xlagraph.Graph, state ops.Node, sh *shape.Shape) (ops.Node, ops.Node, error)) interp.FuncBuiltin {
+	return func(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+		dims, err := elements.AxesFromElement(args[len(args)-1])
+		if err != nil {
+			return nil, err
+		}
+		sh := &shape.Shape{DType: dtyp, AxisLengths: dims}
+		return evalSample(env, call, fn, sh, func(g *xlagraph.Graph, state ops.Node) (ops.Node, ops.Node, error) {
+			return f(g, state, sh)
+		})
+	}
+}
+
+func evalTruncatedNormal(dtyp dtype.DataType) interp.FuncBuiltin {
+	return func(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+		bounds, err := materialise.AllWithShapes(builtin.Materialiser(env), args[:2])
+		if err != nil {
+			return nil, err
+		}
+		dims, err := elements.AxesFromElement(args[2])
+		if err != nil {
+			return nil, err
+		}
+		sh := &shape.Shape{DType: dtyp, AxisLengths: dims}
+		return evalSample(env, call, fn, sh, func(g *xlagraph.Graph, state ops.Node) (ops.Node, ops.Node, error) {
+			return g.RandomTruncatedNormal(state, bounds[0].Node, bounds[1].Node, sh)
+		})
+	}
+}
+
+func evalBernoulli(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	p, _, err := materialise.Element(builtin.Materialiser(env), args[0])
+	if err != nil {
+		return nil, err
+	}
+	dims, err := elements.AxesFromElement(args[1])
+	if err != nil {
+		return nil, err
+	}
+	sh := &shape.Shape{DType: dtype.Bool, AxisLengths: dims}
+	return evalSample(env, call, fn, sh, func(g *xlagraph.Graph, state ops.Node) (ops.Node, ops.Node, error) {
+		return g.RandomBernoulli(state, p, dims)
+	})
+}
+
+func evalCategorical(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	logits, _, err := materialise.Element(builtin.Materialiser(env), args[0])
+	if err != nil {
+		return nil, err
+	}
+	dims, err := elements.AxesFromElement(args[1])
+	if err != nil {
+		return nil, err
+	}
+	sh := &shape.Shape{DType: dtype.Int64, AxisLengths: dims}
+	return evalSample(env, call, fn, sh, func(g *xlagraph.Graph, state ops.Node) (ops.Node, ops.Node, error) {
+		return g.RandomCategorical(state, logits, dims)
+	})
+}
+
+func evalPermutation(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	n, err := elements.ConstantIntFromElement(args[0])
+	if err != nil {
+		return nil, err
+	}
+	sh := &shape.Shape{DType: dtype.Int64, AxisLengths: []int{n}}
+	return evalSample(env, call, fn, sh, func(g *xlagraph.Graph, state ops.Node) (ops.Node, ops.Node, error) {
+		return g.RandomPermutation(state, n)
+	})
+}
+
+func evalPhiloxSplit(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	n, err := elements.ConstantIntFromElement(args[0])
+	if err != nil {
+		return nil, err
+	}
+	mat := builtin.Materialiser(env)
+	state, err := receiverState(mat, call, fn)
+	if err != nil {
+		return nil, err
+	}
+	seeds, err := pjrtGraph(env).RandomSplit(state, n)
+	if err != nil {
+		return nil, err
+	}
+	return resultElements(env, call, &ops.OutputNode{
+		Node:  seeds,
+		Shape: &shape.Shape{DType: dtype.Uint64, AxisLengths: []int{n, 3}},
+	})
+}
+
+func evalPhiloxFoldIn(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	data, _, err := materialise.Element(mat, args[0])
+	if err != nil {
+		return nil, err
+	}
+	state, err := receiverState(mat, call, fn)
+	if err != nil {
+		return nil, err
+	}
+	newState, err := pjrtGraph(env).RandomFoldIn(state, data)
+	if err != nil {
+		return nil, err
+	}
+	philox, err := newPhiloxElement(env, call, fn, newState)
+	if err != nil {
+		return nil, err
+	}
+	return []ir.Element{philox}, nil
+}
@@ -0,0 +1,245 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdlib
+
+import (
+	"go/ast"
+	"slices"
+
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/build/builtins"
+	"github.com/gx-org/gx/build/fmterr"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/interp/elements"
+	"github.com/gx-org/gx/interp/evaluator"
+	"github.com/gx-org/gx/interp/fun"
+	"github.com/gx-org/gx/interp"
+	"github.com/gx-org/gx/interp/materialise"
+	"github.com/gx-org/gx/stdlib/builtin"
+	"github.com/gx-org/gx/stdlib/impl"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+)
+
+// integerIndices returns the type of an array of integer indices passed as argument to a builtin.
+func integerIndices(fetcher ir.Fetcher, call *ir.CallExpr, name string, param ir.Type, argIndex int) (ir.ArrayType, error) {
+	typ, ok := param.(ir.ArrayType)
+	if !ok || !ir.IsInteger(typ.DataType()) {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[argIndex].Source(), "cannot use %s as indices in call to %s: want an array of integers", param.String(), name)
+	}
+	if _, err := axisValues(fetcher, call, name, typ); err != nil {
+		return nil, err
+	}
+	return typ, nil
+}
+
+// staticLength evaluates at compile time a length passed as an argument to a builtin.
+func staticLength(fetcher ir.Fetcher, call *ir.CallExpr, name string, argIndex int) (int, error) {
+	arg := call.Args[argIndex]
+	length, err := elements.EvalInt(fetcher, arg)
+	if err != nil {
+		return 0, fmterr.Errorf(fetcher.File().FileSet(), arg.Source(), "argument %d in call to %s must be known at compile time: %v", argIndex+1, name, err)
+	}
+	if length < 0 {
+		return 0, fmterr.Errorf(fetcher.File().FileSet(), arg.Source(), "invalid length %d in call to %s: cannot be negative", length, name)
+	}
+	return length, nil
+}
+
+// buildSegmentType returns the type of a segment reduction:
+//
+//	func(data [n, rest___]T, segmentIDs [n]I, numSegments intlen) [numSegments, rest___]T
+func buildSegmentType(fetcher ir.Fetcher, call *ir.CallExpr, name string) (*ir.FuncType, error) {
+	params, err := builtins.BuildFuncParams(fetcher, call, name, []ir.Type{
+		builtins.GenericArrayType,
+		builtins.GenericArrayType,
+		ir.IntLenType(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	data, ok := params[0].(ir.ArrayType)
+	if !ok || !ir.IsFloat(data.DataType()) && !ir.IsInteger(data.DataType()) {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[0].Source(), "cannot use %s in call to %s: want an array of numbers", params[0].String(), name)
+	}
+	if _, err := axisValues(fetcher, call, name, data); err != nil {
+		return nil, err
+	}
+	axes := data.Rank().Axes()
+	if len(axes) == 0 {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[0].Source(), "cannot use %s in call to %s: at least 1 axis is required", data.String(), name)
+	}
+	ids, err := integerIndices(fetcher, call, name, params[1], 1)
+	if err != nil {
+		return nil, err
+	}
+	idsAxes := ids.Rank().Axes()
+	if len(idsAxes) != 1 {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[1].Source(), "cannot use %s as segment ids in call to %s: want an array with a single axis", ids.String(), name)
+	}
+	if err := checkAssignableAxes(fetcher, call, name, idsAxes[0], axes[0]); err != nil {
+		return nil, err
+	}
+	numSegments, err := staticLength(fetcher, call, name, 2)
+	if err != nil {
+		return nil, err
+	}
+	outAxes := append([]ir.AxisLengths{axisLength(call, intLen(call, numSegments))}, axes[1:]...)
+	return funcType(call, params, withAxes(data, outAxes)), nil
+}
+
+// evalSegment returns the implementation of a segment reduction.
+func evalSegment(reduction pjrtgraph.SegmentReduction) interp.FuncBuiltin {
+	return func(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+		mat := builtin.Materialiser(env)
+		operands, err := materialise.AllWithShapes(mat, args[:2])
+		if err != nil {
+			return nil, err
+		}
+		numSegments, err := elements.ConstantIntFromElement(args[2])
+		if err != nil {
+			return nil, err
+		}
+		const sorted = false
+		node, err := pjrtGraph(env).SegmentReduce(operands[0].Node, operands[1].Node, numSegments, reduction, sorted)
+		if err != nil {
+			return nil, err
+		}
+		data := operands[0].Shape
+		out, err := outputNode(pjrtGraph(env), node, &shape.Shape{
+			DType:       data.DType,
+			AxisLengths: append([]int{numSegments}, data.AxisLengths[1:]...),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return mat.ElementsFromNodes(call.File(), call.Node(), out)
+	}
+}
+
+type segmentSum struct {
+	builtin.Func
+}
+
+func (f segmentSum) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[segmentSum]("SegmentSum", withPosition(evalSegment(pjrtgraph.SegmentSum)), pkg), nil
+}
+
+func (f segmentSum) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildSegmentType(fetcher, call, f.Name())
+}
+
+type segmentMax struct {
+	builtin.Func
+}
+
+func (f segmentMax) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[segmentMax]("SegmentMax", withPosition(evalSegment(pjrtgraph.SegmentMax)), pkg), nil
+}
+
+func (f segmentMax) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildSegmentType(fetcher, call, f.Name())
+}
+
+type segmentMin struct {
+	builtin.Func
+}
+
+func (f segmentMin) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[segmentMin]("SegmentMin", withPosition(evalSegment(pjrtgraph.SegmentMin)), pkg), nil
+}
+
+func (f segmentMin) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildSegmentType(fetcher, call, f.Name())
+}
+
+type segmentMean struct {
+	builtin.Func
+}
+
+func (f segmentMean) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[segmentMean]("SegmentMean", withPosition(evalSegment(pjrtgraph.SegmentMean)), pkg), nil
+}
+
+func (f segmentMean) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildSegmentType(fetcher, call, f.Name())
+}
+
+type oneHot struct {
+	builtin.Func
+}
+
+func (f oneHot) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[oneHot]("OneHot", withPosition(evalOneHot), pkg), nil
+}
+
+// BuildFuncType returns the type of OneHot:
+//
+//	func(indices [dims___]I, depth intlen, on, off T) [dims___][depth]T
+func (f oneHot) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	if len(call.Args) != 4 {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "wrong number of arguments in call to %s: got %d but want 4", f.Name(), len(call.Args))
+	}
+	indices, err := integerIndices(fetcher, call, f.Name(), call.Args[0].Type(), 0)
+	if err != nil {
+		return nil, err
+	}
+	depthType, _, err := builtins.InferFromNumericalType(fetcher, call, 1, ir.IntLenType())
+	if err != nil {
+		return nil, err
+	}
+	depth, err := staticLength(fetcher, call, f.Name(), 1)
+	if err != nil {
+		return nil, err
+	}
+	if depth == 0 {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[1].Source(), "invalid depth 0 in call to %s: must be positive", f.Name())
+	}
+	values, dtype, _, err := broadcastParams(fetcher, call, f.Name(), 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		if arr, ok := value.(ir.ArrayType); ok && !arr.Rank().IsAtomic() {
+			return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Args[2+i].Source(), "cannot use %s in call to %s: want a scalar", value.String(), f.Name())
+		}
+	}
+	outAxes := append(slices.Clone(indices.Rank().Axes()), axisLength(call, intLen(call, depth)))
+	result := ir.NewArrayType(&ast.ArrayType{}, dtype, &ir.Rank{Ax: outAxes})
+	return funcType(call, append([]ir.Type{indices, depthType}, values...), result), nil
+}
+
+func evalOneHot(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	operands, err := materialise.AllWithShapes(mat, []ir.Element{args[0], args[2], args[3]})
+	if err != nil {
+		return nil, err
+	}
+	depth, err := elements.ConstantIntFromElement(args[1])
+	if err != nil {
+		return nil, err
+	}
+	node, err := pjrtGraph(env).OneHot(operands[0].Node, depth, operands[1].Node, operands[2].Node)
+	if err != nil {
+		return nil, err
+	}
+	out, err := outputNode(pjrtGraph(env), node, &shape.Shape{
+		DType:       operands[1].Shape.DType,
+		AxisLengths: append(slices.Clone(operands[0].Shape.AxisLengths), depth),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
+}
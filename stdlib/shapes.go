@@ -16,6 +16,7 @@ package stdlib
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/gx-org/backend/ops"
 	"github.com/gx-org/backend/shape"
@@ -26,6 +27,7 @@ import (
 	"github.com/gx-org/gx/interp/fun"
 	"github.com/gx-org/gx/interp/materialise"
 	"github.com/gx-org/gx/stdlib/builtin"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
 )
 
 func evalConcat(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
@@ -43,17 +45,22 @@ func evalConcat(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir
 	if err != nil {
 		return nil, err
 	}
+	lengths, err := concatAxisLengths(int(axis), xShapes)
+	if err != nil {
+		return nil, err
+	}
 	op, err := pjrtGraph(env).Concat(int(axis), xs)
 	if err != nil {
 		return nil, err
 	}
-	return mat.ElementsFromNodes(call.File(), call.Node(), &ops.OutputNode{
-		Node: op,
-		Shape: &shape.Shape{
-			DType:       xShapes[0].DType,
-			AxisLengths: op.(interface{ PJRTDims() []int }).PJRTDims(),
-		},
+	out, err := outputNode(pjrtGraph(env), op, &shape.Shape{
+		DType:       xShapes[0].DType,
+		AxisLengths: lengths,
 	})
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
 }
 
 func evalLen(env evaluator.Env, call elements.CallAt, _ fun.Func, _ *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
@@ -87,81 +94,89 @@ func evalSplit(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.
 	if err != nil {
 		return nil, err
 	}
+	lengths, err := splitAxisLengths(firstArgShape.AxisLengths, int(axis), int(numSplits))
+	if err != nil {
+		return nil, err
+	}
 	op, err := pjrtGraph(env).Split(node, int(axis), int(numSplits))
 	if err != nil {
 		return nil, err
 	}
-	return mat.ElementsFromNodes(call.File(), call.Node(), &ops.OutputNode{
-		Node: op,
-		Shape: &shape.Shape{
-			DType:       firstArgShape.DType,
-			AxisLengths: op.(interface{ PJRTDims() []int }).PJRTDims(),
-		},
+	out, err := outputNode(pjrtGraph(env), op, &shape.Shape{
+		DType:       firstArgShape.DType,
+		AxisLengths: lengths,
 	})
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
 }
 
 func evalGather(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
-	inputShape, err := elements.ShapeFromElement(args[0])
+	mat := builtin.Materialiser(env)
+	x, xShape, err := materialise.Element(mat, args[0])
 	if err != nil {
 		return nil, err
 	}
-	indicesShape, err := elements.ShapeFromElement(args[1])
+	indicesNode, indicesShape, err := materialise.Element(mat, args[1])
 	if err != nil {
 		return nil, err
 	}
-	paramsRank := len(inputShape.AxisLengths)
-	indicesRank := len(indicesShape.AxisLengths)
-	indexedSubRank := indicesShape.AxisLengths[indicesRank-1] // N from documentation.
-	slicesSubRank := paramsRank - indexedSubRank              // S from documentation, the slices dimensions.
-	if slicesSubRank < 0 {
-		return nil, fmt.Errorf("Gather params are \"over-indexed\": params has only rank %d and "+
-			"indexed rank is %d (last dimension of indices)", paramsRank, indexedSubRank)
-	}
-	outputSubRank := indicesRank - 1
-
-	// * indexVectorDim is always the last one.
-	indexVectorDim := indicesRank - 1
-	// * startIndexMap is sequential and sorted
-	startIndexMap := make([]int, indexedSubRank)
-	for ii := 0; ii < indexedSubRank; ii++ {
-		startIndexMap[ii] = ii
-	}
-	// * sliceSizes are 1 everywhere but on the sliced dimensions.
-	// * collapsedSliceDims is set to collapse all dimensions set to 1.
-	sliceSizes := make([]int, paramsRank)
-	collapsedSliceDims := make([]int, indexedSubRank)
-	for ii := 0; ii < paramsRank; ii++ {
-		if ii < indexedSubRank {
-			sliceSizes[ii] = 1
-			collapsedSliceDims[ii] = ii
-		} else {
-			sliceSizes[ii] = inputShape.AxisLengths[ii]
-		}
-	}
-	// * offsetDims are the dimensions indexed.
-	offsetDims := make([]int, paramsRank-indexedSubRank)
-	for ii := range offsetDims {
-		offsetDims[ii] = outputSubRank + ii
-	}
-
-	mat := builtin.Materialiser(env)
-	x, xShape, err := materialise.Element(mat, args[0])
+	cfg, err := gatherNDConfig(xShape.AxisLengths, indicesShape.AxisLengths)
 	if err != nil {
 		return nil, err
 	}
-	indicesNode, _, err := materialise.Element(mat, args[1])
+	lengths, err := pjrtgraph.GatherAxisLengths(xShape.AxisLengths, indicesShape.AxisLengths, cfg)
 	if err != nil {
 		return nil, err
 	}
-	op, err := pjrtGraph(env).Gather(x, indicesNode, indexVectorDim, offsetDims, collapsedSliceDims, startIndexMap, sliceSizes, false)
+	op, err := pjrtGraph(env).GatherWithConfig(x, indicesNode, cfg)
 	if err != nil {
 		return nil, err
 	}
-	return mat.ElementsFromNodes(call.File(), call.Node(), &ops.OutputNode{
-		Node: op,
-		Shape: &shape.Shape{
-			DType:       xShape.DType,
-			AxisLengths: op.(interface{ PJRTDims() []int }).PJRTDims(),
-		},
+	out, err := outputNode(pjrtGraph(env), op, &shape.Shape{
+		DType:       xShape.DType,
+		AxisLengths: lengths,
 	})
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
+}
+
+// gatherNDConfig returns the configuration of a TensorFlow-style gather_nd:
+// the last axis of the indices indexes the first axes of the operand,
+// and the remaining axes of the operand are sliced whole.
+func gatherNDConfig(operand, indices []int) (*pjrtgraph.GatherConfig, error) {
+	if len(indices) == 0 {
+		return nil, fmt.Errorf("Gather indices cannot be a scalar")
+	}
+	paramsRank := len(operand)
+	indicesRank := len(indices)
+	indexedSubRank := indices[indicesRank-1] // N from documentation.
+	if indexedSubRank > paramsRank {
+		return nil, fmt.Errorf("Gather params are \"over-indexed\": params has only rank %d and "+
+			"indexed rank is %d (last dimension of indices)", paramsRank, indexedSubRank)
+	}
+	cfg := &pjrtgraph.GatherConfig{
+		// The index vector is always on the last axis.
+		IndexVectorAxis: indicesRank - 1,
+		// The start index map is sequential and sorted.
+		StartIndexMap: make([]int, indexedSubRank),
+		// The indexed axes are collapsed.
+		CollapsedSliceAxes: make([]int, indexedSubRank),
+		// Slice sizes are 1 on the indexed axes and the full axis length everywhere else.
+		SliceSizes: slices.Clone(operand),
+		// The offset axes follow the batch axes.
+		OffsetAxes: make([]int, paramsRank-indexedSubRank),
+	}
+	for i := range indexedSubRank {
+		cfg.StartIndexMap[i] = i
+		cfg.CollapsedSliceAxes[i] = i
+		cfg.SliceSizes[i] = 1
+	}
+	for i := range cfg.OffsetAxes {
+		cfg.OffsetAxes[i] = indicesRank - 1 + i
+	}
+	return cfg, nil
 }
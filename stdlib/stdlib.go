@@ -19,7 +19,6 @@ import (
 	"fmt"
 
 	"github.com/gomlx/gopjrt/xlabuilder"
-	"github.com/gx-org/backend/ops"
 	"github.com/gx-org/backend/shape"
 	"github.com/gx-org/gx/build/ir"
 	"github.com/gx-org/gx/interp/elements"
@@ -35,42 +34,42 @@ import (
 // Stdlib is the PJRT implementation of the standard library.
 var Stdlib = &impl.Stdlib{
 	Dtype: impl.Dtype{
-		Reinterpret: evalReinterpret,
+		Reinterpret: withPosition(evalReinterpret),
 	},
 	Math: impl.Math{
-		Abs:      xlaUnaryFunc(xlabuilder.Abs),
-		Ceil:     xlaUnaryFunc(xlabuilder.Ceil),
-		Erf:      xlaUnaryFunc(xlabuilder.Erf),
-		Expm1:    xlaUnaryFunc(xlabuilder.Expm1),
-		Floor:    xlaUnaryFunc(xlabuilder.Floor),
-		Log1p:    xlaUnaryFunc(xlabuilder.Log1p),
-		Logistic: xlaUnaryFunc(xlabuilder.Logistic),
-		Max:      xlaBinaryFunc(xlabuilder.Max, minmaxDType),
-		Min:      xlaBinaryFunc(xlabuilder.Min, minmaxDType),
-		Pow:      xlaBinaryFunc(xlabuilder.Pow, firstArgument),
-		Round:    xlaUnaryFunc(xlabuilder.Round),
-		Rsqrt:    xlaUnaryFunc(xlabuilder.Rsqrt),
-		Sign:     xlaUnaryFunc(xlabuilder.Sign),
-		Sqrt:     xlaUnaryFunc(xlabuilder.Sqrt),
+		Abs:      withPosition(xlaUnaryFunc(xlabuilder.Abs)),
+		Ceil:     withPosition(xlaUnaryFunc(xlabuilder.Ceil)),
+		Erf:      withPosition(xlaUnaryFunc(xlabuilder.Erf)),
+		Expm1:    withPosition(xlaUnaryFunc(xlabuilder.Expm1)),
+		Floor:    withPosition(xlaUnaryFunc(xlabuilder.Floor)),
+		Log1p:    withPosition(xlaUnaryFunc(xlabuilder.Log1p)),
+		Logistic: withPosition(xlaUnaryFunc(xlabuilder.Logistic)),
+		Max:      withPosition(xlaBinaryFunc(xlabuilder.Max, minmaxDType)),
+		Min:      withPosition(xlaBinaryFunc(xlabuilder.Min, minmaxDType)),
+		Pow:      withPosition(xlaBinaryFunc(xlabuilder.Pow, firstArgument)),
+		Round:    withPosition(xlaUnaryFunc(xlabuilder.Round)),
+		Rsqrt:    withPosition(xlaUnaryFunc(xlabuilder.Rsqrt)),
+		Sign:     withPosition(xlaUnaryFunc(xlabuilder.Sign)),
+		Sqrt:     withPosition(xlaUnaryFunc(xlabuilder.Sqrt)),
 	},
 	Num: impl.Num{
-		Iota:      evalIota,
-		Transpose: evalTranspose,
-		Einsum:    evalEinsum,
-		MatMul:    xlaBinaryFunc(xlabuilder.Dot, matmulShape),
-		Sum:       xlaReductionFunc(xlabuilder.ReduceSum),
-		ReduceMax: xlaReductionFunc(xlabuilder.ReduceMax),
-		Argmax:    evalArgmax,
+		Iota:      withPosition(evalIota),
+		Transpose: withPosition(evalTranspose),
+		Einsum:    withPosition(evalEinsum),
+		MatMul:    withPosition(evalMatMul),
+		Sum:       withPosition(xlaReductionFunc(xlabuilder.ReduceSum)),
+		ReduceMax: withPosition(xlaReductionFunc(xlabuilder.ReduceMax)),
+		Argmax:    withPosition(evalArgmax),
 	},
 	Rand: impl.Rand{
-		PhiloxUint32: evalPhiloxUint32,
-		PhiloxUint64: evalPhiloxUint64,
+		PhiloxUint32: withPosition(evalPhiloxUint32),
+		PhiloxUint64: withPosition(evalPhiloxUint64),
 	},
 	Shapes: impl.Shapes{
-		Concat: evalConcat,
-		Len:    evalLen,
-		Split:  evalSplit,
-		Gather: evalGather,
+		Concat: withPosition(evalConcat),
+		Len:    withPosition(evalLen),
+		Split:  withPosition(evalSplit),
+		Gather: withPosition(evalGather),
 	},
 }
 
@@ -88,10 +87,11 @@ func xlaUnaryFunc(f func(*xlabuilder.Op) (*xlabuilder.Op, error)) interp.FuncBui
 		if err != nil {
 			return nil, err
 		}
-		return mat.ElementsFromNodes(call.File(), call.Node(), &ops.OutputNode{
-			Node:  node,
-			Shape: xShape,
-		})
+		out, err := outputNode(pjrtGraph(env), node, xShape)
+		if err != nil {
+			return nil, err
+		}
+		return mat.ElementsFromNodes(call.File(), call.Node(), out)
 	}
 }
 
@@ -110,20 +110,6 @@ func minmaxDType(x, y *shape.Shape) *shape.Shape {
 	return target
 }
 
-func matmulShape(x, y *shape.Shape) *shape.Shape {
-	var lengths []int
-	if len(x.AxisLengths) > 0 {
-		lengths = append(lengths, x.AxisLengths[:len(x.AxisLengths)-1]...)
-	}
-	if len(y.AxisLengths) > 0 {
-		lengths = append(lengths, y.AxisLengths[1:]...)
-	}
-	return &shape.Shape{
-		DType:       x.DType,
-		AxisLengths: lengths,
-	}
-}
-
 func xlaBinaryFunc(f func(x *xlabuilder.Op, y *xlabuilder.Op) (*xlabuilder.Op, error), shapeF func(x, y *shape.Shape) *shape.Shape) interp.FuncBuiltin {
 	return func(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
 		mat := builtin.Materialiser(env)
@@ -143,10 +129,11 @@ func xlaBinaryFunc(f func(x *xlabuilder.Op, y *xlabuilder.Op) (*xlabuilder.Op, e
 			return nil, err
 		}
 		outShape := shapeF(xShape, yShape)
-		return mat.ElementsFromNodes(call.File(), call.Node(), &ops.OutputNode{
-			Node:  node,
-			Shape: outShape,
-		})
+		out, err := outputNode(pjrtGraph(env), node, outShape)
+		if err != nil {
+			return nil, err
+		}
+		return mat.ElementsFromNodes(call.File(), call.Node(), out)
 	}
 }
 
@@ -0,0 +1,241 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdlib
+
+import (
+	"fmt"
+	"go/ast"
+
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/build/builtins"
+	"github.com/gx-org/gx/build/fmterr"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/interp/elements"
+	"github.com/gx-org/gx/interp/evaluator"
+	"github.com/gx-org/gx/interp/fun"
+	"github.com/gx-org/gx/interp"
+	"github.com/gx-org/gx/interp/materialise"
+	"github.com/gx-org/gx/stdlib/builtin"
+	"github.com/gx-org/gx/stdlib/impl"
+)
+
+// checkPermutation returns an error if perm is not a permutation of the axes of an array of the given rank.
+func checkPermutation(rank int, perm []int) error {
+	if len(perm) != rank {
+		return fmt.Errorf("permutation %v has %d axes but the array has %d axes", perm, len(perm), rank)
+	}
+	seen := make([]bool, rank)
+	for _, axis := range perm {
+		if axis < 0 || axis >= rank {
+			return fmt.Errorf("axis %d in permutation %v is out of bounds for array of rank %d", axis, perm, rank)
+		}
+		if seen[axis] {
+			return fmt.Errorf("axis %d specified more than once in permutation %v", axis, perm)
+		}
+		seen[axis] = true
+	}
+	return nil
+}
+
+// normaliseAxis converts a negative axis, counting from the last axis, into a positive axis.
+func normaliseAxis(rank, axis int) (int, error) {
+	if axis < -rank || axis >= rank {
+		return 0, fmt.Errorf("axis %d is out of bounds for array of rank %d", axis, rank)
+	}
+	if axis < 0 {
+		axis += rank
+	}
+	return axis, nil
+}
+
+func identityPermutation(rank int) []int {
+	perm := make([]int, rank)
+	for i := range perm {
+		perm[i] = i
+	}
+	return perm
+}
+
+// moveAxisPermutation returns the permutation moving the source axis to the destination axis.
+// The other axes keep their relative order.
+func moveAxisPermutation(rank, source, destination int) ([]int, error) {
+	var err error
+	if source, err = normaliseAxis(rank, source); err != nil {
+		return nil, err
+	}
+	if destination, err = normaliseAxis(rank, destination); err != nil {
+		return nil, err
+	}
+	perm := make([]int, 0, rank)
+	for axis := range rank {
+		if axis != source {
+			perm = append(perm, axis)
+		}
+	}
+	perm = append(perm[:destination], append([]int{source}, perm[destination:]...)...)
+	return perm, nil
+}
+
+// swapAxesPermutation returns the permutation swapping two axes.
+func swapAxesPermutation(rank, axis1, axis2 int) ([]int, error) {
+	var err error
+	if axis1, err = normaliseAxis(rank, axis1); err != nil {
+		return nil, err
+	}
+	if axis2, err = normaliseAxis(rank, axis2); err != nil {
+		return nil, err
+	}
+	perm := identityPermutation(rank)
+	perm[axis1], perm[axis2] = perm[axis2], perm[axis1]
+	return perm, nil
+}
+
+// permutationFunc computes a permutation given the rank of an array and
+// a function returning the integers passed as argument.
+type permutationFunc func(rank int, ints func(int) ([]int, error)) ([]int, error)
+
+func transposePermutation(rank int, ints func(int) ([]int, error)) ([]int, error) {
+	perm, err := ints(1)
+	if err != nil {
+		return nil, err
+	}
+	return perm, checkPermutation(rank, perm)
+}
+
+func axisPairPermutation(f func(rank, a, b int) ([]int, error)) permutationFunc {
+	return func(rank int, ints func(int) ([]int, error)) ([]int, error) {
+		a, err := ints(1)
+		if err != nil {
+			return nil, err
+		}
+		b, err := ints(2)
+		if err != nil {
+			return nil, err
+		}
+		return f(rank, a[0], b[0])
+	}
+}
+
+// buildPermuteType returns the type of a builtin permuting the axes of its first argument.
+func buildPermuteType(fetcher ir.Fetcher, call *ir.CallExpr, name string, sig []ir.Type, permF permutationFunc) (*ir.FuncType, error) {
+	params, err := builtins.BuildFuncParams(fetcher, call, name, sig)
+	if err != nil {
+		return nil, err
+	}
+	xType, err := builtins.NarrowType[ir.ArrayType](fetcher, call, params[0])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := axisValues(fetcher, call, name, xType); err != nil {
+		return nil, err
+	}
+	axes := xType.Rank().Axes()
+	perm, err := permF(len(axes), func(i int) ([]int, error) {
+		if sig[i].Kind() == ir.SliceKind {
+			return staticInts(fetcher, call, name, i)
+		}
+		val, err := elements.EvalInt(fetcher, call.Args[i])
+		return []int{val}, err
+	})
+	if err != nil {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "invalid axes in call to %s: %v", name, err)
+	}
+	outAxes := make([]ir.AxisLengths, len(axes))
+	for i, axis := range perm {
+		outAxes[i] = axes[axis]
+	}
+	out := ir.NewArrayType(&ast.ArrayType{}, xType.DataType(), &ir.Rank{Ax: outAxes})
+	return funcType(call, params, out), nil
+}
+
+func evalPermute(permF permutationFunc) interp.FuncBuiltin {
+	return func(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+		mat := builtin.Materialiser(env)
+		x, xShape, err := materialise.Element(mat, args[0])
+		if err != nil {
+			return nil, err
+		}
+		perm, err := permF(len(xShape.AxisLengths), func(i int) ([]int, error) {
+			if _, isSlice := args[i].(*elements.Slice); isSlice {
+				return elements.AxesFromElement(args[i])
+			}
+			val, err := elements.ConstantIntFromElement(args[i])
+			return []int{val}, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		outShape := &shape.Shape{DType: xShape.DType, AxisLengths: make([]int, len(perm))}
+		for i, axis := range perm {
+			outShape.AxisLengths[i] = xShape.AxisLengths[axis]
+		}
+		node, err := pjrtGraph(env).Transpose(x, perm)
+		if err != nil {
+			return nil, err
+		}
+		out, err := outputNode(pjrtGraph(env), node, outShape)
+		if err != nil {
+			return nil, err
+		}
+		return mat.ElementsFromNodes(call.File(), call.Node(), out)
+	}
+}
+
+type transposeAxes struct {
+	builtin.Func
+}
+
+func (f transposeAxes) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[transposeAxes]("Transpose", withPosition(evalPermute(transposePermutation)), pkg), nil
+}
+
+func (f transposeAxes) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildPermuteType(fetcher, call, f.Name(), []ir.Type{
+		builtins.GenericArrayType,
+		ir.IntIndexSliceType(),
+	}, transposePermutation)
+}
+
+type moveAxis struct {
+	builtin.Func
+}
+
+func (f moveAxis) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[moveAxis]("MoveAxis", withPosition(evalPermute(axisPairPermutation(moveAxisPermutation))), pkg), nil
+}
+
+func (f moveAxis) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildPermuteType(fetcher, call, f.Name(), []ir.Type{
+		builtins.GenericArrayType,
+		ir.IntIndexType(),
+		ir.IntIndexType(),
+	}, axisPairPermutation(moveAxisPermutation))
+}
+
+type swapAxes struct {
+	builtin.Func
+}
+
+func (f swapAxes) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[swapAxes]("SwapAxes", withPosition(evalPermute(axisPairPermutation(swapAxesPermutation))), pkg), nil
+}
+
+func (f swapAxes) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildPermuteType(fetcher, call, f.Name(), []ir.Type{
+		builtins.GenericArrayType,
+		ir.IntIndexType(),
+		ir.IntIndexType(),
+	}, axisPairPermutation(swapAxesPermutation))
+}
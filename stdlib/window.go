@@ -0,0 +1,384 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdlib
+
+import (
+	"fmt"
+	"go/token"
+
+	"github.com/gomlx/gopjrt/xlabuilder"
+	"github.com/gx-org/backend/shape"
+	"github.com/gx-org/gx/build/builtins"
+	"github.com/gx-org/gx/build/fmterr"
+	"github.com/gx-org/gx/build/ir"
+	"github.com/gx-org/gx/interp/elements"
+	"github.com/gx-org/gx/interp/evaluator"
+	"github.com/gx-org/gx/interp/fun"
+	"github.com/gx-org/gx/interp"
+	"github.com/gx-org/gx/interp/materialise"
+	"github.com/gx-org/gx/stdlib/builtin"
+	"github.com/gx-org/gx/stdlib/impl"
+	pjrtgraph "github.com/gx-org/xlapjrt/backend/graph"
+)
+
+// windowArgs are the indices of the arguments configuring a window in a call to a builtin.
+// A negative index means that the builtin always uses the default value.
+type windowArgs struct {
+	dimensions, strides, baseDilations, windowDilations, padLow, padHigh int
+}
+
+var (
+	reduceWindowArgs     = windowArgs{dimensions: 1, strides: 2, baseDilations: 3, windowDilations: 4, padLow: 5, padHigh: 6}
+	poolArgs             = windowArgs{dimensions: 1, strides: 2, baseDilations: -1, windowDilations: -1, padLow: -1, padHigh: -1}
+	selectAndScatterArgs = windowArgs{dimensions: 2, strides: 3, baseDilations: -1, windowDilations: -1, padLow: 4, padHigh: 5}
+)
+
+func (wa windowArgs) numArgs() int {
+	return max(wa.dimensions, wa.strides, wa.baseDilations, wa.windowDilations, wa.padLow, wa.padHigh) + 1
+}
+
+// window builds a window given a function returning the integers passed as an argument.
+func (wa windowArgs) window(rank int, ints func(int) ([]int, error)) (*pjrtgraph.Window, error) {
+	window := &pjrtgraph.Window{
+		BaseDilations:   make([]int, rank),
+		WindowDilations: make([]int, rank),
+		Padding:         make([][2]int, rank),
+	}
+	for i := range rank {
+		window.BaseDilations[i] = 1
+		window.WindowDilations[i] = 1
+	}
+	var err error
+	if window.Dimensions, err = ints(wa.dimensions); err != nil {
+		return nil, err
+	}
+	if window.Strides, err = ints(wa.strides); err != nil {
+		return nil, err
+	}
+	if wa.baseDilations >= 0 {
+		if window.BaseDilations, err = ints(wa.baseDilations); err != nil {
+			return nil, err
+		}
+	}
+	if wa.windowDilations >= 0 {
+		if window.WindowDilations, err = ints(wa.windowDilations); err != nil {
+			return nil, err
+		}
+	}
+	if wa.padLow < 0 {
+		return window, nil
+	}
+	low, err := ints(wa.padLow)
+	if err != nil {
+		return nil, err
+	}
+	high, err := ints(wa.padHigh)
+	if err != nil {
+		return nil, err
+	}
+	if len(low) != rank || len(high) != rank {
+		return nil, fmt.Errorf("got %d low paddings and %d high paddings but the array has %d axes", len(low), len(high), rank)
+	}
+	for i := range rank {
+		window.Padding[i] = [2]int{low[i], high[i]}
+	}
+	return window, nil
+}
+
+// buildWindowType returns the type of a builtin sliding a window over its first argument.
+// If withSource is true, the second argument is an array with the shape of the windowed output
+// and the builtin returns an array with the shape of its first argument.
+func buildWindowType(fetcher ir.Fetcher, call *ir.CallExpr, name string, wa windowArgs, withSource bool) (*ir.FuncType, error) {
+	sig := make([]ir.Type, wa.numArgs())
+	sig[0] = builtins.GenericArrayType
+	for i := 1; i < len(sig); i++ {
+		sig[i] = ir.IntLenSliceType()
+	}
+	if withSource {
+		sig[1] = builtins.GenericArrayType
+	}
+	params, err := builtins.BuildFuncParams(fetcher, call, name, sig)
+	if err != nil {
+		return nil, err
+	}
+	xType, err := builtins.NarrowType[ir.ArrayType](fetcher, call, params[0])
+	if err != nil {
+		return nil, err
+	}
+	xAxes, err := axisValues(fetcher, call, name, xType)
+	if err != nil {
+		return nil, err
+	}
+	window, err := wa.window(len(xAxes), func(i int) ([]int, error) {
+		return staticInts(fetcher, call, name, i)
+	})
+	if err != nil {
+		return nil, fmterr.Position(fetcher.File().FileSet(), call.Source(), err)
+	}
+	outAxes, err := windowAxes(fetcher, call, name, xAxes, window)
+	if err != nil {
+		return nil, err
+	}
+	out := arrayType(call, xType.DataType(), outAxes)
+	if !withSource {
+		return funcType(call, params, out), nil
+	}
+	sourceType, err := builtins.NarrowType[ir.ArrayType](fetcher, call, params[1])
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := sourceType.AssignableTo(fetcher, out); err != nil || !ok {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "cannot use %s as the source in call to %s: want %s", sourceType.String(), name, out.String())
+	}
+	return funcType(call, params, xType), nil
+}
+
+// windowAxes returns the axis lengths of the array computed by sliding a window over an array.
+func windowAxes(fetcher ir.Fetcher, call *ir.CallExpr, name string, xAxes []ir.AssignableExpr, window *pjrtgraph.Window) ([]ir.AssignableExpr, error) {
+	if err := window.Validate(len(xAxes)); err != nil {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "invalid window in call to %s: %v", name, err)
+	}
+	out := make([]ir.AssignableExpr, len(xAxes))
+	if lengths, ok := staticAxisLengths(fetcher, xAxes); ok {
+		outLengths, err := window.OutputAxisLengths(lengths)
+		if err != nil {
+			return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "invalid window in call to %s: %v", name, err)
+		}
+		for i, length := range outLengths {
+			out[i] = intLen(call, length)
+		}
+		return out, nil
+	}
+	// The axis lengths are only known when the function is called:
+	// the output axis length is (length*baseDilation+offset)/stride.
+	for i, ax := range xAxes {
+		dilation := window.BaseDilations[i]
+		dilatedWindow := (window.Dimensions[i]-1)*window.WindowDilations[i] + 1
+		offset := 1 - dilation + window.Padding[i][0] + window.Padding[i][1] - dilatedWindow + window.Strides[i]
+		val := ax
+		if dilation != 1 {
+			val = builtins.ToBinaryExpr(token.MUL, val, intLen(call, dilation))
+		}
+		if offset > 0 {
+			val = builtins.ToBinaryExpr(token.ADD, val, intLen(call, offset))
+		} else if offset < 0 {
+			val = builtins.ToBinaryExpr(token.SUB, val, intLen(call, -offset))
+		}
+		if window.Strides[i] != 1 {
+			val = builtins.ToBinaryExpr(token.QUO, val, intLen(call, window.Strides[i]))
+		}
+		out[i] = val
+	}
+	return out, nil
+}
+
+// staticAxisLengths evaluates axis lengths at compile time.
+// It returns false if one of the lengths is unknown.
+func staticAxisLengths(fetcher ir.Fetcher, axes []ir.AssignableExpr) ([]int, bool) {
+	lengths := make([]int, len(axes))
+	for i, ax := range axes {
+		var err error
+		if lengths[i], err = elements.EvalInt(fetcher, ax); err != nil {
+			return nil, false
+		}
+	}
+	return lengths, true
+}
+
+// evalWindow returns the window passed as arguments to a builtin and the shape of the output.
+func (wa windowArgs) evalWindow(args []ir.Element, xShape *shape.Shape) (*pjrtgraph.Window, *shape.Shape, error) {
+	window, err := wa.window(len(xShape.AxisLengths), func(i int) ([]int, error) {
+		return elements.AxesFromElement(args[i])
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	lengths, err := window.OutputAxisLengths(xShape.AxisLengths)
+	if err != nil {
+		return nil, nil, err
+	}
+	return window, &shape.Shape{DType: xShape.DType, AxisLengths: lengths}, nil
+}
+
+func evalReduceWindow(reducer pjrtgraph.WindowReducer, wa windowArgs) interp.FuncBuiltin {
+	return func(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+		mat := builtin.Materialiser(env)
+		x, xShape, err := materialise.Element(mat, args[0])
+		if err != nil {
+			return nil, err
+		}
+		window, outShape, err := wa.evalWindow(args, xShape)
+		if err != nil {
+			return nil, err
+		}
+		node, err := pjrtGraph(env).ReduceWindow(x, reducer, window)
+		if err != nil {
+			return nil, err
+		}
+		out, err := outputNode(pjrtGraph(env), node, outShape)
+		if err != nil {
+			return nil, err
+		}
+		return mat.ElementsFromNodes(call.File(), call.Node(), out)
+	}
+}
+
+func evalAvgPool(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+	mat := builtin.Materialiser(env)
+	x, xShape, err := materialise.Element(mat, args[0])
+	if err != nil {
+		return nil, err
+	}
+	window, outShape, err := poolArgs.evalWindow(args, xShape)
+	if err != nil {
+		return nil, err
+	}
+	graph := pjrtGraph(env)
+	sum, err := graph.ReduceWindow(x, pjrtgraph.WindowSum, window)
+	if err != nil {
+		return nil, err
+	}
+	size := 1
+	for _, dim := range window.Dimensions {
+		size *= dim
+	}
+	count, err := graph.Scalar(float64(size), xShape.DType)
+	if err != nil {
+		return nil, err
+	}
+	node, err := graph.BinaryFunc(sum, count, xlabuilder.Div)
+	if err != nil {
+		return nil, err
+	}
+	out, err := outputNode(pjrtGraph(env), node, outShape)
+	if err != nil {
+		return nil, err
+	}
+	return mat.ElementsFromNodes(call.File(), call.Node(), out)
+}
+
+func evalSelectAndScatter(reducer pjrtgraph.WindowReducer) interp.FuncBuiltin {
+	return func(env evaluator.Env, call elements.CallAt, fn fun.Func, irFunc *ir.FuncBuiltin, args []ir.Element) ([]ir.Element, error) {
+		mat := builtin.Materialiser(env)
+		x, xShape, err := materialise.Element(mat, args[0])
+		if err != nil {
+			return nil, err
+		}
+		source, _, err := materialise.Element(mat, args[1])
+		if err != nil {
+			return nil, err
+		}
+		window, _, err := selectAndScatterArgs.evalWindow(args, xShape)
+		if err != nil {
+			return nil, err
+		}
+		node, err := pjrtGraph(env).SelectAndScatter(x, source, reducer, window)
+		if err != nil {
+			return nil, err
+		}
+		out, err := outputNode(pjrtGraph(env), node, xShape)
+		if err != nil {
+			return nil, err
+		}
+		return mat.ElementsFromNodes(call.File(), call.Node(), out)
+	}
+}
+
+type reduceWindowSum struct {
+	builtin.Func
+}
+
+func (f reduceWindowSum) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[reduceWindowSum]("ReduceWindowSum", withPosition(evalReduceWindow(pjrtgraph.WindowSum, reduceWindowArgs)), pkg), nil
+}
+
+func (f reduceWindowSum) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildWindowType(fetcher, call, f.Name(), reduceWindowArgs, false)
+}
+
+type reduceWindowMax struct {
+	builtin.Func
+}
+
+func (f reduceWindowMax) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[reduceWindowMax]("ReduceWindowMax", withPosition(evalReduceWindow(pjrtgraph.WindowMax, reduceWindowArgs)), pkg), nil
+}
+
+func (f reduceWindowMax) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildWindowType(fetcher, call, f.Name(), reduceWindowArgs, false)
+}
+
+type reduceWindowMin struct {
+	builtin.Func
+}
+
+func (f reduceWindowMin) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[reduceWindowMin]("ReduceWindowMin", withPosition(evalReduceWindow(pjrtgraph.WindowMin, reduceWindowArgs)), pkg), nil
+}
+
+func (f reduceWindowMin) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildWindowType(fetcher, call, f.Name(), reduceWindowArgs, false)
+}
+
+type maxPool struct {
+	builtin.Func
+}
+
+func (f maxPool) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[maxPool]("MaxPool", withPosition(evalReduceWindow(pjrtgraph.WindowMax, poolArgs)), pkg), nil
+}
+
+func (f maxPool) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildWindowType(fetcher, call, f.Name(), poolArgs, false)
+}
+
+type avgPool struct {
+	builtin.Func
+}
+
+func (f avgPool) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[avgPool]("AvgPool", withPosition(evalAvgPool), pkg), nil
+}
+
+func (f avgPool) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	if xType, ok := call.Args[0].Type().(ir.ArrayType); ok && !ir.IsFloat(xType.DataType()) {
+		return nil, fmterr.Errorf(fetcher.File().FileSet(), call.Source(), "cannot use %s in call to %s: requires an array of floating-point numbers", call.Args[0].Type().String(), f.Name())
+	}
+	return buildWindowType(fetcher, call, f.Name(), poolArgs, false)
+}
+
+type selectAndScatterMax struct {
+	builtin.Func
+}
+
+func (f selectAndScatterMax) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[selectAndScatterMax]("SelectAndScatterMax", withPosition(evalSelectAndScatter(pjrtgraph.WindowMax)), pkg), nil
+}
+
+func (f selectAndScatterMax) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildWindowType(fetcher, call, f.Name(), selectAndScatterArgs, true)
+}
+
+type selectAndScatterMin struct {
+	builtin.Func
+}
+
+func (f selectAndScatterMin) BuildFuncIR(impl *impl.Stdlib, pkg *ir.Package) (*ir.FuncBuiltin, error) {
+	return builtin.IRFuncBuiltin[selectAndScatterMin]("SelectAndScatterMin", withPosition(evalSelectAndScatter(pjrtgraph.WindowMin)), pkg), nil
+}
+
+func (f selectAndScatterMin) BuildFuncType(fetcher ir.Fetcher, call *ir.CallExpr) (*ir.FuncType, error) {
+	return buildWindowType(fetcher, call, f.Name(), selectAndScatterArgs, true)
+}
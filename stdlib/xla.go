@@ -0,0 +1,191 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdlib
+
+import (
+	"embed"
+	"maps"
+	"slices"
+
+	"github.com/pkg/errors"
+	"github.com/gx-org/backend/dtype"
+	"github.com/gx-org/gx/build/builder"
+	"github.com/gx-org/gx/build/importers"
+	"github.com/gx-org/gx/stdlib/builtin"
+	xlagraph "github.com/gx-org/xlapjrt/backend/graph"
+)
+
+//go:embed xla
+var xlaFS embed.FS
+
+var xlaPackage = builtin.PackageBuilder{
+	FullPath: "xla",
+	Builders: []builtin.Builder{
+		builtin.ParseSource(&xlaFS, "xla/xla.gx"),
+		builtin.BuildFunc(pad{}),
+		builtin.BuildFunc(reverse{}),
+		builtin.BuildFunc(clamp{}),
+		builtin.BuildFunc(selectFunc{}),
+		builtin.BuildFunc(transposeAxes{}),
+		builtin.BuildFunc(moveAxis{}),
+		builtin.BuildFunc(swapAxes{}),
+		builtin.BuildFunc(batchedMatMul{}),
+		builtin.BuildFunc(matMulWithConfig{}),
+		builtin.BuildFunc(dotGeneral{}),
+		builtin.BuildFunc(einsum{}),
+		builtin.BuildFunc(einsumWithConfig{}),
+		builtin.BuildFunc(roundToPrecision{}),
+		builtin.BuildFunc(segmentSum{}),
+		builtin.BuildFunc(segmentMax{}),
+		builtin.BuildFunc(segmentMin{}),
+		builtin.BuildFunc(segmentMean{}),
+		builtin.BuildFunc(oneHot{}),
+		builtin.BuildFunc(gather{}),
+		builtin.BuildFunc(take{}),
+		builtin.BuildFunc(takeAlongAxis{}),
+	},
+}
+
+var nnPackage = builtin.PackageBuilder{
+	FullPath: "xla/nn",
+	Builders: []builtin.Builder{
+		builtin.ParseSource(&xlaFS, "xla/nn/nn.gx"),
+		builtin.BuildFunc(reduceWindowSum{}),
+		builtin.BuildFunc(reduceWindowMax{}),
+		builtin.BuildFunc(reduceWindowMin{}),
+		builtin.BuildFunc(maxPool{}),
+		builtin.BuildFunc(avgPool{}),
+		builtin.BuildFunc(selectAndScatterMax{}),
+		builtin.BuildFunc(selectAndScatterMin{}),
+	},
+}
+
+var cmplxPackage = builtin.PackageBuilder{
+	FullPath: "xla/cmplx",
+	Builders: []builtin.Builder{
+		builtin.ParseSource(&xlaFS, "xla/cmplx/cmplx.gx"),
+		builtin.BuildFunc(complexMul{}),
+		builtin.BuildFunc(complexDiv{}),
+		builtin.BuildFunc(conj{}),
+		builtin.BuildFunc(complexAbs{}),
+		builtin.BuildFunc(complexAngle{}),
+	},
+}
+
+var fftPackage = builtin.PackageBuilder{
+	FullPath: "xla/fft",
+	Builders: []builtin.Builder{
+		builtin.ParseSource(&xlaFS, "xla/fft/fft.gx"),
+		builtin.BuildFunc(fft{}),
+		builtin.BuildFunc(ifft{}),
+		builtin.BuildFunc(rfft{}),
+		builtin.BuildFunc(irfft{}),
+	},
+}
+
+var linalgPackage = builtin.PackageBuilder{
+	FullPath: "xla/linalg",
+	Builders: []builtin.Builder{
+		builtin.ParseSource(&xlaFS, "xla/linalg/linalg.gx"),
+		builtin.BuildFunc(cholesky{}),
+		builtin.BuildFunc(triangularSolve{}),
+		builtin.BuildFunc(qr{}),
+		builtin.BuildFunc(eigh{}),
+		builtin.BuildFunc(det{}),
+		builtin.BuildFunc(logDet{}),
+		builtin.BuildFunc(inv{}),
+	},
+}
+
+var quantPackage = builtin.PackageBuilder{
+	FullPath: "xla/quant",
+	Builders: []builtin.Builder{
+		builtin.ParseSource(&xlaFS, "xla/quant/quant.gx"),
+		builtin.BuildFunc(quantize{}),
+		builtin.BuildFunc(quantizeAxis{}),
+		builtin.BuildFunc(dequantize{}),
+		builtin.BuildFunc(dequantizeAxis{}),
+	},
+}
+
+var randomPackage = builtin.PackageBuilder{
+	FullPath: "xla/random",
+	Builders: []builtin.Builder{
+		builtin.ParseSource(&xlaFS, "xla/random/random.gx"),
+		builtin.ImplementBuiltin("Philox.Uint32", evalPhiloxUint32),
+		builtin.ImplementBuiltin("Philox.Uint64", evalPhiloxUint64),
+		builtin.ImplementBuiltin("Philox.Float32", evalDistribution(dtype.Float32, (*xlagraph.Graph).RandomUniform)),
+		builtin.ImplementBuiltin("Philox.Float64", evalDistribution(dtype.Float64, (*xlagraph.Graph).RandomUniform)),
+		builtin.ImplementBuiltin("Philox.Normal32", evalDistribution(dtype.Float32, (*xlagraph.Graph).RandomNormal)),
+		builtin.ImplementBuiltin("Philox.Normal64", evalDistribution(dtype.Float64, (*xlagraph.Graph).RandomNormal)),
+		builtin.ImplementBuiltin("Philox.TruncatedNormal32", evalTruncatedNormal(dtype.Float32)),
+		builtin.ImplementBuiltin("Philox.TruncatedNormal64", evalTruncatedNormal(dtype.Float64)),
+		builtin.ImplementBuiltin("Philox.Bernoulli", evalBernoulli),
+		builtin.ImplementBuiltin("Philox.Categorical32", evalCategorical),
+		builtin.ImplementBuiltin("Philox.Categorical64", evalCategorical),
+		builtin.ImplementBuiltin("Philox.Permutation", evalPermutation),
+		builtin.ImplementBuiltin("Philox.Split", evalPhiloxSplit),
+		builtin.ImplementBuiltin("Philox.FoldIn", evalPhiloxFoldIn),
+	},
+}
+
+// xlaPackages are the GX packages only available with the XLA backend.
+var xlaPackages = []builtin.PackageBuilder{
+	xlaPackage,
+	cmplxPackage,
+	fftPackage,
+	linalgPackage,
+	nnPackage,
+	quantPackage,
+	randomPackage,
+}
+
+// XLA imports GX packages specific to the XLA backend.
+// These packages are xla and packages under the xla/ import path.
+type XLA struct {
+	libs map[string]builtin.PackageBuilder
+}
+
+var _ importers.Importer = (*XLA)(nil)
+
+// Importer returns the importer for GX packages specific to the XLA backend.
+func Importer() *XLA {
+	lib := &XLA{libs: make(map[string]builtin.PackageBuilder)}
+	for _, pkgBuilder := range xlaPackages {
+		lib.libs[pkgBuilder.FullPath] = pkgBuilder
+	}
+	return lib
+}
+
+// Support returns true if the path is a GX package specific to the XLA backend.
+func (l *XLA) Support(path string) bool {
+	_, ok := l.libs[path]
+	return ok
+}
+
+// Import a package given its path.
+func (l *XLA) Import(bld *builder.Builder, path string) (builder.Package, error) {
+	pkgBuilder, ok := l.libs[path]
+	if !ok {
+		return nil, errors.Errorf("package %s is not provided by the XLA backend", path)
+	}
+	return builtin.Build(bld, Stdlib, pkgBuilder)
+}
+
+// Paths returns all the paths of the packages specific to the XLA backend
+// (alphabetically ordered).
+func (l *XLA) Paths() []string {
+	return slices.Sorted(maps.Keys(l.libs))
+}